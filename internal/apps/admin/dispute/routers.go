@@ -0,0 +1,239 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispute
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
+	"github.com/linux-do/credit/internal/util"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// listDisputesRequest 仲裁队列查询请求
+type listDisputesRequest struct {
+	Page     int    `form:"page" binding:"min=1"`
+	PageSize int    `form:"page_size" binding:"min=1,max=100"`
+	Status   string `form:"status" binding:"omitempty,oneof=disputing refund closed arbitrating"`
+}
+
+type dispute struct {
+	model.Dispute
+	OrderName     string          `json:"order_name"`
+	Amount        decimal.Decimal `json:"amount"`
+	PayeeUserID   uint64          `json:"payee_user_id"`
+	PayeeUsername string          `json:"payee_username"`
+}
+
+// listDisputesResponse 仲裁队列响应
+type listDisputesResponse struct {
+	Disputes []dispute `json:"disputes"`
+	Total    int64     `json:"total"`
+}
+
+// ListDisputes 获取争议列表，默认返回待仲裁争议，按申诉时间先后排序
+// @Tags admin
+// @Produce json
+// @Param request query listDisputesRequest true "查询参数"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/admin/disputes [get]
+func ListDisputes(c *gin.Context) {
+	var req listDisputesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	status := model.DisputeStatusArbitrating
+	if req.Status != "" {
+		status = model.DisputeStatus(req.Status)
+	}
+
+	query := db.DB(c.Request.Context()).Model(&model.Dispute{}).
+		Joins("JOIN orders ON disputes.order_id = orders.id").
+		Joins("JOIN users as payee_user ON orders.payee_user_id = payee_user.id").
+		Joins("JOIN users as initiator_user ON disputes.initiator_user_id = initiator_user.id").
+		Joins("LEFT JOIN users as handler_user ON disputes.handler_user_id = handler_user.id").
+		Where("disputes.status = ?", status)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	var disputes []dispute
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.
+		Select("disputes.*, orders.order_name, orders.amount, orders.payee_user_id, payee_user.username as payee_username, " +
+			"initiator_user.username as initiator_username, handler_user.username as handler_username").
+		Order("disputes.appealed_at ASC NULLS LAST, disputes.id ASC").
+		Offset(offset).
+		Limit(req.PageSize).
+		Find(&disputes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(listDisputesResponse{
+		Disputes: disputes,
+		Total:    total,
+	}))
+}
+
+// ruleDisputeRequest 仲裁请求
+type ruleDisputeRequest struct {
+	Ruling model.DisputeRuling `json:"ruling" binding:"required,oneof=refund partial_refund reject"`
+	Amount decimal.Decimal     `json:"amount"`
+	Reason string              `json:"reason" binding:"required,max=500"`
+}
+
+// RuleDispute 管理员仲裁争议：全额退款、部分退款或驳回申诉，仲裁理由同步写入争议沟通记录
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "争议ID"
+// @Param request body ruleDisputeRequest true "仲裁结果"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/admin/disputes/{id}/ruling [post]
+func RuleDispute(c *gin.Context) {
+	var req ruleDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, util.Err(disputeNotFound))
+		return
+	}
+
+	admin, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var d model.Dispute
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+			Where("id = ? AND status = ?", disputeID, model.DisputeStatusArbitrating).
+			First(&d).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New(disputeNotFound)
+			}
+			return err
+		}
+
+		var order model.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+			Where("id = ? AND status = ? AND type IN ?", d.OrderID, model.OrderStatusDisputing, []model.OrderType{model.OrderTypePayment, model.OrderTypeOnline}).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New(orderNotFound)
+			}
+			return err
+		}
+
+		if d.InitiatorUserID == admin.ID || order.PayeeUserID == admin.ID {
+			return errors.New(cannotRuleOwnDispute)
+		}
+
+		disputeStatus := model.DisputeStatusRefund
+		orderStatus := model.OrderStatusRefund
+		refundAmount := decimal.Zero
+
+		switch req.Ruling {
+		case model.DisputeRulingRefund:
+			refundAmount = order.Amount
+		case model.DisputeRulingPartialRefund:
+			if err := util.ValidateAmount(req.Amount); err != nil || !req.Amount.LessThan(order.Amount) {
+				return errors.New(partialAmountRequired)
+			}
+			refundAmount = req.Amount
+			orderStatus = model.OrderStatusPartialRefund
+		case model.DisputeRulingReject:
+			disputeStatus = model.DisputeStatusClosed
+			orderStatus = model.OrderStatusRefused
+		}
+
+		if refundAmount.IsPositive() {
+			var payeeUser model.User
+			if err := payeeUser.GetByID(tx, order.PayeeUserID); err != nil {
+				return err
+			}
+
+			var merchantPayConfig model.UserPayConfig
+			if err := merchantPayConfig.GetByPayScore(tx, payeeUser.PayScore); err != nil {
+				return err
+			}
+
+			if err := service.RefundOrderAmount(tx, &order, &merchantPayConfig, refundAmount); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(&model.Dispute{}).
+			Where("id = ?", d.ID).
+			Updates(map[string]interface{}{
+				"status":          disputeStatus,
+				"handler_user_id": admin.ID,
+				"refund_amount":   refundAmount,
+				"ruling":          req.Ruling,
+				"ruling_reason":   req.Reason,
+				"ruled_at":        now,
+			}).Error; err != nil {
+			return err
+		}
+
+		orderUpdates := map[string]interface{}{"status": orderStatus}
+		if refundAmount.IsPositive() {
+			orderUpdates["refunded_at"] = now
+		}
+		if err := tx.Model(&model.Order{}).
+			Where("id = ?", order.ID).
+			Updates(orderUpdates).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&model.DisputeMessage{
+			DisputeID:    d.ID,
+			AuthorUserID: admin.ID,
+			AuthorParty:  model.DisputePartyAdmin,
+			Body:         req.Reason,
+		}).Error
+	}); err != nil {
+		switch err.Error() {
+		case disputeNotFound, orderNotFound:
+			c.JSON(http.StatusNotFound, util.Err(err.Error()))
+		case partialAmountRequired, cannotRuleOwnDispute:
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
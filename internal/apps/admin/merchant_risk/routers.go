@@ -0,0 +1,158 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package merchant_risk
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant/risk"
+	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+)
+
+// listRiskLogsRequest 风控处置记录查询请求
+type listRiskLogsRequest struct {
+	Page     int     `form:"page" binding:"min=1"`
+	PageSize int     `form:"page_size" binding:"min=1,max=100"`
+	UserID   *uint64 `form:"user_id" binding:"omitempty,gt=0"`
+	ClientID string  `form:"client_id" binding:"omitempty,max=64"`
+	Action   string  `form:"action" binding:"omitempty,oneof=settlement_delay fee_rate suspend_api_key lift"`
+}
+
+// listRiskLogsResponse 风控处置记录响应
+type listRiskLogsResponse struct {
+	Logs  []model.MerchantRiskLog `json:"logs"`
+	Total int64                   `json:"total"`
+}
+
+// ListRiskLogs 获取商户风控处置记录，包括自动处置、自动解除与管理员解除
+// @Tags admin
+// @Produce json
+// @Param request query listRiskLogsRequest true "查询参数"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/admin/merchant-risk/logs [get]
+func ListRiskLogs(c *gin.Context) {
+	var req listRiskLogsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	query := db.DB(c.Request.Context()).Model(&model.MerchantRiskLog{}).
+		Joins("JOIN users ON merchant_risk_logs.user_id = users.id")
+	if req.UserID != nil {
+		query = query.Where("merchant_risk_logs.user_id = ?", *req.UserID)
+	}
+	if clientID := strings.TrimSpace(req.ClientID); clientID != "" {
+		query = query.Where("merchant_risk_logs.client_id = ?", clientID)
+	}
+	if req.Action != "" {
+		query = query.Where("merchant_risk_logs.action = ?", req.Action)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	var logs []model.MerchantRiskLog
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.
+		Select("merchant_risk_logs.*, users.username").
+		Order("merchant_risk_logs.created_at DESC").
+		Offset(offset).
+		Limit(req.PageSize).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(listRiskLogsResponse{
+		Logs:  logs,
+		Total: total,
+	}))
+}
+
+// liftRiskPenaltyRequest 解除风控处置请求，指定 client_id 时恢复 API Key，否则解除商户用户的到账与费率处置
+type liftRiskPenaltyRequest struct {
+	UserID   uint64 `json:"user_id"`
+	ClientID string `json:"client_id" binding:"omitempty,max=64"`
+	Reason   string `json:"reason" binding:"required,max=200"`
+}
+
+// LiftRiskPenalty 管理员手动解除风控处置；指标仍超标时下次评估会重新处置
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body liftRiskPenaltyRequest true "解除对象"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/admin/merchant-risk/lift [post]
+func LiftRiskPenalty(c *gin.Context) {
+	var req liftRiskPenaltyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+	if req.UserID == 0 && req.ClientID == "" {
+		c.JSON(http.StatusBadRequest, util.Err(liftTargetRequired))
+		return
+	}
+
+	admin, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	tx := db.DB(c.Request.Context())
+
+	if req.ClientID != "" {
+		var apiKey model.MerchantAPIKey
+		if err := apiKey.GetByClientID(tx, req.ClientID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, util.Err(apiKeyNotFound))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+			return
+		}
+		if err := risk.ResumeAPIKey(tx, &apiKey, admin.ID, req.Reason); err != nil {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, util.OKNil())
+		return
+	}
+
+	var target model.User
+	if err := target.GetByID(tx, req.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(userNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	if err := risk.LiftUserPenalty(tx, target.ID, admin.ID, req.Reason); err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
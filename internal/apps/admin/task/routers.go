@@ -100,6 +100,17 @@ func DispatchTask(c *gin.Context) {
 			taskInfo = asynq.NewTask(meta.AsynqTask, payload)
 			taskID = fmt.Sprintf("manual_%s_user_%d", req.TaskType, *req.UserID)
 		}
+	case task.TaskTypeMerchantStatement:
+		if req.StartTime != nil {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"date": req.StartTime.Format("2006-01-02"),
+			})
+			taskInfo = asynq.NewTask(meta.AsynqTask, payload)
+			taskID = fmt.Sprintf("manual_%s_%s", req.TaskType, req.StartTime.Format("20060102"))
+		} else {
+			taskInfo = asynq.NewTask(meta.AsynqTask, nil)
+			taskID = fmt.Sprintf("manual_%s", req.TaskType)
+		}
 	default:
 		taskInfo = asynq.NewTask(meta.AsynqTask, nil)
 		taskID = fmt.Sprintf("manual_%s", req.TaskType)
@@ -38,23 +38,25 @@ type listUsersRequest struct {
 }
 
 type user struct {
-	ID               uint64           `json:"id"`
-	Username         string           `json:"username"`
-	Nickname         string           `json:"nickname"`
-	AvatarUrl        string           `json:"avatar_url"`
-	TrustLevel       model.TrustLevel `json:"trust_level"`
-	PayScore         int64            `json:"pay_score"`
-	TotalReceive     decimal.Decimal  `json:"total_receive"`
-	TotalPayment     decimal.Decimal  `json:"total_payment"`
-	TotalTransfer    decimal.Decimal  `json:"total_transfer"`
-	TotalCommunity   decimal.Decimal  `json:"total_community"`
-	CommunityBalance decimal.Decimal  `json:"community_balance"`
-	AvailableBalance decimal.Decimal  `json:"available_balance"`
-	IsActive         bool             `json:"is_active"`
-	IsAdmin          bool             `json:"is_admin"`
-	LastLoginAt      time.Time        `json:"last_login_at"`
-	CreatedAt        time.Time        `json:"created_at"`
-	UpdatedAt        time.Time        `json:"updated_at"`
+	ID                      uint64           `json:"id"`
+	Username                string           `json:"username"`
+	Nickname                string           `json:"nickname"`
+	AvatarUrl               string           `json:"avatar_url"`
+	TrustLevel              model.TrustLevel `json:"trust_level"`
+	PayScore                int64            `json:"pay_score"`
+	TotalReceive            decimal.Decimal  `json:"total_receive"`
+	TotalPayment            decimal.Decimal  `json:"total_payment"`
+	TotalTransfer           decimal.Decimal  `json:"total_transfer"`
+	TotalCommunity          decimal.Decimal  `json:"total_community"`
+	CommunityBalance        decimal.Decimal  `json:"community_balance"`
+	AvailableBalance        decimal.Decimal  `json:"available_balance"`
+	IsActive                bool             `json:"is_active"`
+	IsAdmin                 bool             `json:"is_admin"`
+	RiskSettlementDelayDays int              `json:"risk_settlement_delay_days"`
+	RiskFeeRate             decimal.Decimal  `json:"risk_fee_rate"`
+	LastLoginAt             time.Time        `json:"last_login_at"`
+	CreatedAt               time.Time        `json:"created_at"`
+	UpdatedAt               time.Time        `json:"updated_at"`
 }
 
 // listUsersResponse 用户列表响应
@@ -101,6 +103,7 @@ func ListUsers(c *gin.Context) {
 		Select("id, username, nickname, avatar_url, trust_level, pay_score, " +
 			"total_receive, total_payment, total_transfer, total_community, " +
 			"community_balance, available_balance, is_active, is_admin, " +
+			"risk_settlement_delay_days, risk_fee_rate, " +
 			"last_login_at, created_at, updated_at").
 		Order("id DESC").
 		Offset(offset).
@@ -50,7 +50,7 @@ func queryDailyAmounts(ctx context.Context, userID uint64, isIncome bool, startD
 		err = db.DB(ctx).Model(&model.Order{}).
 			Select("DATE_TRUNC('day', created_at) as date, SUM(amount) as amount").
 			Where("payee_user_id = ?", userID).
-			Where("status = ?", model.OrderStatusSuccess).
+			Where("status = ? AND is_sandbox = ?", model.OrderStatusSuccess, false).
 			Where("created_at >= ? AND created_at < ?", startDate, endDate).
 			Group("DATE_TRUNC('day', created_at)").
 			Scan(&results).Error
@@ -60,7 +60,7 @@ func queryDailyAmounts(ctx context.Context, userID uint64, isIncome bool, startD
 		err = db.DB(ctx).Model(&model.Order{}).
 			Select("DATE_TRUNC('day', created_at) as date, SUM(amount) as amount").
 			Where("payer_user_id = ?", userID).
-			Where("status = ?", model.OrderStatusSuccess).
+			Where("status = ? AND is_sandbox = ?", model.OrderStatusSuccess, false).
 			Where("type != ?", model.OrderTypeRedEnvelopeReceive).
 			Where("created_at >= ? AND created_at < ?", startDate, endDate).
 			Group("DATE_TRUNC('day', created_at)").
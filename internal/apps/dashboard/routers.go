@@ -21,7 +21,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/apps/merchant"
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/util"
@@ -59,6 +59,7 @@ type TopCustomer struct {
 // @Tags dashboard
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param days query int true "查询天数，最大7天"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/dashboard/stats/daily [get]
@@ -69,7 +70,7 @@ func GetDailyStats(c *gin.Context) {
 		return
 	}
 
-	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	user, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 	ctx := c.Request.Context()
 	startDate, endDate := getDateRange(req.Days)
 
@@ -97,6 +98,7 @@ func GetDailyStats(c *gin.Context) {
 // @Tags dashboard
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param days query int true "查询天数，最大7天"
 // @Param limit query int true "返回数量，最大10"
 // @Success 200 {object} util.ResponseAny
@@ -108,7 +110,7 @@ func GetTopCustomers(c *gin.Context) {
 		return
 	}
 
-	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	user, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 	ctx := c.Request.Context()
 	startDate, endDate := getDateRange(req.Days)
 
@@ -123,7 +125,7 @@ func GetTopCustomers(c *gin.Context) {
 		`).
 		Joins("LEFT JOIN users ON orders.payer_user_id = users.id").
 		Where("orders.payee_user_id = ?", user.ID).
-		Where("orders.status = ?", model.OrderStatusSuccess).
+		Where("orders.status = ? AND orders.is_sandbox = ?", model.OrderStatusSuccess, false).
 		Where("orders.type in ?", []model.OrderType{model.OrderTypePayment, model.OrderTypeOnline}).
 		Where("orders.created_at >= ? AND orders.created_at < ?", startDate, endDate).
 		Group("orders.payer_user_id, users.username").
@@ -0,0 +1,26 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispute
+
+const (
+	// EventDisputeReminder 争议处理截止提醒的回调事件
+	EventDisputeReminder = "dispute.reminder"
+	// disputeAutoRefundTaskIDFormat 争议到期自动退款任务 ID，保证每个争议只有一个待执行任务
+	disputeAutoRefundTaskIDFormat = "dispute:auto_refund:%d"
+	// disputeReminderTaskIDFormat 争议截止提醒任务 ID：dispute:reminder:<争议ID>:<提前小时数>
+	disputeReminderTaskIDFormat = "dispute:reminder:%d:%d"
+)
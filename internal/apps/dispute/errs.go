@@ -23,4 +23,14 @@ const (
 	ReasonRequiredForRefusal = "拒绝退款时必须提供理由"
 	DisputeTimeWindowExpired = "订单已交易完成,超过争议时间窗口,无法发起争议"
 	DuplicateDispute         = "无法重复发起争议，如仍有疑问请联系商家或LINUX DO Credit 团队"
+	InvalidDisputeID         = "争议 ID 格式错误"
+	DisputeAccessDenied      = "无权访问该争议"
+	DisputeMessageClosed     = "争议已结束，无法继续留言"
+	EvidenceInvalid          = "凭证文件不存在或不属于当前用户"
+	TooManyAttachments       = "单条消息最多上传 5 个附件"
+	AttachmentNotFound       = "附件不存在"
+	DisputeNotAppealable     = "仅商家拒绝退款的争议可申诉"
+	AppealWindowExpired      = "已超过申诉时间窗口"
+	OfferAmountInvalid       = "部分退款金额须大于 0 且小于订单金额"
+	OfferNotFound            = "商家尚未提出部分退款方案"
 )
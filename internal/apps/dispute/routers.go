@@ -18,14 +18,21 @@ package dispute
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
 	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/apps/upload"
 	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
 	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
 	"github.com/linux-do/credit/internal/util"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -36,7 +43,7 @@ import (
 type ListDisputesRequest struct {
 	Page      int     `json:"page" form:"page" binding:"min=1"`
 	PageSize  int     `json:"page_size" form:"page_size" binding:"min=1,max=100"`
-	Status    string  `json:"status" form:"status" binding:"omitempty,oneof=disputing refund closed"`
+	Status    string  `json:"status" form:"status" binding:"omitempty,oneof=disputing refund closed arbitrating"`
 	DisputeID *uint64 `json:"dispute_id,string" form:"dispute_id" binding:"omitempty"`
 }
 
@@ -109,6 +116,7 @@ func ListDisputes(c *gin.Context) {
 // @Tags order
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param request body ListDisputesRequest false "request body"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/order/disputes/merchant [post]
@@ -119,7 +127,7 @@ func ListMerchantDisputes(c *gin.Context) {
 		return
 	}
 
-	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 
 	baseQuery := db.DB(c.Request.Context()).Model(&model.Dispute{}).
 		Select("disputes.*, orders.order_name, payee_user.username as payee_username, orders.amount, initiator_user.username as initiator_username, handler_user.username as handler_username").
@@ -127,7 +135,7 @@ func ListMerchantDisputes(c *gin.Context) {
 		Joins("JOIN users as payee_user ON orders.payee_user_id = payee_user.id").
 		Joins("JOIN users as initiator_user ON disputes.initiator_user_id = initiator_user.id").
 		Joins("LEFT JOIN users as handler_user ON disputes.handler_user_id = handler_user.id").
-		Where("orders.payee_user_id = ?", user.ID)
+		Where("orders.payee_user_id = ?", merchantUser.ID)
 
 	if req.Status != "" {
 		baseQuery = baseQuery.Where("disputes.status = ?", model.DisputeStatus(req.Status))
@@ -186,11 +194,14 @@ func CreateDispute(c *gin.Context) {
 		return
 	}
 
+	// 商家需在截止时间前处理，逾期自动全额退款
+	respondBy := time.Now().Add(time.Duration(disputeTimeHours) * time.Hour)
 	dispute := model.Dispute{
 		OrderID:         req.OrderID,
 		InitiatorUserID: user.ID,
 		Reason:          req.Reason,
 		Status:          model.DisputeStatusDisputing,
+		RespondBy:       &respondBy,
 	}
 
 	if err := db.DB(c.Request.Context()).Transaction(
@@ -237,6 +248,11 @@ func CreateDispute(c *gin.Context) {
 		return
 	}
 
+	// 下发失败不影响争议创建，由补偿任务重新下发
+	if err := ScheduleDisputeTasks(c.Request.Context(), dispute.ID, respondBy, respondBy); err != nil {
+		logger.ErrorF(c.Request.Context(), "%v", err)
+	}
+
 	c.JSON(http.StatusOK, util.OK(dispute))
 }
 
@@ -251,6 +267,7 @@ type RefundReviewRequest struct {
 // @Tags order
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param request body RefundReviewRequest true "request body"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/order/refund-review [post]
@@ -267,7 +284,9 @@ func RefundReview(c *gin.Context) {
 		return
 	}
 
-	merchantUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	// 处理人为实际操作的成员，资金与信用分变动归属商户所有者
+	handlerUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 
 	if err := db.DB(c.Request.Context()).Transaction(
 		func(tx *gorm.DB) error {
@@ -292,35 +311,13 @@ func RefundReview(c *gin.Context) {
 			}
 
 			if status == model.DisputeStatusRefund {
-				var payerUser model.User
-				if err := payerUser.GetByID(tx, order.PayerUserID); err != nil {
-					return err
-				}
-
 				// 获取商家的支付配置
 				var merchantPayConfig model.UserPayConfig
 				if err := merchantPayConfig.GetByPayScore(tx, merchantUser.PayScore); err != nil {
 					return err
 				}
 
-				merchantScoreDecrease := order.Amount.Mul(merchantPayConfig.ScoreRate).Round(0).IntPart()
-				if err := tx.Model(&model.User{}).
-					Where("id = ?", merchantUser.ID).
-					UpdateColumns(map[string]interface{}{
-						"available_balance": gorm.Expr("available_balance - ?", order.Amount),
-						"total_receive":     gorm.Expr("total_receive - ?", order.Amount),
-						"pay_score":         gorm.Expr("pay_score - ?", merchantScoreDecrease),
-					}).Error; err != nil {
-					return err
-				}
-
-				if err := tx.Model(&model.User{}).
-					Where("id = ?", payerUser.ID).
-					UpdateColumns(map[string]interface{}{
-						"available_balance": gorm.Expr("available_balance + ?", order.Amount),
-						"total_payment":     gorm.Expr("total_payment - ?", order.Amount),
-						"pay_score":         gorm.Expr("pay_score - ?", order.Amount.Round(0).IntPart()),
-					}).Error; err != nil {
+				if err := service.RefundOrderAmount(tx, &order, &merchantPayConfig, order.Amount); err != nil {
 					return err
 				}
 
@@ -328,21 +325,26 @@ func RefundReview(c *gin.Context) {
 					Where("id = ?", dispute.ID).
 					Updates(map[string]interface{}{
 						"status":          model.DisputeStatusRefund,
-						"handler_user_id": merchantUser.ID,
+						"handler_user_id": handlerUser.ID,
+						"refund_amount":   order.Amount,
 					}).Error; err != nil {
 					return err
 				}
 
 				if err := tx.Model(&model.Order{}).
 					Where("id = ?", order.ID).
-					Update("status", model.OrderStatusRefund).Error; err != nil {
+					Updates(map[string]interface{}{
+						"status":      model.OrderStatusRefund,
+						"refunded_at": time.Now(),
+					}).Error; err != nil {
 					return err
 				}
 			} else if status == model.DisputeStatusClosed {
 				updateData := map[string]interface{}{
 					"status":          model.DisputeStatusClosed,
-					"handler_user_id": merchantUser.ID,
+					"handler_user_id": handlerUser.ID,
 					"reason":          dispute.Reason + " [服务方拒绝理由: " + req.Reason + "]",
+					"refused_at":      time.Now(),
 				}
 
 				if err := tx.Model(&model.Dispute{}).
@@ -370,6 +372,8 @@ func RefundReview(c *gin.Context) {
 		return
 	}
 
+	util.SetToContext(c, merchant.AuditTargetIDKey, strconv.FormatUint(req.DisputeID, 10))
+
 	c.JSON(http.StatusOK, util.OKNil())
 }
 
@@ -447,3 +451,461 @@ func CloseDispute(c *gin.Context) {
 
 	c.JSON(http.StatusOK, util.OKNil())
 }
+
+// ListDisputeMessages 查询争议沟通记录
+// @Tags order
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path string true "争议 ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/order/dispute/{id}/messages [get]
+func ListDisputeMessages(c *gin.Context) {
+	disputeID, err := parseDisputeID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	if _, _, err := resolveDisputeParty(c, db.DB(c.Request.Context()), disputeID); err != nil {
+		c.JSON(disputeErrorStatus(err.Error()), util.Err(err.Error()))
+		return
+	}
+
+	var messages []model.DisputeMessage
+	if err := db.DB(c.Request.Context()).
+		Model(&model.DisputeMessage{}).
+		Select("dispute_messages.*, COALESCE(users.username, '') as author_username").
+		Joins("LEFT JOIN users ON dispute_messages.author_user_id = users.id").
+		Where("dispute_messages.dispute_id = ?", disputeID).
+		Order("dispute_messages.created_at ASC").
+		Find(&messages).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(messages))
+}
+
+// CreateDisputeMessageRequest 发送争议消息请求
+type CreateDisputeMessageRequest struct {
+	Body          string   `json:"body" binding:"required,max=1000"`
+	AttachmentIDs []string `json:"attachment_ids" binding:"omitempty,dive,numeric"`
+}
+
+// CreateDisputeMessage 发送争议消息，附件须为当前用户上传的 evidence 类型文件
+// @Tags order
+// @Accept json
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path string true "争议 ID"
+// @Param request body CreateDisputeMessageRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/order/dispute/{id}/messages [post]
+func CreateDisputeMessage(c *gin.Context) {
+	disputeID, err := parseDisputeID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	var req CreateDisputeMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+	attachmentIDs := slices.Compact(slices.Sorted(slices.Values(req.AttachmentIDs)))
+	if len(attachmentIDs) > model.MaxDisputeAttachments {
+		c.JSON(http.StatusBadRequest, util.Err(TooManyAttachments))
+		return
+	}
+	uploadIDs := make([]uint64, 0, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		uploadID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(EvidenceInvalid))
+			return
+		}
+		uploadIDs = append(uploadIDs, uploadID)
+	}
+
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	var message model.DisputeMessage
+
+	if err := db.DB(c.Request.Context()).Transaction(
+		func(tx *gorm.DB) error {
+			dispute, party, err := resolveDisputeParty(c, tx, disputeID)
+			if err != nil {
+				return err
+			}
+			if dispute.Status != model.DisputeStatusDisputing && dispute.Status != model.DisputeStatusArbitrating {
+				return errors.New(DisputeMessageClosed)
+			}
+
+			if len(uploadIDs) > 0 {
+				var count int64
+				if err := tx.Model(&model.Upload{}).
+					Where("id IN ? AND user_id = ? AND type = ? AND status IN (?, ?)", uploadIDs, user.ID, model.UploadTypeEvidence, model.UploadStatusPending, model.UploadStatusUsed).
+					Count(&count).Error; err != nil {
+					return err
+				}
+				if count != int64(len(uploadIDs)) {
+					return errors.New(EvidenceInvalid)
+				}
+
+				if err := tx.Model(&model.Upload{}).
+					Where("id IN ? AND status = ?", uploadIDs, model.UploadStatusPending).
+					Update("status", model.UploadStatusUsed).Error; err != nil {
+					return err
+				}
+			}
+
+			message = model.DisputeMessage{
+				DisputeID:      disputeID,
+				AuthorUserID:   user.ID,
+				AuthorParty:    party,
+				Body:           req.Body,
+				AttachmentIDs:  attachmentIDs,
+				AuthorUsername: user.Username,
+			}
+			return tx.Create(&message).Error
+		},
+	); err != nil {
+		c.JSON(disputeErrorStatus(err.Error()), util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(message))
+}
+
+// GetDisputeAttachment 下载争议消息附件
+// @Tags order
+// @Produce octet-stream
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path string true "争议 ID"
+// @Param uploadId path string true "附件上传 ID"
+// @Success 200
+// @Router /api/v1/order/dispute/{id}/attachments/{uploadId} [get]
+func GetDisputeAttachment(c *gin.Context) {
+	disputeID, err := parseDisputeID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+	uploadID, err := strconv.ParseUint(c.Param("uploadId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, util.Err(AttachmentNotFound))
+		return
+	}
+
+	if _, _, err := resolveDisputeParty(c, db.DB(c.Request.Context()), disputeID); err != nil {
+		c.JSON(disputeErrorStatus(err.Error()), util.Err(err.Error()))
+		return
+	}
+
+	// 仅允许访问本争议消息中引用的附件
+	var referenced int64
+	if err := db.DB(c.Request.Context()).
+		Model(&model.DisputeMessage{}).
+		Where("dispute_id = ? AND attachment_ids @> ?", disputeID, fmt.Sprintf(`["%d"]`, uploadID)).
+		Count(&referenced).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	if referenced == 0 {
+		c.JSON(http.StatusNotFound, util.Err(AttachmentNotFound))
+		return
+	}
+
+	var evidence model.Upload
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND type = ? AND status = ?", uploadID, model.UploadTypeEvidence, model.UploadStatusUsed).
+		First(&evidence).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(AttachmentNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	upload.ServeUpload(c, &evidence)
+}
+
+// AppealDisputeRequest 申诉仲裁请求
+type AppealDisputeRequest struct {
+	DisputeID uint64 `json:"dispute_id,string" binding:"required"`
+	Reason    string `json:"reason" binding:"required,max=500"`
+}
+
+// AppealDispute 商家拒绝退款后，争议发起者申诉并提交管理员仲裁
+// @Tags order
+// @Accept json
+// @Produce json
+// @Param request body AppealDisputeRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/order/dispute/appeal [post]
+func AppealDispute(c *gin.Context) {
+	var req AppealDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	// 获取申诉时间窗口配置（小时）
+	appealWindowHours := model.GetDisputeAppealWindowHours(c.Request.Context())
+
+	if err := db.DB(c.Request.Context()).Transaction(
+		func(tx *gorm.DB) error {
+			var dispute model.Dispute
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+				Where("id = ? AND initiator_user_id = ?", req.DisputeID, user.ID).
+				First(&dispute).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(DisputeNotFound)
+				}
+				return err
+			}
+
+			// 仅商家拒绝且未申诉过的争议可申诉，仲裁结果为终局
+			if dispute.Status != model.DisputeStatusClosed || dispute.RefusedAt == nil || dispute.AppealedAt != nil {
+				return errors.New(DisputeNotAppealable)
+			}
+
+			var order model.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+				Where("id = ? AND status = ?", dispute.OrderID, model.OrderStatusRefused).
+				First(&order).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(DisputeNotAppealable)
+				}
+				return err
+			}
+
+			// 商家拒绝时间 + 申诉时间窗口 <= 当前时间，则无法申诉
+			if time.Now().After(dispute.RefusedAt.Add(time.Duration(appealWindowHours) * time.Hour)) {
+				return errors.New(AppealWindowExpired)
+			}
+
+			now := time.Now()
+			if err := tx.Model(&model.Dispute{}).
+				Where("id = ?", dispute.ID).
+				Updates(map[string]interface{}{
+					"status":        model.DisputeStatusArbitrating,
+					"appeal_reason": req.Reason,
+					"appealed_at":   now,
+				}).Error; err != nil {
+				return err
+			}
+
+			// 订单回到争议中，仲裁结束前不可再次操作
+			return tx.Model(&model.Order{}).
+				Where("id = ?", order.ID).
+				Update("status", model.OrderStatusDisputing).Error
+		},
+	); err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case DisputeNotFound:
+			c.JSON(http.StatusNotFound, util.Err(errMsg))
+		case DisputeNotAppealable, AppealWindowExpired:
+			c.JSON(http.StatusBadRequest, util.Err(errMsg))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(errMsg))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
+
+// RefundOfferRequest 商家部分退款方案请求
+type RefundOfferRequest struct {
+	DisputeID uint64          `json:"dispute_id,string" binding:"required"`
+	Amount    decimal.Decimal `json:"amount" binding:"required"`
+	Message   string          `json:"message" binding:"omitempty,max=1000"`
+}
+
+// RefundOffer 商家提出部分退款方案，买家接受后按该金额退款；重复提交将覆盖之前的方案
+// @Tags order
+// @Accept json
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param request body RefundOfferRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/order/dispute/offer [post]
+func RefundOffer(c *gin.Context) {
+	var req RefundOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+	if err := util.ValidateAmount(req.Amount); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(OfferAmountInvalid))
+		return
+	}
+
+	handlerUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+
+	if err := db.DB(c.Request.Context()).Transaction(
+		func(tx *gorm.DB) error {
+			var dispute model.Dispute
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+				Where("id = ? AND status = ?", req.DisputeID, model.DisputeStatusDisputing).
+				First(&dispute).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(DisputeNotFound)
+				}
+				return err
+			}
+
+			var order model.Order
+			if err := tx.Where("id = ? AND payee_user_id = ? AND status = ?", dispute.OrderID, merchantUser.ID, model.OrderStatusDisputing).
+				First(&order).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(NotOrderMerchant)
+				}
+				return err
+			}
+
+			if !req.Amount.LessThan(order.Amount) {
+				return errors.New(OfferAmountInvalid)
+			}
+
+			if err := tx.Model(&model.Dispute{}).
+				Where("id = ?", dispute.ID).
+				Updates(map[string]interface{}{
+					"offer_amount": req.Amount,
+					"offered_at":   time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+
+			// 方案同步到争议沟通记录，便于买家查看
+			body := req.Message
+			if body == "" {
+				body = fmt.Sprintf("商家提出部分退款 %s，买家接受后争议结束", req.Amount.StringFixed(2))
+			}
+			return tx.Create(&model.DisputeMessage{
+				DisputeID:    dispute.ID,
+				AuthorUserID: handlerUser.ID,
+				AuthorParty:  model.DisputePartyMerchant,
+				Body:         body,
+			}).Error
+		},
+	); err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case DisputeNotFound:
+			c.JSON(http.StatusNotFound, util.Err(errMsg))
+		case NotOrderMerchant:
+			c.JSON(http.StatusForbidden, util.Err(errMsg))
+		case OfferAmountInvalid:
+			c.JSON(http.StatusBadRequest, util.Err(errMsg))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(errMsg))
+		}
+		return
+	}
+
+	util.SetToContext(c, merchant.AuditTargetIDKey, strconv.FormatUint(req.DisputeID, 10))
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
+
+// AcceptRefundOfferRequest 接受部分退款方案请求
+type AcceptRefundOfferRequest struct {
+	DisputeID uint64 `json:"dispute_id,string" binding:"required"`
+}
+
+// AcceptRefundOffer 争议发起者接受商家的部分退款方案，余额、累计金额与积分按退款金额比例冲回
+// @Tags order
+// @Accept json
+// @Produce json
+// @Param request body AcceptRefundOfferRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/order/dispute/offer/accept [post]
+func AcceptRefundOffer(c *gin.Context) {
+	var req AcceptRefundOfferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	if err := db.DB(c.Request.Context()).Transaction(
+		func(tx *gorm.DB) error {
+			var dispute model.Dispute
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+				Where("id = ? AND initiator_user_id = ? AND status = ?", req.DisputeID, user.ID, model.DisputeStatusDisputing).
+				First(&dispute).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(DisputeNotFound)
+				}
+				return err
+			}
+			if dispute.OfferAmount == nil {
+				return errors.New(OfferNotFound)
+			}
+
+			var order model.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+				Where("id = ? AND status = ? AND type IN ?", dispute.OrderID, model.OrderStatusDisputing, []model.OrderType{model.OrderTypePayment, model.OrderTypeOnline}).
+				First(&order).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(OrderNotFoundForDispute)
+				}
+				return err
+			}
+
+			var merchantUser model.User
+			if err := merchantUser.GetByID(tx, order.PayeeUserID); err != nil {
+				return err
+			}
+
+			var merchantPayConfig model.UserPayConfig
+			if err := merchantPayConfig.GetByPayScore(tx, merchantUser.PayScore); err != nil {
+				return err
+			}
+
+			if err := service.RefundOrderAmount(tx, &order, &merchantPayConfig, *dispute.OfferAmount); err != nil {
+				return err
+			}
+
+			if err := tx.Model(&model.Dispute{}).
+				Where("id = ?", dispute.ID).
+				Updates(map[string]interface{}{
+					"status":          model.DisputeStatusRefund,
+					"handler_user_id": user.ID,
+					"refund_amount":   *dispute.OfferAmount,
+				}).Error; err != nil {
+				return err
+			}
+
+			return tx.Model(&model.Order{}).
+				Where("id = ?", order.ID).
+				Updates(map[string]interface{}{
+					"status":      model.OrderStatusPartialRefund,
+					"refunded_at": time.Now(),
+				}).Error
+		},
+	); err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case DisputeNotFound, OrderNotFoundForDispute:
+			c.JSON(http.StatusNotFound, util.Err(errMsg))
+		case OfferNotFound:
+			c.JSON(http.StatusBadRequest, util.Err(errMsg))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(errMsg))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
@@ -17,24 +17,67 @@ limitations under the License.
 package dispute
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/linux-do/credit/internal/apps/payment"
 	"github.com/linux-do/credit/internal/config"
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/logger"
 	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
 	"github.com/linux-do/credit/internal/task"
 	"github.com/linux-do/credit/internal/task/scheduler"
+	"github.com/linux-do/credit/internal/util"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-// HandleAutoRefundExpiredDisputes 处理所有过期争议的批量任务
+// ScheduleDisputeTasks 按争议的处理截止时间下发截止前提醒与到期自动退款任务
+// refundAt 为自动退款任务的执行时间，通常等于截止时间；任务 ID 固定，重复下发会被忽略
+func ScheduleDisputeTasks(ctx context.Context, disputeID uint64, respondBy time.Time, refundAt time.Time) error {
+	for _, hours := range model.GetDisputeReminderHours(ctx) {
+		remindAt := respondBy.Add(-time.Duration(hours) * time.Hour)
+		if remindAt.Before(time.Now()) {
+			continue
+		}
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"dispute_id": disputeID,
+			"hours":      hours,
+		})
+		if _, err := scheduler.AsynqClient.Enqueue(
+			asynq.NewTask(task.DisputeDeadlineReminderTask, payload),
+			asynq.TaskID(fmt.Sprintf(disputeReminderTaskIDFormat, disputeID, hours)),
+			asynq.ProcessAt(remindAt),
+			asynq.MaxRetry(3),
+		); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return fmt.Errorf("下发争议[ID:%d]截止提醒任务失败: %w", disputeID, err)
+		}
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"dispute_id": disputeID,
+	})
+	if _, err := scheduler.AsynqClient.Enqueue(
+		asynq.NewTask(task.AutoRefundSingleDisputeTask, payload),
+		asynq.TaskID(fmt.Sprintf(disputeAutoRefundTaskIDFormat, disputeID)),
+		asynq.ProcessAt(refundAt),
+		asynq.MaxRetry(5),
+	); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("下发争议[ID:%d]自动退款任务失败: %w", disputeID, err)
+	}
+	return nil
+}
+
+// HandleAutoRefundExpiredDisputes 补偿任务：为缺少截止时间的历史争议补齐 respond_by，
+// 并为处理中的争议重新下发提醒与自动退款任务，逾期的争议按间隔依次退款
 func HandleAutoRefundExpiredDisputes(ctx context.Context, t *asynq.Task) error {
 	// 获取争议时间窗口配置（小时）
 	disputeTimeHours, errGet := model.GetIntByKey(ctx, model.ConfigKeyDisputeTimeWindowHours)
@@ -46,19 +89,16 @@ func HandleAutoRefundExpiredDisputes(ctx context.Context, t *asynq.Task) error {
 	pageSize := 1000
 	lastID := uint64(0)
 	currentDelay := 0 * time.Second
-
-	// 计算过期时间阈值：created_at < deadline 的争议需要自动退款
-	deadline := time.Now().Add(-time.Duration(disputeTimeHours) * time.Hour)
+	now := time.Now()
 
 	for {
 		var disputes []model.Dispute
 		if err := db.DB(ctx).
-			Where("id > ? AND status = ? AND created_at < ?",
-				lastID, model.DisputeStatusDisputing, deadline).
+			Where("id > ? AND status = ?", lastID, model.DisputeStatusDisputing).
 			Order("id ASC").
 			Limit(pageSize).
 			Find(&disputes).Error; err != nil {
-			logger.ErrorF(ctx, "查询过期争议失败: %v", err)
+			logger.ErrorF(ctx, "查询处理中争议失败: %v", err)
 			return err
 		}
 
@@ -68,21 +108,26 @@ func HandleAutoRefundExpiredDisputes(ctx context.Context, t *asynq.Task) error {
 		}
 
 		for _, dispute := range disputes {
-			currentDelay += time.Duration(config.Config.Scheduler.DisputeAutoRefundDispatchIntervalSeconds) * time.Second
-
-			payload, _ := json.Marshal(map[string]interface{}{
-				"dispute_id": dispute.ID,
-			})
-
-			if _, errTask := scheduler.AsynqClient.Enqueue(
-				asynq.NewTask(task.AutoRefundSingleDisputeTask, payload),
-				asynq.ProcessIn(currentDelay),
-				asynq.MaxRetry(5),
-			); errTask != nil {
-				logger.ErrorF(ctx, "下发争议[ID:%d]自动退款任务失败: %v", dispute.ID, errTask)
-				return errTask
-			} else {
-				logger.InfoF(ctx, "下发争议[ID:%d]自动退款任务成功", dispute.ID)
+			if dispute.RespondBy == nil {
+				respondBy := dispute.CreatedAt.Add(time.Duration(disputeTimeHours) * time.Hour)
+				if err := db.DB(ctx).Model(&model.Dispute{}).
+					Where("id = ?", dispute.ID).
+					Update("respond_by", respondBy).Error; err != nil {
+					logger.ErrorF(ctx, "补齐争议[ID:%d]处理截止时间失败: %v", dispute.ID, err)
+					return err
+				}
+				dispute.RespondBy = &respondBy
+			}
+
+			refundAt := *dispute.RespondBy
+			if refundAt.Before(now) {
+				currentDelay += time.Duration(config.Config.Scheduler.DisputeAutoRefundDispatchIntervalSeconds) * time.Second
+				refundAt = now.Add(currentDelay)
+			}
+
+			if err := ScheduleDisputeTasks(ctx, dispute.ID, *dispute.RespondBy, refundAt); err != nil {
+				logger.ErrorF(ctx, "%v", err)
+				return err
 			}
 		}
 
@@ -91,6 +136,135 @@ func HandleAutoRefundExpiredDisputes(ctx context.Context, t *asynq.Task) error {
 	return nil
 }
 
+// HandleDisputeDeadlineReminder 处理争议截止前提醒任务：写入站内系统消息并通知商户回调地址
+func HandleDisputeDeadlineReminder(ctx context.Context, t *asynq.Task) error {
+	var payload struct {
+		DisputeID uint64 `json:"dispute_id"`
+		Hours     int    `json:"hours"`
+	}
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w", err)
+	}
+
+	var dispute model.Dispute
+	if err := db.DB(ctx).
+		Where("id = ? AND status = ?", payload.DisputeID, model.DisputeStatusDisputing).
+		First(&dispute).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.InfoF(ctx, "争议[ID:%d]已被处理或不存在，跳过提醒", payload.DisputeID)
+			return nil
+		}
+		return err
+	}
+	if dispute.RespondBy == nil {
+		return nil
+	}
+
+	var order model.Order
+	if err := db.DB(ctx).Where("id = ?", dispute.OrderID).First(&order).Error; err != nil {
+		return fmt.Errorf("查询争议订单失败: %w", err)
+	}
+
+	// 站内提醒：写入争议沟通记录，商家与买家均可见
+	if err := db.DB(ctx).Create(&model.DisputeMessage{
+		DisputeID:   dispute.ID,
+		AuthorParty: model.DisputePartySystem,
+		Body: fmt.Sprintf(
+			"距离商家处理截止时间 %s 还剩约 %d 小时，逾期未处理将自动全额退款给买家",
+			dispute.RespondBy.Format(time.DateTime), payload.Hours,
+		),
+	}).Error; err != nil {
+		return fmt.Errorf("写入争议提醒消息失败: %w", err)
+	}
+
+	// 商户 API 订单额外回调通知商户
+	if order.ClientID != "" {
+		notifyPayload, _ := json.Marshal(map[string]interface{}{
+			"dispute_id": dispute.ID,
+			"hours":      payload.Hours,
+		})
+		if _, err := scheduler.AsynqClient.Enqueue(
+			asynq.NewTask(task.DisputeReminderNotifyTask, notifyPayload),
+			asynq.Queue(task.QueueWebhook),
+			asynq.MaxRetry(5),
+			asynq.Timeout(30*time.Second),
+		); err != nil {
+			logger.ErrorF(ctx, "下发争议[ID:%d]提醒回调任务失败: %v", dispute.ID, err)
+		}
+	}
+
+	logger.InfoF(ctx, "争议[ID:%d]截止提醒已发送: 剩余[%d]小时", dispute.ID, payload.Hours)
+	return nil
+}
+
+// HandleDisputeReminderNotify 向商户回调地址推送争议截止提醒
+func HandleDisputeReminderNotify(ctx context.Context, t *asynq.Task) error {
+	var payload struct {
+		DisputeID uint64 `json:"dispute_id"`
+		Hours     int    `json:"hours"`
+	}
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w", err)
+	}
+
+	var dispute model.Dispute
+	if err := db.DB(ctx).
+		Where("id = ? AND status = ?", payload.DisputeID, model.DisputeStatusDisputing).
+		First(&dispute).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.InfoF(ctx, "争议[ID:%d]已被处理或不存在，跳过提醒回调", payload.DisputeID)
+			return nil
+		}
+		return err
+	}
+	if dispute.RespondBy == nil {
+		return nil
+	}
+
+	var order model.Order
+	if err := db.DB(ctx).Where("id = ?", dispute.OrderID).First(&order).Error; err != nil {
+		return fmt.Errorf("查询争议订单失败: %w", err)
+	}
+
+	var apiKey model.MerchantAPIKey
+	if err := apiKey.GetByClientID(db.DB(ctx), order.ClientID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.InfoF(ctx, "商户[ClientID:%s]已删除，跳过争议提醒回调", order.ClientID)
+			return nil
+		}
+		return fmt.Errorf("查询商户信息失败: %w", err)
+	}
+
+	callbackURL := cmp.Or(order.NotifyURL, apiKey.NotifyURL)
+	if callbackURL == "" || (config.Config.App.IsProduction() && util.IsLocalhost(callbackURL)) {
+		return nil
+	}
+
+	callbackParams := map[string]string{
+		"pid":          order.ClientID,
+		"event":        EventDisputeReminder,
+		"dispute_id":   strconv.FormatUint(dispute.ID, 10),
+		"trade_no":     strconv.FormatUint(order.ID, 10),
+		"out_trade_no": util.DerefString(order.MerchantOrderNo),
+		"money":        order.Amount.Truncate(2).StringFixed(2),
+		"respond_by":   strconv.FormatInt(dispute.RespondBy.Unix(), 10),
+		"hours_left":   strconv.Itoa(payload.Hours),
+	}
+	if order.Attach != "" {
+		callbackParams["attach"] = order.Attach
+	}
+	payment.SignCallbackParams(callbackParams, &apiKey, order.SignType)
+
+	if err := payment.SendCallbackRequest(ctx, callbackURL, callbackParams); err != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		logger.ErrorF(ctx, "争议提醒回调失败: 争议[ID:%d] 重试次数[%d] 错误: %v", dispute.ID, retried+1, err)
+		return err
+	}
+
+	logger.InfoF(ctx, "争议提醒回调成功: 争议[ID:%d] ClientID[%s]", dispute.ID, order.ClientID)
+	return nil
+}
+
 // HandleAutoRefundSingleDispute 处理单个争议的自动退款任务
 func HandleAutoRefundSingleDispute(ctx context.Context, t *asynq.Task) error {
 	// 解析任务参数
@@ -144,29 +318,9 @@ func HandleAutoRefundSingleDispute(ctx context.Context, t *asynq.Task) error {
 			return fmt.Errorf("查询商家支付配置失败: %w", err)
 		}
 
-		// 计算商家积分减少：订单金额 × 商家的 score_rate
-		merchantScoreDecrease := order.Amount.Mul(merchantPayConfig.ScoreRate).Round(0).IntPart()
-
-		// 商家(收款方)退款：扣除可用余额、总收款和积分
-		if err := tx.Model(&model.User{}).
-			Where("id = ?", payeeUser.ID).
-			UpdateColumns(map[string]interface{}{
-				"available_balance": gorm.Expr("available_balance - ?", order.Amount),
-				"total_receive":     gorm.Expr("total_receive - ?", order.Amount),
-				"pay_score":         gorm.Expr("pay_score - ?", merchantScoreDecrease),
-			}).Error; err != nil {
-			return fmt.Errorf("商家退款失败: %w", err)
-		}
-
-		// 付款方收到退款：增加可用余额，减少总支付和支付积分
-		if err := tx.Model(&model.User{}).
-			Where("id = ?", payerUser.ID).
-			UpdateColumns(map[string]interface{}{
-				"available_balance": gorm.Expr("available_balance + ?", order.Amount),
-				"total_payment":     gorm.Expr("total_payment - ?", order.Amount),
-				"pay_score":         gorm.Expr("pay_score - ?", order.Amount.Round(0).IntPart()),
-			}).Error; err != nil {
-			return fmt.Errorf("付款方退款失败: %w", err)
+		// 商家(收款方)按分账明细冲回余额、总收款和积分，付款方收到全额退款
+		if err := service.RefundOrderAmount(tx, &order, &merchantPayConfig, order.Amount); err != nil {
+			return fmt.Errorf("退款失败: %w", err)
 		}
 
 		// 更新争议状态为已退款，handler_user_id 设为 0（系统自动处理）
@@ -175,6 +329,7 @@ func HandleAutoRefundSingleDispute(ctx context.Context, t *asynq.Task) error {
 			Updates(map[string]interface{}{
 				"status":          model.DisputeStatusRefund,
 				"handler_user_id": 0,
+				"refund_amount":   order.Amount,
 			}).Error; err != nil {
 			return fmt.Errorf("更新争议状态失败: %w", err)
 		}
@@ -182,7 +337,10 @@ func HandleAutoRefundSingleDispute(ctx context.Context, t *asynq.Task) error {
 		// 更新订单状态为已退款
 		if err := tx.Model(&model.Order{}).
 			Where("id = ?", order.ID).
-			Update("status", model.OrderStatusRefund).Error; err != nil {
+			Updates(map[string]interface{}{
+				"status":      model.OrderStatusRefund,
+				"refunded_at": time.Now(),
+			}).Error; err != nil {
 			return fmt.Errorf("更新订单状态失败: %w", err)
 		}
 
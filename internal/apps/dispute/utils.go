@@ -0,0 +1,86 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispute
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
+	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+)
+
+// resolveDisputeParty 加载争议并判断当前用户身份，仅争议双方（含具备争议权限的商户成员）与管理员可访问
+func resolveDisputeParty(c *gin.Context, tx *gorm.DB, disputeID uint64) (*model.Dispute, model.DisputeParty, error) {
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+	role, _ := util.GetFromContext[model.MerchantRole](c, merchant.MerchantRoleKey)
+
+	var dispute model.Dispute
+	if err := tx.Where("id = ?", disputeID).First(&dispute).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New(DisputeNotFound)
+		}
+		return nil, "", err
+	}
+
+	if dispute.InitiatorUserID == user.ID {
+		return &dispute, model.DisputePartyBuyer, nil
+	}
+
+	var order model.Order
+	if err := tx.Select("id", "payee_user_id").Where("id = ?", dispute.OrderID).First(&order).Error; err != nil {
+		return nil, "", err
+	}
+	if order.PayeeUserID == merchantUser.ID && role.HasPermission(model.MerchantPermissionDispute) {
+		return &dispute, model.DisputePartyMerchant, nil
+	}
+
+	if user.IsAdmin {
+		return &dispute, model.DisputePartyAdmin, nil
+	}
+
+	return nil, "", errors.New(DisputeAccessDenied)
+}
+
+// parseDisputeID 解析路径中的争议 ID
+func parseDisputeID(c *gin.Context) (uint64, error) {
+	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, errors.New(InvalidDisputeID)
+	}
+	return disputeID, nil
+}
+
+// disputeErrorStatus 争议访问错误对应的 HTTP 状态码
+func disputeErrorStatus(errMsg string) int {
+	switch errMsg {
+	case InvalidDisputeID, DisputeMessageClosed, EvidenceInvalid, TooManyAttachments:
+		return http.StatusBadRequest
+	case DisputeAccessDenied:
+		return http.StatusForbidden
+	case DisputeNotFound, AttachmentNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
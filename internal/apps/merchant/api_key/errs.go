@@ -17,6 +17,7 @@ limitations under the License.
 package api_key
 
 const (
-	APIKeyNotFound   = "API Key 不存在"
-	NoFieldsToUpdate = "没有需要更新的字段"
+	APIKeyNotFound     = "API Key 不存在"
+	NoFieldsToUpdate   = "没有需要更新的字段"
+	InvalidIPAllowlist = "IP 白名单格式错误，仅支持 IP 或 CIDR"
 )
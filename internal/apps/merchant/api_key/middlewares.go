@@ -21,7 +21,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/linux-do/credit/internal/apps/merchant"
-	"github.com/linux-do/credit/internal/apps/oauth"
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/util"
@@ -29,11 +28,11 @@ import (
 
 func RequireAPIKey() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+		merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 
 		var apiKey model.MerchantAPIKey
 		if err := db.DB(c.Request.Context()).
-			Where("id = ? AND user_id = ?", c.Param("id"), user.ID).
+			Where("id = ? AND user_id = ?", c.Param("id"), merchantUser.ID).
 			First(&apiKey).Error; err != nil {
 			c.AbortWithStatusJSON(http.StatusNotFound, util.Err(APIKeyNotFound))
 			return
@@ -17,35 +17,47 @@ limitations under the License.
 package api_key
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/linux-do/credit/internal/apps/merchant"
-	"github.com/linux-do/credit/internal/apps/oauth"
 	"github.com/linux-do/credit/internal/apps/payment"
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
 	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
 )
 
 type CreateAPIKeyRequest struct {
-	AppName        string `json:"app_name" binding:"required,max=20"`
-	AppHomepageURL string `json:"app_homepage_url" binding:"required,max=100,url"`
-	AppDescription string `json:"app_description" binding:"max=100"`
-	RedirectURI    string `json:"redirect_uri" binding:"omitempty,max=100,url"`
-	NotifyURL      string `json:"notify_url" binding:"required,max=100,url"`
-	PublicKey      string `json:"public_key" binding:"omitempty,max=100"`
-	TestMode       bool   `json:"test_mode"`
+	AppName        string           `json:"app_name" binding:"required,max=20"`
+	AppHomepageURL string           `json:"app_homepage_url" binding:"required,max=100,url"`
+	AppDescription string           `json:"app_description" binding:"max=100"`
+	RedirectURI    string           `json:"redirect_uri" binding:"omitempty,max=100,url"`
+	NotifyURL      string           `json:"notify_url" binding:"required,max=100,url"`
+	PublicKey      string           `json:"public_key" binding:"omitempty,max=100"`
+	RSAPublicKey   string           `json:"rsa_public_key" binding:"omitempty,max=2048"`
+	DisableMD5     bool             `json:"disable_md5"`
+	TestMode       bool             `json:"test_mode"`
+	IPAllowlist    []string         `json:"ip_allowlist" binding:"omitempty,max=20,dive,max=64"`
+	SplitRules     model.SplitRules `json:"split_rules"`
 }
 
 type UpdateAPIKeyRequest struct {
-	AppName        string `json:"app_name" binding:"omitempty,max=20"`
-	AppHomepageURL string `json:"app_homepage_url" binding:"omitempty,max=100,url"`
-	AppDescription string `json:"app_description" binding:"omitempty,max=100"`
-	RedirectURI    string `json:"redirect_uri" binding:"omitempty,max=100,url"`
-	NotifyURL      string `json:"notify_url" binding:"omitempty,max=100,url"`
-	PublicKey      string `json:"public_key" binding:"omitempty,max=100"`
-	TestMode       bool   `json:"test_mode"`
+	AppName        string            `json:"app_name" binding:"omitempty,max=20"`
+	AppHomepageURL string            `json:"app_homepage_url" binding:"omitempty,max=100,url"`
+	AppDescription string            `json:"app_description" binding:"omitempty,max=100"`
+	RedirectURI    string            `json:"redirect_uri" binding:"omitempty,max=100,url"`
+	NotifyURL      string            `json:"notify_url" binding:"omitempty,max=100,url"`
+	PublicKey      string            `json:"public_key" binding:"omitempty,max=100"`
+	RSAPublicKey   string            `json:"rsa_public_key" binding:"omitempty,max=2048"`
+	DisableMD5     bool              `json:"disable_md5"`
+	TestMode       bool              `json:"test_mode"`
+	IPAllowlist    *[]string         `json:"ip_allowlist" binding:"omitempty,max=20,dive,max=64"`
+	SplitRules     *model.SplitRules `json:"split_rules"`
 }
 
 type APIKeyListResponse struct {
@@ -53,10 +65,36 @@ type APIKeyListResponse struct {
 	Data  []model.MerchantAPIKey `json:"data"`
 }
 
+type ListIPDenialsRequest struct {
+	Page     int `form:"page" binding:"min=1"`
+	PageSize int `form:"page_size" binding:"min=1,max=100"`
+}
+
+type ListIPDenialsResponse struct {
+	Total    int64                        `json:"total"`
+	Page     int                          `json:"page"`
+	PageSize int                          `json:"page_size"`
+	Denials  []model.MerchantAPIKeyDenial `json:"denials"`
+}
+
+// normalizeIPAllowlist 校验并规范化 IP 白名单
+func normalizeIPAllowlist(entries []string) (util.StringArray, error) {
+	allowlist := make(util.StringArray, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := model.ParseIPPrefix(entry)
+		if err != nil {
+			return nil, errors.New(InvalidIPAllowlist)
+		}
+		allowlist = append(allowlist, prefix.String())
+	}
+	return allowlist, nil
+}
+
 // CreateAPIKey 创建商户 API Key
 // @Tags merchant
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param request body CreateAPIKeyRequest true "request body"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/merchant/api-keys [post]
@@ -67,10 +105,10 @@ func CreateAPIKey(c *gin.Context) {
 		return
 	}
 
-	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 
 	apiKey := model.MerchantAPIKey{
-		UserID:         user.ID,
+		UserID:         merchantUser.ID,
 		ClientID:       util.GenerateUniqueIDSimple(),
 		ClientSecret:   util.GenerateUniqueIDSimple(),
 		AppName:        req.AppName,
@@ -78,9 +116,27 @@ func CreateAPIKey(c *gin.Context) {
 		AppDescription: req.AppDescription,
 		RedirectURI:    req.RedirectURI,
 		NotifyURL:      req.NotifyURL,
+		DisableMD5:     req.DisableMD5,
 		TestMode:       req.TestMode,
 	}
 
+	if len(req.IPAllowlist) > 0 {
+		allowlist, err := normalizeIPAllowlist(req.IPAllowlist)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+			return
+		}
+		apiKey.IPAllowlist = allowlist
+	}
+
+	if len(req.SplitRules) > 0 {
+		if err := service.ValidateSplitRules(db.DB(c.Request.Context()), merchantUser.ID, req.SplitRules, nil); err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+			return
+		}
+		apiKey.SplitRules = req.SplitRules
+	}
+
 	if len(req.PublicKey) > 0 {
 		publicKeyBytes, err := util.Base64Decode(req.PublicKey)
 		if err != nil {
@@ -94,25 +150,36 @@ func CreateAPIKey(c *gin.Context) {
 		apiKey.PublicKey = publicKeyBytes
 	}
 
+	if req.RSAPublicKey != "" {
+		if _, err := util.ParseRSAPublicKey(req.RSAPublicKey); err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(payment.InvalidRSAPublicKey))
+			return
+		}
+		apiKey.RSAPublicKey = strings.TrimSpace(req.RSAPublicKey)
+	}
+
 	if err := db.DB(c.Request.Context()).Create(&apiKey).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		return
 	}
 
+	util.SetToContext(c, merchant.AuditTargetIDKey, strconv.FormatUint(apiKey.ID, 10))
+
 	c.JSON(http.StatusOK, util.OK(apiKey))
 }
 
 // ListAPIKeys 获取商户 API Key 列表
 // @Tags merchant
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/merchant/api-keys [get]
 func ListAPIKeys(c *gin.Context) {
-	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
 
 	var apiKeys []model.MerchantAPIKey
 	if err := db.DB(c.Request.Context()).
-		Where("user_id = ?", user.ID).
+		Where("user_id = ?", merchantUser.ID).
 		Order("created_at DESC").
 		Find(&apiKeys).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
@@ -125,6 +192,7 @@ func ListAPIKeys(c *gin.Context) {
 // GetAPIKey 获取单个商户 API Key
 // @Tags merchant
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/merchant/api-keys/{id} [get]
@@ -137,6 +205,7 @@ func GetAPIKey(c *gin.Context) {
 // @Tags merchant
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Param request body UpdateAPIKeyRequest true "request body"
 // @Success 200 {object} util.ResponseAny
@@ -156,9 +225,30 @@ func UpdateAPIKey(c *gin.Context) {
 		"app_description":  req.AppDescription,
 		"redirect_uri":     req.RedirectURI,
 		"notify_url":       req.NotifyURL,
+		"disable_md5":      req.DisableMD5,
 		"test_mode":        req.TestMode,
 	}
 
+	if req.IPAllowlist != nil {
+		allowlist, err := normalizeIPAllowlist(*req.IPAllowlist)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+			return
+		}
+		updates["ip_allowlist"] = allowlist
+	}
+
+	if req.SplitRules != nil {
+		if err := service.ValidateSplitRules(db.DB(c.Request.Context()), apiKey.UserID, *req.SplitRules, nil); err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+			return
+		}
+		updates["split_rules"] = *req.SplitRules
+		if len(*req.SplitRules) == 0 {
+			updates["split_rules"] = gorm.Expr("NULL")
+		}
+	}
+
 	if len(req.PublicKey) > 0 {
 		publicKeyBytes, err := util.Base64Decode(req.PublicKey)
 		if err != nil {
@@ -172,6 +262,14 @@ func UpdateAPIKey(c *gin.Context) {
 		updates["public_key"] = publicKeyBytes
 	}
 
+	if req.RSAPublicKey != "" {
+		if _, err := util.ParseRSAPublicKey(req.RSAPublicKey); err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(payment.InvalidRSAPublicKey))
+			return
+		}
+		updates["rsa_public_key"] = strings.TrimSpace(req.RSAPublicKey)
+	}
+
 	if err := db.DB(c.Request.Context()).
 		Model(&apiKey).
 		Updates(updates).Error; err != nil {
@@ -185,6 +283,7 @@ func UpdateAPIKey(c *gin.Context) {
 // DeleteAPIKey 删除商户 API Key
 // @Tags merchant
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/merchant/api-keys/{id} [delete]
@@ -198,3 +297,47 @@ func DeleteAPIKey(c *gin.Context) {
 
 	c.JSON(http.StatusOK, util.OKNil())
 }
+
+// ListIPDenials 获取 API Key 被 IP 白名单拒绝的访问记录
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Param request query ListIPDenialsRequest true "request query"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/ip-denials [get]
+func ListIPDenials(c *gin.Context) {
+	var req ListIPDenialsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	baseQuery := db.DB(c.Request.Context()).
+		Model(&model.MerchantAPIKeyDenial{}).
+		Where("merchant_api_key_id = ?", apiKey.ID)
+
+	response := &ListIPDenialsResponse{
+		Page:     req.Page,
+		PageSize: req.PageSize,
+		Denials:  []model.MerchantAPIKeyDenial{},
+	}
+
+	if err := baseQuery.Count(&response.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	if err := baseQuery.
+		Order("created_at DESC").
+		Offset((req.Page - 1) * req.PageSize).
+		Limit(req.PageSize).
+		Find(&response.Denials).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(response))
+}
@@ -19,4 +19,15 @@ package merchant
 const (
 	APIKeyObjKey      = "merchant_api_key_obj"
 	PaymentLinkObjKey = "payment_link_obj"
+	// MerchantUserObjKey 当前操作所属的商户（所有者）用户
+	MerchantUserObjKey = "merchant_user_obj"
+	// MerchantRoleKey 当前登录用户在该商户下的角色
+	MerchantRoleKey = "merchant_role"
+	// AuditTargetIDKey 审计日志的操作对象 ID，由处理函数按需设置
+	AuditTargetIDKey = "merchant_audit_target_id"
+)
+
+const (
+	// MerchantIDHeader 成员代表其他商户操作时携带的商户用户 ID 请求头
+	MerchantIDHeader = "X-Merchant-ID"
 )
@@ -17,7 +17,12 @@ limitations under the License.
 package link
 
 const (
-	PaymentLinkNotFound           = "支付链接不存在"
-	PaymentLinkTotalLimitExceeded = "该支付链接已达到付款次数上限"
-	PaymentLinkUserLimitExceeded  = "您已达到该链接的付款次数限制"
+	PaymentLinkNotFound          = "支付链接不存在"
+	PaymentLinkUserLimitExceeded = "您已达到该链接的付款次数限制"
+	InvalidAmountRange           = "最低金额不能大于最高金额"
+	PresetAmountOutOfRange       = "预设金额必须在最低与最高金额之间"
+	PaymentAmountOutOfRange      = "付款金额不在该链接允许的范围内"
+	PayerMessageNotAllowed       = "该支付链接不支持留言"
+	InvalidTimeWindow            = "结束时间必须晚于开始时间"
+	MerchantSuspended            = "该商户已暂停收款"
 )
@@ -20,6 +20,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -27,6 +30,7 @@ import (
 	"github.com/linux-do/credit/internal/apps/oauth"
 	"github.com/linux-do/credit/internal/common"
 	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/service"
 	"github.com/linux-do/credit/internal/util"
@@ -36,24 +40,50 @@ import (
 
 // PayByLinkRequest 通过支付链接支付请求
 type PayByLinkRequest struct {
-	Token  string `json:"token" binding:"required"`
-	PayKey string `json:"pay_key" binding:"required,max=6"`
-	Remark string `json:"remark" binding:"max=100"`
+	Token   string          `json:"token" binding:"required"`
+	PayKey  string          `json:"pay_key" binding:"required,max=6"`
+	Remark  string          `json:"remark" binding:"max=100"`
+	Amount  decimal.Decimal `json:"amount"`
+	Message string          `json:"message" binding:"max=100"`
 }
 
 // PaymentLinkRequest 创建支付链接请求
 type PaymentLinkRequest struct {
-	Amount      decimal.Decimal `json:"amount" binding:"required"`
-	ProductName string          `json:"product_name" binding:"required,max=30"`
-	Remark      string          `json:"remark" binding:"max=100"`
-	TotalLimit  *uint           `json:"total_limit" binding:"omitempty,min=1"`
-	UserLimit   *uint           `json:"user_limit" binding:"omitempty,min=1"`
+	Amount        decimal.Decimal   `json:"amount"`
+	AmountType    string            `json:"amount_type" binding:"omitempty,oneof=fixed custom"`
+	MinAmount     *decimal.Decimal  `json:"min_amount"`
+	MaxAmount     *decimal.Decimal  `json:"max_amount"`
+	PresetAmounts []decimal.Decimal `json:"preset_amounts" binding:"omitempty,max=10"`
+	AllowMessage  bool              `json:"allow_message"`
+	ProductName   string            `json:"product_name" binding:"required,max=30"`
+	Remark        string            `json:"remark" binding:"max=100"`
+	TotalLimit    *uint             `json:"total_limit" binding:"omitempty,min=1"`
+	UserLimit     *uint             `json:"user_limit" binding:"omitempty,min=1"`
+	StartsAt      *time.Time        `json:"starts_at"`
+	EndsAt        *time.Time        `json:"ends_at"`
+	IsPaused      bool              `json:"is_paused"`
+}
+
+// PausePaymentLinkRequest 暂停/恢复支付链接请求
+type PausePaymentLinkRequest struct {
+	Paused bool `json:"paused"`
+}
+
+// PaymentLinkStats 支付链接转化统计
+type PaymentLinkStats struct {
+	Views          int64           `json:"views"`
+	SuccessCount   int64           `json:"success_count"`
+	UniquePayers   int64           `json:"unique_payers"`
+	Revenue        decimal.Decimal `json:"revenue"`
+	ConversionRate decimal.Decimal `json:"conversion_rate"`
+	RemainingStock *uint           `json:"remaining_stock"`
 }
 
 // CreatePaymentLink 创建支付链接
 // @Tags merchant
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Param request body PaymentLinkRequest true "创建支付链接请求"
 // @Success 200 {object} util.ResponseAny
@@ -66,7 +96,7 @@ func CreatePaymentLink(c *gin.Context) {
 	}
 
 	// 验证金额
-	if err := util.ValidateAmount(req.Amount); err != nil {
+	if err := normalizePaymentLinkRequest(&req); err != nil {
 		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
 		return
 	}
@@ -77,10 +107,19 @@ func CreatePaymentLink(c *gin.Context) {
 		MerchantAPIKeyID: apiKey.ID,
 		Token:            util.GenerateUniqueIDSimple(),
 		Amount:           req.Amount,
+		AmountType:       model.PaymentLinkAmountType(req.AmountType),
+		MinAmount:        req.MinAmount,
+		MaxAmount:        req.MaxAmount,
+		PresetAmounts:    req.PresetAmounts,
+		AllowMessage:     req.AllowMessage,
 		ProductName:      req.ProductName,
 		Remark:           req.Remark,
 		TotalLimit:       req.TotalLimit,
 		UserLimit:        req.UserLimit,
+		RemainingStock:   req.TotalLimit,
+		StartsAt:         req.StartsAt,
+		EndsAt:           req.EndsAt,
+		IsPaused:         req.IsPaused,
 	}
 
 	if err := db.DB(c.Request.Context()).Create(&paymentLink).Error; err != nil {
@@ -88,26 +127,38 @@ func CreatePaymentLink(c *gin.Context) {
 		return
 	}
 
+	util.SetToContext(c, merchant.AuditTargetIDKey, strconv.FormatUint(paymentLink.ID, 10))
+
 	c.JSON(http.StatusOK, util.OK(paymentLink))
 }
 
 // PaymentLinkDetail 支付链接详情
 type PaymentLinkDetail struct {
-	ID          uint64          `json:"id,string"`
-	Token       string          `json:"token"`
-	Amount      decimal.Decimal `json:"amount"`
-	ProductName string          `json:"product_name"`
-	Remark      string          `json:"remark"`
-	TotalLimit  *uint           `json:"total_limit"`
-	UserLimit   *uint           `json:"user_limit"`
-	CreatedAt   time.Time       `json:"created_at"`
-	AppName     string          `json:"app_name"`
-	RedirectURI string          `json:"redirect_uri"`
+	ID             uint64                      `json:"id,string"`
+	Token          string                      `json:"token"`
+	Amount         decimal.Decimal             `json:"amount"`
+	AmountType     model.PaymentLinkAmountType `json:"amount_type"`
+	MinAmount      *decimal.Decimal            `json:"min_amount"`
+	MaxAmount      *decimal.Decimal            `json:"max_amount"`
+	PresetAmounts  util.DecimalArray           `json:"preset_amounts"`
+	AllowMessage   bool                        `json:"allow_message"`
+	ProductName    string                      `json:"product_name"`
+	Remark         string                      `json:"remark"`
+	TotalLimit     *uint                       `json:"total_limit"`
+	UserLimit      *uint                       `json:"user_limit"`
+	RemainingStock *uint                       `json:"remaining_stock"`
+	StartsAt       *time.Time                  `json:"starts_at"`
+	EndsAt         *time.Time                  `json:"ends_at"`
+	IsPaused       bool                        `json:"is_paused"`
+	CreatedAt      time.Time                   `json:"created_at"`
+	AppName        string                      `json:"app_name"`
+	RedirectURI    string                      `json:"redirect_uri"`
 }
 
 // ListPaymentLinks 获取支付链接列表
 // @Tags merchant
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Success 200 {object} util.ResponseAny
 // @Router /api/v1/merchant/api-keys/{id}/payment-links [get]
@@ -117,7 +168,7 @@ func ListPaymentLinks(c *gin.Context) {
 	var paymentLinks []PaymentLinkDetail
 	if err := db.DB(c.Request.Context()).
 		Table("merchant_payment_links").
-		Select("merchant_payment_links.id, merchant_payment_links.token, merchant_payment_links.amount, merchant_payment_links.product_name, merchant_payment_links.remark, merchant_payment_links.total_limit, merchant_payment_links.user_limit, merchant_payment_links.created_at, merchant_api_keys.app_name, merchant_api_keys.redirect_uri").
+		Select("merchant_payment_links.id, merchant_payment_links.token, merchant_payment_links.amount, merchant_payment_links.amount_type, merchant_payment_links.min_amount, merchant_payment_links.max_amount, merchant_payment_links.preset_amounts, merchant_payment_links.allow_message, merchant_payment_links.product_name, merchant_payment_links.remark, merchant_payment_links.total_limit, merchant_payment_links.user_limit, merchant_payment_links.remaining_stock, merchant_payment_links.starts_at, merchant_payment_links.ends_at, merchant_payment_links.is_paused, merchant_payment_links.created_at, merchant_api_keys.app_name, merchant_api_keys.redirect_uri").
 		Joins("JOIN merchant_api_keys ON merchant_api_keys.id = merchant_payment_links.merchant_api_key_id").
 		Where("merchant_payment_links.merchant_api_key_id = ? AND merchant_payment_links.deleted_at IS NULL", apiKey.ID).
 		Order("merchant_payment_links.created_at DESC").
@@ -139,7 +190,7 @@ func GetPaymentLinkByToken(c *gin.Context) {
 	var paymentLink PaymentLinkDetail
 	if err := db.DB(c.Request.Context()).
 		Table("merchant_payment_links").
-		Select("merchant_payment_links.id, merchant_payment_links.token, merchant_payment_links.amount, merchant_payment_links.product_name, merchant_payment_links.remark, merchant_payment_links.created_at, merchant_api_keys.app_name, merchant_api_keys.redirect_uri").
+		Select("merchant_payment_links.id, merchant_payment_links.token, merchant_payment_links.amount, merchant_payment_links.amount_type, merchant_payment_links.min_amount, merchant_payment_links.max_amount, merchant_payment_links.preset_amounts, merchant_payment_links.allow_message, merchant_payment_links.product_name, merchant_payment_links.remark, merchant_payment_links.remaining_stock, merchant_payment_links.starts_at, merchant_payment_links.ends_at, merchant_payment_links.is_paused, merchant_payment_links.created_at, merchant_api_keys.app_name, merchant_api_keys.redirect_uri").
 		Joins("JOIN merchant_api_keys ON merchant_api_keys.id = merchant_payment_links.merchant_api_key_id").
 		Where("merchant_payment_links.token = ? AND merchant_payment_links.deleted_at IS NULL", c.Param("token")).
 		First(&paymentLink).Error; err != nil {
@@ -151,12 +202,18 @@ func GetPaymentLinkByToken(c *gin.Context) {
 		return
 	}
 
+	// 记录浏览次数，失败不影响展示
+	if err := (&model.MerchantPaymentLink{ID: paymentLink.ID}).IncreaseViewCount(db.DB(c.Request.Context())); err != nil {
+		logger.ErrorF(c.Request.Context(), "记录支付链接[ID:%d]浏览次数失败: %v", paymentLink.ID, err)
+	}
+
 	c.JSON(http.StatusOK, util.OK(paymentLink))
 }
 
 // DeletePaymentLink 删除支付链接
 // @Tags merchant
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Param linkId path uint64 true "Payment Link ID"
 // @Success 200 {object} util.ResponseAny
@@ -186,6 +243,7 @@ func DeletePaymentLink(c *gin.Context) {
 // @Tags merchant
 // @Accept json
 // @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
 // @Param id path uint64 true "API Key ID"
 // @Param linkId path uint64 true "Payment Link ID"
 // @Param request body PaymentLinkRequest true "更新支付链接请求"
@@ -199,7 +257,7 @@ func UpdatePaymentLink(c *gin.Context) {
 	}
 
 	// 验证金额
-	if err := util.ValidateAmount(req.Amount); err != nil {
+	if err := normalizePaymentLinkRequest(&req); err != nil {
 		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
 		return
 	}
@@ -207,16 +265,76 @@ func UpdatePaymentLink(c *gin.Context) {
 	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
 	linkID := c.Param("linkId")
 
+	var paymentLink model.MerchantPaymentLink
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND merchant_api_key_id = ?", linkID, apiKey.ID).
+		First(&paymentLink).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(PaymentLinkNotFound))
+		} else {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
+		return
+	}
+
+	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		// 与支付流程共用锁，保证库存重算期间没有并发扣减
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", paymentLink.ID).Error; err != nil {
+			return err
+		}
+
+		remainingStock, err := calculateRemainingStock(tx, paymentLink.ID, req.TotalLimit)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&paymentLink).Updates(map[string]interface{}{
+			"amount":          req.Amount,
+			"amount_type":     req.AmountType,
+			"min_amount":      req.MinAmount,
+			"max_amount":      req.MaxAmount,
+			"preset_amounts":  util.DecimalArray(req.PresetAmounts),
+			"allow_message":   req.AllowMessage,
+			"product_name":    req.ProductName,
+			"remark":          req.Remark,
+			"total_limit":     req.TotalLimit,
+			"user_limit":      req.UserLimit,
+			"remaining_stock": remainingStock,
+			"starts_at":       req.StartsAt,
+			"ends_at":         req.EndsAt,
+			"is_paused":       req.IsPaused,
+		}).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
+
+// PausePaymentLink 暂停或恢复支付链接
+// @Tags merchant
+// @Accept json
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Param linkId path uint64 true "Payment Link ID"
+// @Param request body PausePaymentLinkRequest true "暂停请求"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/payment-links/{linkId}/pause [put]
+func PausePaymentLink(c *gin.Context) {
+	var req PausePaymentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
 	result := db.DB(c.Request.Context()).
 		Model(&model.MerchantPaymentLink{}).
-		Where("id = ? AND merchant_api_key_id = ?", linkID, apiKey.ID).
-		Updates(map[string]interface{}{
-			"amount":       req.Amount,
-			"product_name": req.ProductName,
-			"remark":       req.Remark,
-			"total_limit":  req.TotalLimit,
-			"user_limit":   req.UserLimit,
-		})
+		Where("id = ? AND merchant_api_key_id = ?", c.Param("linkId"), apiKey.ID).
+		Update("is_paused", req.Paused)
 
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, util.Err(result.Error.Error()))
@@ -231,6 +349,49 @@ func UpdatePaymentLink(c *gin.Context) {
 	c.JSON(http.StatusOK, util.OKNil())
 }
 
+// GetPaymentLinkStats 获取支付链接转化统计
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Param linkId path uint64 true "Payment Link ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/payment-links/{linkId}/stats [get]
+func GetPaymentLinkStats(c *gin.Context) {
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	var paymentLink model.MerchantPaymentLink
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND merchant_api_key_id = ?", c.Param("linkId"), apiKey.ID).
+		First(&paymentLink).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(PaymentLinkNotFound))
+		} else {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
+		return
+	}
+
+	stats := PaymentLinkStats{
+		Views:          paymentLink.ViewCount,
+		RemainingStock: paymentLink.RemainingStock,
+	}
+	if err := db.DB(c.Request.Context()).
+		Model(&model.Order{}).
+		Select("COUNT(*) AS success_count, COUNT(DISTINCT payer_user_id) AS unique_payers, COALESCE(SUM(amount), 0) AS revenue").
+		Where("payment_link_id = ? AND status = ?", paymentLink.ID, model.OrderStatusSuccess).
+		Scan(&stats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	if stats.Views > 0 {
+		stats.ConversionRate = decimal.NewFromInt(stats.SuccessCount).Div(decimal.NewFromInt(stats.Views)).Round(4)
+	}
+
+	c.JSON(http.StatusOK, util.OK(stats))
+}
+
 // PayByLink 通过支付链接支付
 // @Tags merchant
 // @Accept json
@@ -251,6 +412,31 @@ func PayByLink(c *gin.Context) {
 		return
 	}
 
+	// 检查链接是否处于可付款状态
+	if err := paymentLink.CheckAvailable(time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	// 确定付款金额：自定义金额链接由付款人填写，需在允许范围内
+	amount := paymentLink.Amount
+	if paymentLink.IsCustomAmount() {
+		if err := util.ValidateAmount(req.Amount); err != nil {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+			return
+		}
+		if !paymentLink.IsAmountAllowed(req.Amount) {
+			c.JSON(http.StatusBadRequest, util.Err(PaymentAmountOutOfRange))
+			return
+		}
+		amount = req.Amount
+	}
+
+	if req.Message != "" && !paymentLink.AllowMessage {
+		c.JSON(http.StatusBadRequest, util.Err(PayerMessageNotAllowed))
+		return
+	}
+
 	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
 
 	if !currentUser.VerifyPayKey(req.PayKey) {
@@ -259,7 +445,7 @@ func PayByLink(c *gin.Context) {
 	}
 
 	// 检查余额是否足够
-	if currentUser.AvailableBalance.LessThan(paymentLink.Amount) {
+	if currentUser.AvailableBalance.LessThan(amount) {
 		c.JSON(http.StatusBadRequest, util.Err(common.InsufficientBalance))
 		return
 	}
@@ -270,6 +456,10 @@ func PayByLink(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		return
 	}
+	if merchantAPIKey.IsSuspended() {
+		c.JSON(http.StatusForbidden, util.Err(MerchantSuspended))
+		return
+	}
 
 	// 查询商户用户
 	var merchantUser model.User
@@ -286,7 +476,7 @@ func PayByLink(c *gin.Context) {
 
 	// 获取商户的支付配置
 	var merchantPayConfig model.UserPayConfig
-	if err := merchantPayConfig.GetByPayScore(db.DB(c.Request.Context()), merchantUser.PayScore); err != nil {
+	if err := merchantPayConfig.GetByMerchant(db.DB(c.Request.Context()), &merchantUser); err != nil {
 		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		return
 	}
@@ -304,23 +494,15 @@ func PayByLink(c *gin.Context) {
 		func(tx *gorm.DB) error {
 			// 非测试模式
 			if !isTestMode {
-				if paymentLink.TotalLimit != nil || paymentLink.UserLimit != nil {
+				if paymentLink.RemainingStock != nil || paymentLink.UserLimit != nil {
 					if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", paymentLink.ID).Error; err != nil {
 						return err
 					}
 				}
 
-				// 检查总付款次数限制
-				if paymentLink.TotalLimit != nil {
-					var totalCount int64
-					if err := tx.Table("orders").
-						Where("payment_link_id = ? AND status = ?", paymentLink.ID, model.OrderStatusSuccess).
-						Count(&totalCount).Error; err != nil {
-						return err
-					}
-					if totalCount >= int64(*paymentLink.TotalLimit) {
-						return errors.New(PaymentLinkTotalLimitExceeded)
-					}
+				// 扣减库存
+				if err := paymentLink.DecreaseStock(tx); err != nil {
+					return err
 				}
 
 				// 检查单用户付款次数限制
@@ -338,13 +520,13 @@ func PayByLink(c *gin.Context) {
 				}
 
 				// 检查每日限额
-				if err := service.CheckDailyLimit(tx, currentUser.ID, paymentLink.Amount, payerPayConfig.DailyLimit); err != nil {
+				if err := service.CheckDailyLimit(tx, currentUser.ID, amount, payerPayConfig.DailyLimit); err != nil {
 					return err
 				}
 			}
 
 			// 计算手续费
-			_, merchantAmount, feePercent := service.CalculateFee(paymentLink.Amount, merchantPayConfig.FeeRate)
+			_, _, feePercent := service.CalculateFee(amount, merchantPayConfig.FeeRate)
 
 			var remark string
 			var orderType model.OrderType
@@ -355,11 +537,9 @@ func PayByLink(c *gin.Context) {
 				orderType = model.OrderTypeTest
 			} else {
 				feeRemark := fmt.Sprintf("[系统]: 收取商家%d%%手续费", feePercent)
-				if req.Remark != "" {
-					remark = req.Remark + " " + feeRemark
-				} else {
-					remark = feeRemark
-				}
+				remark = strings.Join(slices.DeleteFunc([]string{req.Remark, formatPayerMessage(req.Message), feeRemark}, func(s string) bool {
+					return s == ""
+				}), " ")
 				orderType = model.OrderTypeOnline
 				paymentLinkID = &paymentLink.ID
 			}
@@ -370,55 +550,43 @@ func PayByLink(c *gin.Context) {
 				PayerUserID:   currentUser.ID,
 				PayeeUserID:   merchantUser.ID,
 				ClientID:      merchantAPIKey.ClientID,
-				Amount:        paymentLink.Amount,
+				Amount:        amount,
 				Status:        model.OrderStatusSuccess,
 				Type:          orderType,
 				Remark:        remark,
+				PayerMessage:  req.Message,
 				PaymentLinkID: paymentLinkID,
 				TradeTime:     time.Now(),
 				ExpiresAt:     time.Now(),
+				IsSandbox:     isTestMode,
 			}
 			if err := tx.Create(&order).Error; err != nil {
 				return err
 			}
 
+			// 测试模式：仅变动沙箱模拟余额
+			if isTestMode {
+				if err := service.ApplySandboxPayment(tx, merchantAPIKey.ID, &order, merchantPayConfig.FeeRate); err != nil {
+					return err
+				}
+			}
+
 			// 非测试模式：扣减用户余额和增加商户余额
 			if !isTestMode {
 				// 扣用户
 				if err := service.UpdateBalance(tx, service.BalanceUpdateOptions{
 					UserID:       currentUser.ID,
-					Amount:       paymentLink.Amount,
+					Amount:       amount,
 					Operation:    service.BalanceDeduct,
-					ScoreChange:  paymentLink.Amount.Round(0).IntPart(),
+					ScoreChange:  amount.Round(0).IntPart(),
 					TotalField:   "total_payment",
 					CheckBalance: true,
 				}); err != nil {
 					return err
 				}
 
-				// 加商家
-				merchantScoreIncrease := paymentLink.Amount.Mul(merchantPayConfig.ScoreRate).Round(0).IntPart()
-				if err := service.UpdateBalance(tx, service.BalanceUpdateOptions{
-					UserID:        merchantUser.ID,
-					Amount:        merchantAmount,
-					Operation:     service.BalanceAdd,
-					ScoreChange:   merchantScoreIncrease,
-					TotalField:    "total_receive",
-					CheckBalance:  false,
-					AsyncTransfer: true,
-				}); err != nil {
-					return err
-				}
-
-				// 创建异步流转记录
-				orderTransfer := model.OrderTransfer{
-					OrderID:     order.ID,
-					PayeeUserID: merchantUser.ID,
-					Amount:      merchantAmount,
-					Status:      model.OrderTransferStatusPending,
-					TransferAt:  model.GetRandomSettleAt(c.Request.Context()),
-				}
-				if err := tx.Create(&orderTransfer).Error; err != nil {
+				// 按 API Key 的分账规则加给商家及各收款方
+				if err := service.CreditMerchantPayees(tx, &order, &merchantPayConfig, merchantAPIKey.SplitRules); err != nil {
 					return err
 				}
 			}
@@ -429,7 +597,8 @@ func PayByLink(c *gin.Context) {
 		errMsg := err.Error()
 		switch errMsg {
 		case common.InsufficientBalance, common.DailyLimitExceeded,
-			PaymentLinkTotalLimitExceeded, PaymentLinkUserLimitExceeded:
+			common.PaymentLinkSoldOut, PaymentLinkUserLimitExceeded,
+			common.SplitAmountExceeded, common.SplitPayeeInvalid:
 			c.JSON(http.StatusBadRequest, util.Err(errMsg))
 		default:
 			c.JSON(http.StatusInternalServerError, util.Err(errMsg))
@@ -0,0 +1,106 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package link
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// normalizePaymentLinkRequest 校验并规范化支付链接金额配置
+func normalizePaymentLinkRequest(req *PaymentLinkRequest) error {
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.New(InvalidTimeWindow)
+	}
+
+	if req.AmountType == "" {
+		req.AmountType = string(model.PaymentLinkAmountTypeFixed)
+	}
+
+	// 固定金额：忽略自定义金额相关配置
+	if req.AmountType == string(model.PaymentLinkAmountTypeFixed) {
+		req.MinAmount = nil
+		req.MaxAmount = nil
+		req.PresetAmounts = nil
+		return util.ValidateAmount(req.Amount)
+	}
+
+	// 自定义金额：链接本身不设固定金额
+	req.Amount = decimal.Zero
+
+	for _, bound := range []*decimal.Decimal{req.MinAmount, req.MaxAmount} {
+		if bound == nil {
+			continue
+		}
+		if err := util.ValidateAmount(*bound); err != nil {
+			return err
+		}
+	}
+	if req.MinAmount != nil && req.MaxAmount != nil && req.MinAmount.GreaterThan(*req.MaxAmount) {
+		return errors.New(InvalidAmountRange)
+	}
+
+	link := model.MerchantPaymentLink{MinAmount: req.MinAmount, MaxAmount: req.MaxAmount}
+	for _, preset := range req.PresetAmounts {
+		if err := util.ValidateAmount(preset); err != nil {
+			return err
+		}
+		if !link.IsAmountAllowed(preset) {
+			return errors.New(PresetAmountOutOfRange)
+		}
+	}
+
+	return nil
+}
+
+// formatPayerMessage 格式化付款人留言，写入订单备注
+func formatPayerMessage(message string) string {
+	if message == "" {
+		return ""
+	}
+	return fmt.Sprintf("[留言]: %s", message)
+}
+
+// soldOrderStatuses 占用支付链接库存的订单状态，已付款且未全额退款
+var soldOrderStatuses = []model.OrderStatus{
+	model.OrderStatusSuccess,
+	model.OrderStatusDisputing,
+	model.OrderStatusRefused,
+	model.OrderStatusPartialRefund,
+}
+
+// calculateRemainingStock 根据总量与已售出数重新计算剩余库存，全额退款的订单不计入已售出
+func calculateRemainingStock(tx *gorm.DB, linkID uint64, totalLimit *uint) (*uint, error) {
+	if totalLimit == nil {
+		return nil, nil
+	}
+
+	var soldCount int64
+	if err := tx.Model(&model.Order{}).
+		Where("payment_link_id = ? AND status IN ?", linkID, soldOrderStatuses).
+		Count(&soldCount).Error; err != nil {
+		return nil, err
+	}
+
+	remaining := uint(max(int64(*totalLimit)-soldCount, 0))
+	return &remaining, nil
+}
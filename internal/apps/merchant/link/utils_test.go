@@ -0,0 +1,78 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package link
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestCalculateRemainingStock 已付款未全额退款的订单计入已售出，全额退款与未付款订单不计入
+func TestCalculateRemainingStock(t *testing.T) {
+	tx, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := tx.AutoMigrate(&model.Order{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	linkID := uint64(10)
+	statuses := []model.OrderStatus{
+		model.OrderStatusSuccess,
+		model.OrderStatusDisputing,
+		model.OrderStatusRefused,
+		model.OrderStatusPartialRefund,
+		model.OrderStatusRefund,
+		model.OrderStatusPending,
+		model.OrderStatusExpired,
+	}
+	for i, status := range statuses {
+		order := &model.Order{
+			ID:            uint64(100 + i),
+			OrderName:     "link",
+			Amount:        decimal.NewFromInt(1),
+			Status:        status,
+			Type:          model.OrderTypePayment,
+			PaymentLinkID: &linkID,
+			ExpiresAt:     time.Now(),
+		}
+		if err := tx.Create(order).Error; err != nil {
+			t.Fatalf("create order: %v", err)
+		}
+	}
+
+	totalLimit := uint(10)
+	remaining, err := calculateRemainingStock(tx, linkID, &totalLimit)
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if remaining == nil || *remaining != 6 {
+		t.Fatalf("remaining = %v, want 6", remaining)
+	}
+
+	totalLimit = 3
+	if remaining, err = calculateRemainingStock(tx, linkID, &totalLimit); err != nil || *remaining != 0 {
+		t.Fatalf("remaining = %v, err = %v, want 0", remaining, err)
+	}
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package member
+
+// 审计操作类型
+const (
+	AuditActionAPIKeyCreate        = "api_key.create"
+	AuditActionAPIKeyUpdate        = "api_key.update"
+	AuditActionAPIKeyDelete        = "api_key.delete"
+	AuditActionSandboxReset        = "sandbox.reset"
+	AuditActionSandboxSimulate     = "sandbox.simulate"
+	AuditActionPaymentLinkCreate   = "payment_link.create"
+	AuditActionPaymentLinkUpdate   = "payment_link.update"
+	AuditActionPaymentLinkDelete   = "payment_link.delete"
+	AuditActionPaymentLinkPause    = "payment_link.pause"
+	AuditActionDisputeRefundReview = "dispute.refund_review"
+	AuditActionDisputeRefundOffer  = "dispute.refund_offer"
+	AuditActionMemberInvite        = "member.invite"
+	AuditActionMemberUpdate        = "member.update"
+	AuditActionMemberRemove        = "member.remove"
+)
@@ -0,0 +1,30 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package member
+
+const (
+	InvalidMerchantID        = "商户 ID 格式错误"
+	NotMerchantMember        = "您不是该商户的成员"
+	MerchantPermissionDenied = "当前角色无权执行该操作"
+	MemberNotFound           = "成员不存在"
+	MemberUserNotFound       = "被邀请用户不存在"
+	MemberAlreadyExists      = "该用户已是商户成员或已被邀请"
+	CannotInviteSelf         = "不能邀请商户所有者自己"
+	OnlyOwnerCanManageAdmin  = "仅商户所有者可以授予或管理管理员角色"
+	InvitationNotFound       = "邀请不存在或已处理"
+	MembershipNotFound       = "成员身份不存在"
+)
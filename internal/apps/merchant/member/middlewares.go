@@ -0,0 +1,138 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package member
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
+	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+)
+
+// RequireMerchantContext 解析当前操作所属的商户
+// 未携带 X-Merchant-ID 或与当前用户相同时，以所有者身份操作自己的商户；
+// 否则要求当前用户是该商户的有效成员
+func RequireMerchantContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+		merchantIDStr := c.GetHeader(merchant.MerchantIDHeader)
+		if merchantIDStr == "" {
+			util.SetToContext(c, merchant.MerchantUserObjKey, user)
+			util.SetToContext(c, merchant.MerchantRoleKey, model.MerchantRoleOwner)
+			c.Next()
+			return
+		}
+
+		merchantUserID, err := strconv.ParseUint(merchantIDStr, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, util.Err(InvalidMerchantID))
+			return
+		}
+
+		if merchantUserID == user.ID {
+			util.SetToContext(c, merchant.MerchantUserObjKey, user)
+			util.SetToContext(c, merchant.MerchantRoleKey, model.MerchantRoleOwner)
+			c.Next()
+			return
+		}
+
+		var member model.MerchantMember
+		if err := member.GetActiveMembership(db.DB(c.Request.Context()), merchantUserID, user.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.AbortWithStatusJSON(http.StatusForbidden, util.Err(NotMerchantMember))
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, util.Err(err.Error()))
+			return
+		}
+
+		var merchantUser model.User
+		if err := merchantUser.GetByID(db.DB(c.Request.Context()), merchantUserID); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, util.Err(NotMerchantMember))
+			return
+		}
+
+		util.SetToContext(c, merchant.MerchantUserObjKey, &merchantUser)
+		util.SetToContext(c, merchant.MerchantRoleKey, member.Role)
+
+		c.Next()
+	}
+}
+
+// RequirePermission 要求当前角色拥有指定权限
+func RequirePermission(permission model.MerchantPermission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := util.GetFromContext[model.MerchantRole](c, merchant.MerchantRoleKey)
+		if !role.HasPermission(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, util.Err(MerchantPermissionDenied))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuditAction 在写操作成功后记录审计日志，归属到实际执行操作的成员
+func AuditAction(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.IsAborted() || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+		merchantUser, ok := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+		if user == nil || !ok {
+			return
+		}
+		role, _ := util.GetFromContext[model.MerchantRole](c, merchant.MerchantRoleKey)
+
+		targetID, _ := util.GetFromContext[string](c, merchant.AuditTargetIDKey)
+		if targetID == "" {
+			for _, param := range []string{"memberId", "linkId", "id"} {
+				if value := c.Param(param); value != "" {
+					targetID = value
+					break
+				}
+			}
+		}
+
+		auditLog := model.MerchantAuditLog{
+			MerchantUserID: merchantUser.ID,
+			ActorUserID:    user.ID,
+			ActorRole:      string(role),
+			Action:         action,
+			Method:         c.Request.Method,
+			Path:           util.TruncateString(c.FullPath(), 255),
+			TargetID:       targetID,
+			ClientIP:       c.ClientIP(),
+		}
+		if err := db.DB(c.Request.Context()).Create(&auditLog).Error; err != nil {
+			logger.ErrorF(c.Request.Context(), "记录商户审计日志失败: 商户[%d] 操作者[%d] 操作[%s] 错误: %v", merchantUser.ID, user.ID, action, err)
+		}
+	}
+}
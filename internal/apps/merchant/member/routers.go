@@ -0,0 +1,369 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package member
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
+	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InviteMemberRequest struct {
+	Username string `json:"username" binding:"required,max=64"`
+	Role     string `json:"role" binding:"required,oneof=admin developer support finance"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin developer support finance"`
+}
+
+type ListAuditLogsRequest struct {
+	Page        int     `form:"page" binding:"min=1"`
+	PageSize    int     `form:"page_size" binding:"min=1,max=100"`
+	ActorUserID *uint64 `form:"actor_user_id"`
+	Action      string  `form:"action" binding:"omitempty,max=64"`
+}
+
+type ListAuditLogsResponse struct {
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+	Logs     []model.MerchantAuditLog `json:"logs"`
+}
+
+// checkAdminManagement 仅所有者可以授予管理员角色或管理已有管理员
+func checkAdminManagement(operatorRole model.MerchantRole, roles ...model.MerchantRole) error {
+	if operatorRole == model.MerchantRoleOwner {
+		return nil
+	}
+	for _, role := range roles {
+		if role == model.MerchantRoleAdmin {
+			return errors.New(OnlyOwnerCanManageAdmin)
+		}
+	}
+	return nil
+}
+
+// ListMembers 获取商户成员列表
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/members [get]
+func ListMembers(c *gin.Context) {
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+
+	members := []model.MerchantMember{}
+	if err := db.DB(c.Request.Context()).
+		Model(&model.MerchantMember{}).
+		Select("merchant_members.*, users.username").
+		Joins("JOIN users ON users.id = merchant_members.user_id").
+		Where("merchant_members.merchant_user_id = ?", merchantUser.ID).
+		Order("merchant_members.created_at DESC").
+		Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(members))
+}
+
+// InviteMember 邀请用户加入商户
+// @Tags merchant
+// @Accept json
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param request body InviteMemberRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/members [post]
+func InviteMember(c *gin.Context) {
+	var req InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+	operatorRole, _ := util.GetFromContext[model.MerchantRole](c, merchant.MerchantRoleKey)
+
+	role := model.MerchantRole(req.Role)
+	if err := checkAdminManagement(operatorRole, role); err != nil {
+		c.JSON(http.StatusForbidden, util.Err(err.Error()))
+		return
+	}
+
+	var invitee model.User
+	if err := db.DB(c.Request.Context()).
+		Where("username = ? AND is_active = ?", req.Username, true).
+		First(&invitee).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(MemberUserNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	if invitee.ID == merchantUser.ID {
+		c.JSON(http.StatusBadRequest, util.Err(CannotInviteSelf))
+		return
+	}
+
+	member := model.MerchantMember{
+		MerchantUserID:  merchantUser.ID,
+		UserID:          invitee.ID,
+		Role:            role,
+		Status:          model.MerchantMemberStatusPending,
+		InvitedByUserID: user.ID,
+	}
+	if err := db.DB(c.Request.Context()).Create(&member).Error; err != nil {
+		if strings.Contains(err.Error(), "SQLSTATE 23505") {
+			c.JSON(http.StatusBadRequest, util.Err(MemberAlreadyExists))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	member.Username = invitee.Username
+
+	util.SetToContext(c, merchant.AuditTargetIDKey, strconv.FormatUint(member.ID, 10))
+
+	c.JSON(http.StatusOK, util.OK(member))
+}
+
+// UpdateMember 修改成员角色
+// @Tags merchant
+// @Accept json
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param memberId path string true "成员 ID"
+// @Param request body UpdateMemberRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/members/{memberId} [put]
+func UpdateMember(c *gin.Context) {
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+	operatorRole, _ := util.GetFromContext[model.MerchantRole](c, merchant.MerchantRoleKey)
+	role := model.MerchantRole(req.Role)
+
+	if err := db.DB(c.Request.Context()).Transaction(
+		func(tx *gorm.DB) error {
+			var member model.MerchantMember
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+				Where("id = ? AND merchant_user_id = ?", c.Param("memberId"), merchantUser.ID).
+				First(&member).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New(MemberNotFound)
+				}
+				return err
+			}
+
+			if err := checkAdminManagement(operatorRole, member.Role, role); err != nil {
+				return err
+			}
+
+			return tx.Model(&member).Update("role", role).Error
+		},
+	); err != nil {
+		switch err.Error() {
+		case MemberNotFound:
+			c.JSON(http.StatusNotFound, util.Err(err.Error()))
+		case OnlyOwnerCanManageAdmin:
+			c.JSON(http.StatusForbidden, util.Err(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
+
+// RemoveMember 移除商户成员或撤回邀请
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param memberId path string true "成员 ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/members/{memberId} [delete]
+func RemoveMember(c *gin.Context) {
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+	operatorRole, _ := util.GetFromContext[model.MerchantRole](c, merchant.MerchantRoleKey)
+
+	var member model.MerchantMember
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND merchant_user_id = ?", c.Param("memberId"), merchantUser.ID).
+		First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(MemberNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	if err := checkAdminManagement(operatorRole, member.Role); err != nil {
+		c.JSON(http.StatusForbidden, util.Err(err.Error()))
+		return
+	}
+
+	if err := db.DB(c.Request.Context()).Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
+
+// ListAuditLogs 获取商户操作审计日志
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param request query ListAuditLogsRequest true "request query"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/audit-logs [get]
+func ListAuditLogs(c *gin.Context) {
+	var req ListAuditLogsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	merchantUser, _ := util.GetFromContext[*model.User](c, merchant.MerchantUserObjKey)
+
+	baseQuery := db.DB(c.Request.Context()).
+		Model(&model.MerchantAuditLog{}).
+		Where("merchant_audit_logs.merchant_user_id = ?", merchantUser.ID)
+	if req.ActorUserID != nil {
+		baseQuery = baseQuery.Where("merchant_audit_logs.actor_user_id = ?", *req.ActorUserID)
+	}
+	if req.Action != "" {
+		baseQuery = baseQuery.Where("merchant_audit_logs.action = ?", req.Action)
+	}
+
+	response := &ListAuditLogsResponse{
+		Page:     req.Page,
+		PageSize: req.PageSize,
+		Logs:     []model.MerchantAuditLog{},
+	}
+
+	if err := baseQuery.Count(&response.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	if err := baseQuery.
+		Select("merchant_audit_logs.*, users.username as actor_username").
+		Joins("LEFT JOIN users ON users.id = merchant_audit_logs.actor_user_id").
+		Order("merchant_audit_logs.created_at DESC").
+		Offset((req.Page - 1) * req.PageSize).
+		Limit(req.PageSize).
+		Find(&response.Logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(response))
+}
+
+// ListMemberships 获取当前用户加入或受邀加入的商户
+// @Tags merchant
+// @Produce json
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/memberships [get]
+func ListMemberships(c *gin.Context) {
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	memberships := []model.MerchantMember{}
+	if err := db.DB(c.Request.Context()).
+		Model(&model.MerchantMember{}).
+		Select("merchant_members.*, users.username as merchant_username").
+		Joins("JOIN users ON users.id = merchant_members.merchant_user_id").
+		Where("merchant_members.user_id = ?", user.ID).
+		Order("merchant_members.created_at DESC").
+		Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(memberships))
+}
+
+// AcceptInvitation 接受商户邀请
+// @Tags merchant
+// @Produce json
+// @Param memberId path string true "成员 ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/memberships/{memberId}/accept [post]
+func AcceptInvitation(c *gin.Context) {
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	result := db.DB(c.Request.Context()).
+		Model(&model.MerchantMember{}).
+		Where("id = ? AND user_id = ? AND status = ?", c.Param("memberId"), user.ID, model.MerchantMemberStatusPending).
+		Update("status", model.MerchantMemberStatusActive)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(result.Error.Error()))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, util.Err(InvitationNotFound))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
+
+// LeaveMerchant 拒绝邀请或退出商户
+// @Tags merchant
+// @Produce json
+// @Param memberId path string true "成员 ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/memberships/{memberId} [delete]
+func LeaveMerchant(c *gin.Context) {
+	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	result := db.DB(c.Request.Context()).
+		Where("id = ? AND user_id = ?", c.Param("memberId"), user.ID).
+		Delete(&model.MerchantMember{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(result.Error.Error()))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, util.Err(MembershipNotFound))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OKNil())
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package risk
+
+// defaultWindowDays 未配置统计窗口时使用的滚动窗口（天）
+var defaultWindowDays = []int{7, 30}
+
+const (
+	// defaultMinOrders 未配置时窗口内参与风控判定的最少订单数
+	defaultMinOrders = 20
+	// ratePrecision 争议率与退款率保留的小数位数
+	ratePrecision = 4
+	// liftDetail 指标恢复后自动解除处置的说明
+	liftDetail = "争议率与退款率已恢复至阈值以下，自动解除处置"
+)
@@ -0,0 +1,331 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// thresholds 风控阈值与处置配置
+type thresholds struct {
+	windowDays     []int
+	minOrders      int
+	disputeRate    decimal.Decimal
+	refundRate     decimal.Decimal
+	suspendRate    decimal.Decimal
+	delayDays      int
+	penaltyFeeRate decimal.Decimal
+}
+
+// metric 单个统计窗口内的商户订单指标，ClientID 为空表示按商户用户汇总
+type metric struct {
+	UserID       uint64
+	ClientID     string
+	OrderCount   int64
+	DisputeCount int64
+	RefundCount  int64
+	windowDays   int
+}
+
+func (m *metric) disputeRate() decimal.Decimal {
+	return decimal.NewFromInt(m.DisputeCount).Div(decimal.NewFromInt(m.OrderCount)).Round(ratePrecision)
+}
+
+func (m *metric) refundRate() decimal.Decimal {
+	return decimal.NewFromInt(m.RefundCount).Div(decimal.NewFromInt(m.OrderCount)).Round(ratePrecision)
+}
+
+// exceeds 争议率或退款率任一达到阈值即视为超标，阈值为 0 的指标不参与判定
+func (m *metric) exceeds(disputeRate, refundRate decimal.Decimal) bool {
+	return (disputeRate.IsPositive() && m.disputeRate().GreaterThanOrEqual(disputeRate)) ||
+		(refundRate.IsPositive() && m.refundRate().GreaterThanOrEqual(refundRate))
+}
+
+// newLog 根据指标构建处置记录
+func (m *metric) newLog(action model.MerchantRiskAction, detail string) *model.MerchantRiskLog {
+	return &model.MerchantRiskLog{
+		UserID:       m.UserID,
+		ClientID:     m.ClientID,
+		Action:       action,
+		WindowDays:   m.windowDays,
+		OrderCount:   m.OrderCount,
+		DisputeCount: m.DisputeCount,
+		RefundCount:  m.RefundCount,
+		DisputeRate:  m.disputeRate(),
+		RefundRate:   m.refundRate(),
+		Detail:       detail,
+	}
+}
+
+// HandleEvaluateMerchantRisk 按滚动窗口统计商户用户与 API Key 的争议率、退款率，
+// 超过阈值的商户延长到账并提高手续费率，超过暂停阈值的 API Key 暂停使用；指标恢复后自动解除用户维度的处置
+func HandleEvaluateMerchantRisk(ctx context.Context, t *asynq.Task) error {
+	cfg := loadThresholds(ctx)
+	if cfg.disputeRate.IsZero() && cfg.refundRate.IsZero() && cfg.suspendRate.IsZero() {
+		logger.InfoF(ctx, "商户风控阈值均未配置，跳过")
+		return nil
+	}
+
+	flaggedUsers := make(map[uint64]*metric)
+	evaluatedUsers := make(map[uint64]bool)
+	flaggedClients := make(map[string]*metric)
+	for _, days := range cfg.windowDays {
+		since := time.Now().AddDate(0, 0, -days)
+
+		userMetrics, err := queryMetrics(ctx, since, cfg.minOrders, false)
+		if err != nil {
+			logger.ErrorF(ctx, "统计商户风控指标失败: 窗口[%d天] 错误: %v", days, err)
+			return err
+		}
+		for i := range userMetrics {
+			m := &userMetrics[i]
+			m.windowDays = days
+			evaluatedUsers[m.UserID] = true
+			if _, ok := flaggedUsers[m.UserID]; !ok && m.exceeds(cfg.disputeRate, cfg.refundRate) {
+				flaggedUsers[m.UserID] = m
+			}
+		}
+
+		clientMetrics, err := queryMetrics(ctx, since, cfg.minOrders, true)
+		if err != nil {
+			logger.ErrorF(ctx, "统计 API Key 风控指标失败: 窗口[%d天] 错误: %v", days, err)
+			return err
+		}
+		for i := range clientMetrics {
+			m := &clientMetrics[i]
+			m.windowDays = days
+			if _, ok := flaggedClients[m.ClientID]; !ok && m.exceeds(cfg.suspendRate, cfg.suspendRate) {
+				flaggedClients[m.ClientID] = m
+			}
+		}
+	}
+
+	for _, m := range flaggedUsers {
+		if err := penalizeUser(ctx, m, &cfg); err != nil {
+			logger.ErrorF(ctx, "商户[ID:%d]风控处置失败: %v", m.UserID, err)
+		}
+	}
+	if err := liftRecoveredUsers(ctx, db.DB(ctx), flaggedUsers, evaluatedUsers); err != nil {
+		logger.ErrorF(ctx, "解除商户风控处置失败: %v", err)
+		return err
+	}
+	for _, m := range flaggedClients {
+		if err := suspendAPIKey(ctx, m); err != nil {
+			logger.ErrorF(ctx, "API Key[ClientID:%s]暂停失败: %v", m.ClientID, err)
+		}
+	}
+
+	logger.InfoF(ctx, "商户风控评估完成: 超标商户[%d] 超标 API Key[%d]", len(flaggedUsers), len(flaggedClients))
+	return nil
+}
+
+// loadThresholds 读取风控配置，缺失或非法的配置视为 0（不判定/不处置）
+func loadThresholds(ctx context.Context) thresholds {
+	cfg := thresholds{windowDays: defaultWindowDays, minOrders: defaultMinOrders}
+	if windowDays, err := model.GetIntListByKey(ctx, model.ConfigKeyMerchantRiskWindowDays); err == nil && len(windowDays) > 0 {
+		cfg.windowDays = windowDays
+	}
+	if minOrders, err := model.GetIntByKey(ctx, model.ConfigKeyMerchantRiskMinOrders); err == nil && minOrders > 0 {
+		cfg.minOrders = minOrders
+	}
+	cfg.disputeRate, _ = model.GetDecimalByKey(ctx, model.ConfigKeyMerchantRiskDisputeRate, ratePrecision)
+	cfg.refundRate, _ = model.GetDecimalByKey(ctx, model.ConfigKeyMerchantRiskRefundRate, ratePrecision)
+	cfg.suspendRate, _ = model.GetDecimalByKey(ctx, model.ConfigKeyMerchantRiskSuspendRate, ratePrecision)
+	cfg.delayDays, _ = model.GetIntByKey(ctx, model.ConfigKeyMerchantRiskSettlementDelayDays)
+	cfg.penaltyFeeRate, _ = model.GetDecimalByKey(ctx, model.ConfigKeyMerchantRiskFeeRate, 2)
+	return cfg
+}
+
+// queryMetrics 统计窗口内已支付的商户订单数、争议数与退款数，byClient 为 true 时按 API Key 分组
+func queryMetrics(ctx context.Context, since time.Time, minOrders int, byClient bool) ([]metric, error) {
+	groupBy := "orders.payee_user_id"
+	selectClient := "'' AS client_id"
+	if byClient {
+		groupBy = "orders.payee_user_id, orders.client_id"
+		selectClient = "orders.client_id AS client_id"
+	}
+
+	query := db.DB(ctx).Model(&model.Order{}).
+		Select(fmt.Sprintf("orders.payee_user_id AS user_id, %s, "+
+			"COUNT(*) AS order_count, "+
+			"COUNT(disputes.id) AS dispute_count, "+
+			"COUNT(*) FILTER (WHERE orders.status IN ?) AS refund_count", selectClient),
+			[]model.OrderStatus{model.OrderStatusRefund, model.OrderStatusPartialRefund}).
+		Joins("LEFT JOIN disputes ON disputes.order_id = orders.id").
+		Where("orders.type IN ? AND orders.status IN ? AND orders.is_sandbox = ? AND orders.trade_time >= ?",
+			[]model.OrderType{model.OrderTypePayment, model.OrderTypeOnline},
+			[]model.OrderStatus{
+				model.OrderStatusSuccess, model.OrderStatusDisputing, model.OrderStatusRefund,
+				model.OrderStatusRefused, model.OrderStatusPartialRefund,
+			},
+			false, since)
+	if byClient {
+		query = query.Where("orders.client_id <> ''")
+	}
+
+	var metrics []metric
+	if err := query.Group(groupBy).
+		Having("COUNT(*) >= ?", minOrders).
+		Scan(&metrics).Error; err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// penalizeUser 对超标商户延长到账天数并提高手续费率，已处于相同处置时不重复记录
+func penalizeUser(ctx context.Context, m *metric, cfg *thresholds) error {
+	return db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := user.GetByID(tx, m.UserID); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		var logs []*model.MerchantRiskLog
+		if cfg.delayDays > 0 && user.RiskSettlementDelayDays != cfg.delayDays {
+			updates["risk_settlement_delay_days"] = cfg.delayDays
+			logs = append(logs, m.newLog(model.MerchantRiskActionSettlementDelay,
+				fmt.Sprintf("收款延迟到账调整为 %d 天", cfg.delayDays)))
+		}
+		if cfg.penaltyFeeRate.IsPositive() && !user.RiskFeeRate.Equal(cfg.penaltyFeeRate) {
+			updates["risk_fee_rate"] = cfg.penaltyFeeRate
+			logs = append(logs, m.newLog(model.MerchantRiskActionFeeRate,
+				fmt.Sprintf("手续费率提高至 %s", cfg.penaltyFeeRate.StringFixed(2))))
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(logs).Error; err != nil {
+			return err
+		}
+
+		logger.InfoF(ctx, "商户[%s]风控处置: 窗口[%d天] 订单[%d] 争议率[%s] 退款率[%s]",
+			user.Username, m.windowDays, m.OrderCount, m.disputeRate().String(), m.refundRate().String())
+		return nil
+	})
+}
+
+// liftRecoveredUsers 解除本次未超标商户的用户维度处置，
+// 仅在至少一个窗口内订单数达到最小样本量时才视为指标恢复，订单量不足的商户保持现有处置
+func liftRecoveredUsers(ctx context.Context, tx *gorm.DB, flagged map[uint64]*metric, evaluated map[uint64]bool) error {
+	var users []model.User
+	if err := tx.
+		Where("risk_settlement_delay_days > 0 OR risk_fee_rate > 0").
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if _, ok := flagged[user.ID]; ok || !evaluated[user.ID] {
+			continue
+		}
+		if err := LiftUserPenalty(tx, user.ID, 0, liftDetail); err != nil {
+			logger.ErrorF(ctx, "解除商户[%s]风控处置失败: %v", user.Username, err)
+			continue
+		}
+		logger.InfoF(ctx, "商户[%s]指标恢复，已解除风控处置", user.Username)
+	}
+	return nil
+}
+
+// suspendAPIKey 暂停超标的 API Key，已暂停的不重复处置；暂停后需管理员手动解除
+func suspendAPIKey(ctx context.Context, m *metric) error {
+	return db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		reason := fmt.Sprintf("近 %d 天争议率 %s、退款率 %s 超过暂停阈值",
+			m.windowDays, m.disputeRate().String(), m.refundRate().String())
+
+		result := tx.Model(&model.MerchantAPIKey{}).
+			Where("client_id = ? AND suspended_at IS NULL", m.ClientID).
+			Updates(map[string]interface{}{
+				"suspended_at":   time.Now(),
+				"suspend_reason": reason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		logger.InfoF(ctx, "API Key[ClientID:%s]已暂停: %s", m.ClientID, reason)
+		return tx.Create(m.newLog(model.MerchantRiskActionSuspendAPIKey, reason)).Error
+	})
+}
+
+// LiftUserPenalty 解除商户用户维度的风控处置并记录，operatorUserID 为 0 表示系统自动解除
+func LiftUserPenalty(tx *gorm.DB, userID uint64, operatorUserID uint64, detail string) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).
+			Where("id = ? AND (risk_settlement_delay_days > 0 OR risk_fee_rate > 0)", userID).
+			Updates(map[string]interface{}{
+				"risk_settlement_delay_days": 0,
+				"risk_fee_rate":              decimal.Zero,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Create(&model.MerchantRiskLog{
+			UserID:         userID,
+			Action:         model.MerchantRiskActionLift,
+			Detail:         detail,
+			OperatorUserID: operatorUserID,
+		}).Error
+	})
+}
+
+// ResumeAPIKey 解除 API Key 的暂停状态并记录
+func ResumeAPIKey(tx *gorm.DB, apiKey *model.MerchantAPIKey, operatorUserID uint64, detail string) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.MerchantAPIKey{}).
+			Where("id = ? AND suspended_at IS NOT NULL", apiKey.ID).
+			Updates(map[string]interface{}{
+				"suspended_at":   nil,
+				"suspend_reason": "",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Create(&model.MerchantRiskLog{
+			UserID:         apiKey.UserID,
+			ClientID:       apiKey.ClientID,
+			Action:         model.MerchantRiskActionLift,
+			Detail:         detail,
+			OperatorUserID: operatorUserID,
+		}).Error
+	})
+}
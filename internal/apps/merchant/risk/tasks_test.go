@@ -0,0 +1,83 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package risk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestLiftRecoveredUsers 仅解除本次达到最小样本量且未超标商户的处置
+func TestLiftRecoveredUsers(t *testing.T) {
+	tx, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := tx.AutoMigrate(&model.User{}, &model.MerchantRiskLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	const (
+		flaggedID   = 1 // 本次仍超标
+		recoveredID = 2 // 订单量达标且未超标
+		sparseID    = 3 // 订单量低于最小样本量
+	)
+	for _, id := range []uint64{flaggedID, recoveredID, sparseID} {
+		user := &model.User{
+			ID:                      id,
+			Username:                fmt.Sprintf("merchant%d", id),
+			SignKey:                 fmt.Sprintf("k%d", id),
+			RiskSettlementDelayDays: 7,
+			RiskFeeRate:             decimal.NewFromFloat(0.1),
+		}
+		if err := tx.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	flagged := map[uint64]*metric{flaggedID: {UserID: flaggedID}}
+	evaluated := map[uint64]bool{flaggedID: true, recoveredID: true}
+	if err := liftRecoveredUsers(context.Background(), tx, flagged, evaluated); err != nil {
+		t.Fatalf("liftRecoveredUsers: %v", err)
+	}
+
+	want := map[uint64]int{flaggedID: 7, recoveredID: 0, sparseID: 7}
+	for id, delayDays := range want {
+		var user model.User
+		if err := tx.First(&user, id).Error; err != nil {
+			t.Fatalf("load user: %v", err)
+		}
+		if user.RiskSettlementDelayDays != delayDays {
+			t.Fatalf("user %d risk_settlement_delay_days = %d, want %d", id, user.RiskSettlementDelayDays, delayDays)
+		}
+	}
+
+	var logs int64
+	if err := tx.Model(&model.MerchantRiskLog{}).Where("action = ?", model.MerchantRiskActionLift).Count(&logs).Error; err != nil {
+		t.Fatalf("count logs: %v", err)
+	}
+	if logs != 1 {
+		t.Fatalf("lift logs = %d, want 1", logs)
+	}
+}
@@ -0,0 +1,24 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sandbox
+
+const (
+	SandboxNotEnabled     = "该 API Key 未开启测试模式，无法使用沙箱"
+	SandboxOrderNotFound  = "沙箱订单不存在"
+	SandboxOrderIDMissing = "trade_no 与 out_trade_no 至少填写一个"
+	SandboxActionInvalid  = "当前订单状态不支持该模拟操作"
+)
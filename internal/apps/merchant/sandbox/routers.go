@@ -0,0 +1,213 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
+	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/common"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SimulateAction 沙箱模拟事件
+type SimulateAction string
+
+const (
+	SimulateActionSuccess SimulateAction = "success"
+	SimulateActionFailed  SimulateAction = "failed"
+	SimulateActionRefund  SimulateAction = "refund"
+	// SimulateActionDispute 仅将订单置为争议中并发送回调，不创建争议记录，不进入争议处理流程
+	SimulateActionDispute SimulateAction = "dispute"
+)
+
+// SimulateRequest 沙箱模拟请求
+type SimulateRequest struct {
+	TradeNo         uint64         `json:"trade_no,string"`
+	MerchantOrderNo string         `json:"out_trade_no" binding:"max=64"`
+	Action          SimulateAction `json:"action" binding:"required,oneof=success failed refund dispute"`
+}
+
+// GetSandboxAccount 获取沙箱账户模拟余额
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/sandbox [get]
+func GetSandboxAccount(c *gin.Context) {
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	account, err := service.GetOrCreateSandboxAccount(db.DB(c.Request.Context()), apiKey.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(account))
+}
+
+// ResetSandboxAccount 重置沙箱账户模拟余额
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/sandbox/reset [post]
+func ResetSandboxAccount(c *gin.Context) {
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	var account *model.SandboxAccount
+	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var err error
+		account, err = service.ResetSandboxAccount(tx, apiKey.ID)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(account))
+}
+
+// SimulateOrderEvent 模拟沙箱订单的支付成功、失败、退款或争议，并触发商户回调
+// 争议仅模拟订单状态与回调，不创建争议记录，之后可继续模拟退款
+// @Tags merchant
+// @Accept json
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Param request body SimulateRequest true "request body"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/sandbox/simulate [post]
+func SimulateOrderEvent(c *gin.Context) {
+	var req SimulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+	if req.TradeNo == 0 && req.MerchantOrderNo == "" {
+		c.JSON(http.StatusBadRequest, util.Err(SandboxOrderIDMissing))
+		return
+	}
+
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	var order model.Order
+	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
+			Where("client_id = ? AND is_sandbox = ?", apiKey.ClientID, true)
+		if req.TradeNo != 0 {
+			query = query.Where("id = ?", req.TradeNo)
+		} else {
+			query = query.Where("merchant_order_no = ?", req.MerchantOrderNo)
+		}
+		if err := query.First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New(SandboxOrderNotFound)
+			}
+			return err
+		}
+
+		var merchantUser model.User
+		if err := merchantUser.GetByID(tx, apiKey.UserID); err != nil {
+			return err
+		}
+		var merchantPayConfig model.UserPayConfig
+		if err := merchantPayConfig.GetByPayScore(tx, merchantUser.PayScore); err != nil {
+			return err
+		}
+
+		var tradeStatus string
+		switch req.Action {
+		case SimulateActionSuccess:
+			if order.Status != model.OrderStatusPending {
+				return errors.New(SandboxActionInvalid)
+			}
+
+			if err := service.ApplySandboxPayment(tx, apiKey.ID, &order, merchantPayConfig.FeeRate); err != nil {
+				return err
+			}
+
+			order.Status = model.OrderStatusSuccess
+			order.Type = model.OrderTypeTest
+			order.PayerUserID = apiKey.UserID
+			order.Remark = common.TestModeOrderRemark
+			order.TradeTime = time.Now()
+			tradeStatus = common.TradeStatusSuccess
+
+			expireKey := db.PrefixedKey(fmt.Sprintf(payment.OrderExpireKeyFormat, order.ID))
+			if err := db.Redis.Del(c.Request.Context(), expireKey).Err(); err != nil {
+				log.Printf("[Sandbox] 删除订单过期key失败: order_id=%d, error=%v", order.ID, err)
+			}
+		case SimulateActionFailed:
+			if order.Status != model.OrderStatusPending {
+				return errors.New(SandboxActionInvalid)
+			}
+			order.Status = model.OrderStatusFailed
+			tradeStatus = common.TradeStatusFailed
+		case SimulateActionDispute:
+			if order.Status != model.OrderStatusSuccess {
+				return errors.New(SandboxActionInvalid)
+			}
+			// 沙箱争议只用于联调回调：创建争议记录会进入截止提醒与超时自动退款任务，动用真实余额
+			order.Status = model.OrderStatusDisputing
+			tradeStatus = common.TradeStatusDispute
+		case SimulateActionRefund:
+			if order.Status != model.OrderStatusSuccess && order.Status != model.OrderStatusDisputing {
+				return errors.New(SandboxActionInvalid)
+			}
+			if err := service.ApplySandboxRefund(tx, apiKey.ID, &order, merchantPayConfig.FeeRate); err != nil {
+				return err
+			}
+			refundedAt := time.Now()
+			order.Status = model.OrderStatusRefund
+			order.RefundedAt = &refundedAt
+			tradeStatus = common.TradeStatusRefund
+		}
+
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+
+		return service.EnqueueMerchantEventNotify(order.ID, order.ClientID, tradeStatus)
+	}); err != nil {
+		errMsg := err.Error()
+		switch errMsg {
+		case SandboxOrderNotFound:
+			c.JSON(http.StatusNotFound, util.Err(errMsg))
+		case SandboxActionInvalid, common.InsufficientBalance:
+			c.JSON(http.StatusBadRequest, util.Err(errMsg))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(errMsg))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(order))
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statement
+
+const (
+	// DateLayout 对账单日期格式
+	DateLayout = "2006-01-02"
+	// storageKeyFormat 对账单 CSV 存储路径：statements/<client_id>/<日期>.csv
+	storageKeyFormat = "statements/%s/%s.csv"
+	// csvContentType 对账单文件类型
+	csvContentType = "text/csv; charset=utf-8"
+	// EventStatementReady 对账单生成完成的回调事件
+	EventStatementReady = "statement.ready"
+	// apiKeyBatchSize 生成对账单时每批处理的 API Key 数量
+	apiKeyBatchSize = 200
+	// detailBatchSize 导出明细时每批查询的订单数量
+	detailBatchSize = 1000
+)
@@ -0,0 +1,145 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/storage"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+)
+
+type ListStatementsRequest struct {
+	Page      int    `form:"page" binding:"min=1"`
+	PageSize  int    `form:"page_size" binding:"min=1,max=100"`
+	StartDate string `form:"start_date" binding:"omitempty,datetime=2006-01-02"`
+	EndDate   string `form:"end_date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type ListStatementsResponse struct {
+	Total      int64                     `json:"total"`
+	Page       int                       `json:"page"`
+	PageSize   int                       `json:"page_size"`
+	Statements []model.MerchantStatement `json:"statements"`
+}
+
+// ListStatements 获取 API Key 的日结对账单列表
+// @Tags merchant
+// @Produce json
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Param request query ListStatementsRequest true "request query"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/merchant/api-keys/{id}/statements [get]
+func ListStatements(c *gin.Context) {
+	var req ListStatementsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	baseQuery := db.DB(c.Request.Context()).
+		Model(&model.MerchantStatement{}).
+		Where("client_id = ?", apiKey.ClientID)
+	if req.StartDate != "" {
+		baseQuery = baseQuery.Where("statement_date >= ?", req.StartDate)
+	}
+	if req.EndDate != "" {
+		baseQuery = baseQuery.Where("statement_date <= ?", req.EndDate)
+	}
+
+	response := &ListStatementsResponse{
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		Statements: []model.MerchantStatement{},
+	}
+
+	if err := baseQuery.Count(&response.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	if err := baseQuery.
+		Order("statement_date DESC").
+		Offset((req.Page - 1) * req.PageSize).
+		Limit(req.PageSize).
+		Find(&response.Statements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(response))
+}
+
+// DownloadStatement 下载日结对账单 CSV
+// @Tags merchant
+// @Produce text/csv
+// @Param X-Merchant-ID header string false "商户用户 ID"
+// @Param id path uint64 true "API Key ID"
+// @Param statementId path string true "对账单 ID"
+// @Success 200 {file} file
+// @Router /api/v1/merchant/api-keys/{id}/statements/{statementId}/download [get]
+func DownloadStatement(c *gin.Context) {
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, merchant.APIKeyObjKey)
+
+	var statement model.MerchantStatement
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND client_id = ?", c.Param("statementId"), apiKey.ClientID).
+		First(&statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(StatementNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	filename := fmt.Sprintf("statement_%s_%s.csv", statement.ClientID, statement.StatementDate.Format(DateLayout))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// 优先读取已存储的文件，存储未启用时按当前数据重新生成
+	if statement.FilePath != "" && storage.IsEnabled() {
+		obj, err := storage.GetObjectViaCache(c.Request.Context(), statement.FilePath)
+		if err != nil {
+			c.JSON(http.StatusNotFound, util.Err(StatementFileNotFound))
+			return
+		}
+		if obj.CachePath != "" {
+			c.File(obj.CachePath)
+			return
+		}
+		defer obj.Body.Close()
+		c.DataFromReader(http.StatusOK, obj.ContentLength, csvContentType, obj.Body, nil)
+		return
+	}
+
+	data, err := buildStatementCSV(c.Request.Context(), &statement)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	c.Data(http.StatusOK, csvContentType, data)
+}
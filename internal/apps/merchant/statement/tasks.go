@@ -0,0 +1,208 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/config"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/storage"
+	"github.com/linux-do/credit/internal/task"
+	"github.com/linux-do/credit/internal/task/scheduler"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+)
+
+// HandleGenerateMerchantStatements 为所有正式 API Key 生成指定日期（默认前一日）的日结对账单
+func HandleGenerateMerchantStatements(ctx context.Context, t *asynq.Task) error {
+	var payload struct {
+		Date string `json:"date"`
+	}
+	_ = json.Unmarshal(t.Payload(), &payload)
+
+	date := time.Now().AddDate(0, 0, -1)
+	if payload.Date != "" {
+		parsed, err := time.ParseInLocation(DateLayout, payload.Date, time.Local)
+		if err != nil {
+			return fmt.Errorf("解析对账日期失败: %w: %w", err, asynq.SkipRetry)
+		}
+		date = parsed
+	}
+
+	logger.InfoF(ctx, "开始生成商户日结对账单: %s", date.Format(DateLayout))
+
+	generated := 0
+	var apiKeys []model.MerchantAPIKey
+	if err := db.DB(ctx).
+		Where("test_mode = ?", false).
+		Order("id ASC").
+		FindInBatches(&apiKeys, apiKeyBatchSize, func(*gorm.DB, int) error {
+			for i := range apiKeys {
+				created, err := generateStatement(ctx, &apiKeys[i], date)
+				if err != nil {
+					logger.ErrorF(ctx, "生成对账单失败: ClientID[%s] 日期[%s] 错误: %v", apiKeys[i].ClientID, date.Format(DateLayout), err)
+					continue
+				}
+				if created {
+					generated++
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		logger.ErrorF(ctx, "查询商户 API Key 失败: %v", err)
+		return err
+	}
+
+	logger.InfoF(ctx, "商户日结对账单生成完成: %s 共生成 %d 份", date.Format(DateLayout), generated)
+	return nil
+}
+
+// generateStatement 生成单个 API Key 的对账单，已存在或当日无资金变动时跳过
+func generateStatement(ctx context.Context, apiKey *model.MerchantAPIKey, date time.Time) (bool, error) {
+	start, _ := dayRange(date)
+
+	var exists int64
+	if err := db.DB(ctx).Model(&model.MerchantStatement{}).
+		Where("client_id = ? AND statement_date = ?", apiKey.ClientID, start).
+		Count(&exists).Error; err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	statement, err := computeStatement(ctx, apiKey, date)
+	if err != nil {
+		return false, err
+	}
+	if isEmpty(statement) {
+		return false, nil
+	}
+
+	data, err := buildStatementCSV(ctx, statement)
+	if err != nil {
+		return false, err
+	}
+	statement.FileSize = int64(len(data))
+
+	if storage.IsEnabled() {
+		key := storage.BuildKey(fmt.Sprintf(storageKeyFormat, apiKey.ClientID, start.Format(DateLayout)))
+		if err := storage.PutObject(ctx, key, bytes.NewReader(data), statement.FileSize, csvContentType); err != nil {
+			return false, err
+		}
+		statement.FilePath = key
+	}
+
+	if err := db.DB(ctx).Create(statement).Error; err != nil {
+		if strings.Contains(err.Error(), "SQLSTATE 23505") {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := enqueueStatementNotify(statement.ID); err != nil {
+		logger.ErrorF(ctx, "下发对账单回调失败: 对账单[ID:%d] 错误: %v", statement.ID, err)
+	}
+	return true, nil
+}
+
+// enqueueStatementNotify 下发对账单生成回调任务
+func enqueueStatementNotify(statementID uint64) error {
+	notifyPayload, _ := json.Marshal(map[string]interface{}{
+		"statement_id": statementID,
+	})
+	if _, err := scheduler.AsynqClient.Enqueue(
+		asynq.NewTask(task.MerchantStatementNotifyTask, notifyPayload),
+		asynq.Queue(task.QueueWebhook),
+		asynq.MaxRetry(10),
+		asynq.Timeout(30*time.Second),
+	); err != nil {
+		return fmt.Errorf("下发对账单回调任务失败: %w", err)
+	}
+	return nil
+}
+
+// HandleMerchantStatementNotify 通知商户对账单已生成
+func HandleMerchantStatementNotify(ctx context.Context, t *asynq.Task) error {
+	var payload struct {
+		StatementID uint64 `json:"statement_id"`
+	}
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w", err)
+	}
+
+	var statement model.MerchantStatement
+	if err := db.DB(ctx).Where("id = ?", payload.StatementID).First(&statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorF(ctx, "对账单[ID:%d]不存在，跳过回调", payload.StatementID)
+			return nil
+		}
+		return fmt.Errorf("查询对账单失败: %w", err)
+	}
+
+	var apiKey model.MerchantAPIKey
+	if err := apiKey.GetByClientID(db.DB(ctx), statement.ClientID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.InfoF(ctx, "商户[ClientID:%s]已删除，跳过对账单回调", statement.ClientID)
+			return nil
+		}
+		return fmt.Errorf("查询商户信息失败: %w", err)
+	}
+
+	callbackURL := apiKey.NotifyURL
+	if callbackURL == "" || (config.Config.App.IsProduction() && util.IsLocalhost(callbackURL)) {
+		return nil
+	}
+
+	callbackParams := map[string]string{
+		"pid":             statement.ClientID,
+		"event":           EventStatementReady,
+		"statement_id":    strconv.FormatUint(statement.ID, 10),
+		"statement_date":  statement.StatementDate.Format(DateLayout),
+		"payment_count":   strconv.FormatInt(statement.PaymentCount, 10),
+		"gross_payments":  statement.GrossPayments.StringFixed(2),
+		"fees_withheld":   statement.FeesWithheld.StringFixed(2),
+		"refunds":         statement.Refunds.StringFixed(2),
+		"dispute_refunds": statement.DisputeRefunds.StringFixed(2),
+		"distributes":     statement.Distributes.StringFixed(2),
+		"settled_amount":  statement.SettledAmount.StringFixed(2),
+		"closing_balance": statement.ClosingBalance.StringFixed(2),
+		"pending_balance": statement.PendingBalance.StringFixed(2),
+	}
+	payment.SignCallbackParams(callbackParams, &apiKey, "")
+
+	if err := payment.SendCallbackRequest(ctx, callbackURL, callbackParams); err != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		logger.ErrorF(ctx, "对账单回调失败: 对账单[ID:%d] 重试次数[%d] 错误: %v", statement.ID, retried+1, err)
+		return err
+	}
+
+	logger.InfoF(ctx, "对账单回调成功: 对账单[ID:%d] ClientID[%s]", statement.ID, statement.ClientID)
+	return nil
+}
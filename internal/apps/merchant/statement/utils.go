@@ -0,0 +1,292 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strconv"
+	"time"
+
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// paidOrderStatuses 视为已完成支付的订单状态（含之后发生退款、争议的订单）
+var paidOrderStatuses = []model.OrderStatus{
+	model.OrderStatusSuccess,
+	model.OrderStatusRefund,
+	model.OrderStatusDisputing,
+	model.OrderStatusRefused,
+	model.OrderStatusPartialRefund,
+}
+
+// paymentOrderTypes 计入收款的订单类型
+var paymentOrderTypes = []model.OrderType{model.OrderTypePayment, model.OrderTypeOnline}
+
+// dayRange 返回指定日期的起止时间 [start, end)
+func dayRange(date time.Time) (time.Time, time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// sumDecimal 执行聚合查询并返回金额合计，args 为 expr 中占位符的参数
+func sumDecimal(query *gorm.DB, expr string, args ...interface{}) (decimal.Decimal, error) {
+	var total decimal.Decimal
+	if err := query.Select("COALESCE(SUM("+expr+"), 0)", args...).Scan(&total).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return total, nil
+}
+
+// computeStatement 汇总指定 API Key 在某日的资金流水
+func computeStatement(ctx context.Context, apiKey *model.MerchantAPIKey, date time.Time) (*model.MerchantStatement, error) {
+	start, end := dayRange(date)
+	tx := db.DB(ctx)
+
+	statement := &model.MerchantStatement{
+		ClientID:       apiKey.ClientID,
+		MerchantUserID: apiKey.UserID,
+		StatementDate:  start,
+	}
+
+	// 收款：当日完成支付的订单
+	paymentQuery := func() *gorm.DB {
+		return tx.Model(&model.Order{}).
+			Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+			Where("orders.type IN ? AND orders.status IN ?", paymentOrderTypes, paidOrderStatuses).
+			Where("orders.trade_time >= ? AND orders.trade_time < ?", start, end)
+	}
+	if err := paymentQuery().Count(&statement.PaymentCount).Error; err != nil {
+		return nil, err
+	}
+	gross, err := sumDecimal(paymentQuery(), "orders.amount")
+	if err != nil {
+		return nil, err
+	}
+	statement.GrossPayments = gross
+
+	// 手续费：订单金额与各收款方实际入账金额之差
+	fees, err := sumDecimal(paymentQuery().
+		Joins("JOIN (SELECT order_id, SUM(amount) AS net_amount FROM order_transfers GROUP BY order_id) t ON t.order_id = orders.id"),
+		"orders.amount - t.net_amount")
+	if err != nil {
+		return nil, err
+	}
+	statement.FeesWithheld = fees
+
+	// 商户主动退款：当日退款且非争议退款的订单
+	refunds, err := sumDecimal(tx.Model(&model.Order{}).
+		Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+		Where("orders.type IN ? AND orders.status = ?", paymentOrderTypes, model.OrderStatusRefund).
+		Where("orders.refunded_at >= ? AND orders.refunded_at < ?", start, end).
+		Where("NOT EXISTS (SELECT 1 FROM disputes WHERE disputes.order_id = orders.id AND disputes.status = ?)", model.DisputeStatusRefund),
+		"orders.amount")
+	if err != nil {
+		return nil, err
+	}
+	statement.Refunds = refunds
+
+	// 争议退款：当日以退款结束的争议
+	disputeRefunds, err := sumDecimal(tx.Model(&model.Dispute{}).
+		Joins("JOIN orders ON orders.id = disputes.order_id").
+		Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+		Where("disputes.status = ?", model.DisputeStatusRefund).
+		Where("orders.refunded_at >= ? AND orders.refunded_at < ?", start, end),
+		"CASE WHEN disputes.refund_amount > 0 THEN disputes.refund_amount ELSE orders.amount END")
+	if err != nil {
+		return nil, err
+	}
+	statement.DisputeRefunds = disputeRefunds
+
+	// 分发：当日商户向用户分发的金额
+	distributes, err := sumDecimal(tx.Model(&model.Order{}).
+		Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+		Where("orders.type = ? AND orders.status = ?", model.OrderTypeDistribute, model.OrderStatusSuccess).
+		Where("orders.trade_time >= ? AND orders.trade_time < ?", start, end),
+		"orders.amount")
+	if err != nil {
+		return nil, err
+	}
+	statement.Distributes = distributes
+
+	// 结算：当日由待结算转入可用余额的金额
+	settled, err := sumDecimal(tx.Model(&model.OrderTransfer{}).
+		Joins("JOIN orders ON orders.id = order_transfers.order_id").
+		Where("orders.client_id = ? AND order_transfers.payee_user_id = ?", apiKey.ClientID, apiKey.UserID).
+		Where("order_transfers.status = ?", model.OrderTransferStatusCompleted).
+		Where("order_transfers.transfer_at >= ? AND order_transfers.transfer_at < ?", start, end),
+		"order_transfers.amount")
+	if err != nil {
+		return nil, err
+	}
+	statement.SettledAmount = settled
+
+	// 期末余额：该 API Key 截至当日结束的累计资金流水
+	if err := computeClosingBalances(tx, apiKey, end, statement); err != nil {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// computeClosingBalances 按 API Key 累计截至 end 的资金流水，得到商户所有者在该 API Key 下的可用余额与待结算余额
+// 可用余额 = 已结算入账 - 退款扣回 - 分发 - 红包支出 + 红包退回；待结算余额为 end 时尚未结算的入账
+func computeClosingBalances(tx *gorm.DB, apiKey *model.MerchantAPIKey, end time.Time, statement *model.MerchantStatement) error {
+	transferQuery := func() *gorm.DB {
+		return tx.Model(&model.OrderTransfer{}).
+			Joins("JOIN orders ON orders.id = order_transfers.order_id").
+			Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+			Where("order_transfers.payee_user_id = ? AND order_transfers.created_at < ?", apiKey.UserID, end)
+	}
+	settled, err := sumDecimal(transferQuery().
+		Where("order_transfers.status = ? AND order_transfers.transfer_at < ?", model.OrderTransferStatusCompleted, end),
+		"order_transfers.amount")
+	if err != nil {
+		return err
+	}
+	pending, err := sumDecimal(transferQuery().
+		Where("NOT (order_transfers.status = ? AND order_transfers.transfer_at < ?)", model.OrderTransferStatusCompleted, end),
+		"order_transfers.amount")
+	if err != nil {
+		return err
+	}
+
+	// 退款扣回：按商户所有者在订单中分得的比例承担退款金额，部分退款以争议退款金额为准
+	refunded, err := sumDecimal(tx.Model(&model.Order{}).
+		Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+		Where("orders.type IN ? AND orders.status IN ?", paymentOrderTypes,
+			[]model.OrderStatus{model.OrderStatusRefund, model.OrderStatusPartialRefund}).
+		Where("orders.refunded_at < ?", end),
+		`CASE WHEN orders.status = ?
+			THEN COALESCE((SELECT MAX(disputes.refund_amount) FROM disputes WHERE disputes.order_id = orders.id AND disputes.status = ?), 0)
+			ELSE orders.amount END
+		* COALESCE(
+			(SELECT order_splits.amount FROM order_splits WHERE order_splits.order_id = orders.id AND order_splits.payee_user_id = ?),
+			CASE WHEN EXISTS (SELECT 1 FROM order_splits WHERE order_splits.order_id = orders.id) THEN 0 ELSE orders.amount END
+		) / orders.amount`,
+		model.OrderStatusPartialRefund, model.DisputeStatusRefund, apiKey.UserID)
+	if err != nil {
+		return err
+	}
+
+	// 商户支出：分发与红包
+	spent, err := sumDecimal(tx.Model(&model.Order{}).
+		Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+		Where("orders.payer_user_id = ? AND orders.status = ?", apiKey.UserID, model.OrderStatusSuccess).
+		Where("orders.type IN ?", []model.OrderType{model.OrderTypeDistribute, model.OrderTypeRedEnvelopeSend}).
+		Where("orders.trade_time < ?", end),
+		"orders.amount")
+	if err != nil {
+		return err
+	}
+
+	// 红包过期退回
+	returned, err := sumDecimal(tx.Model(&model.Order{}).
+		Where("orders.client_id = ? AND orders.is_sandbox = ?", apiKey.ClientID, false).
+		Where("orders.payee_user_id = ? AND orders.type = ?", apiKey.UserID, model.OrderTypeRedEnvelopeRefund).
+		Where("orders.trade_time < ?", end),
+		"orders.amount")
+	if err != nil {
+		return err
+	}
+
+	statement.ClosingBalance = settled.Sub(refunded).Sub(spent).Add(returned).Round(2)
+	statement.PendingBalance = pending
+	return nil
+}
+
+// isEmpty 当日无任何资金变动
+func isEmpty(statement *model.MerchantStatement) bool {
+	return statement.PaymentCount == 0 &&
+		statement.Refunds.IsZero() &&
+		statement.DisputeRefunds.IsZero() &&
+		statement.Distributes.IsZero() &&
+		statement.SettledAmount.IsZero()
+}
+
+// buildStatementCSV 生成对账单 CSV，包含汇总与当日订单明细
+func buildStatementCSV(ctx context.Context, statement *model.MerchantStatement) ([]byte, error) {
+	var buf bytes.Buffer
+	// UTF-8 BOM，便于表格软件正确识别中文
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+
+	summary := [][]string{
+		{"对账日期", statement.StatementDate.Format(DateLayout)},
+		{"Client ID", statement.ClientID},
+		{"收款笔数", strconv.FormatInt(statement.PaymentCount, 10)},
+		{"收款总额", statement.GrossPayments.StringFixed(2)},
+		{"手续费", statement.FeesWithheld.StringFixed(2)},
+		{"商户退款", statement.Refunds.StringFixed(2)},
+		{"争议退款", statement.DisputeRefunds.StringFixed(2)},
+		{"分发", statement.Distributes.StringFixed(2)},
+		{"结算入账", statement.SettledAmount.StringFixed(2)},
+		{"期末可用余额", statement.ClosingBalance.StringFixed(2)},
+		{"期末待结算余额", statement.PendingBalance.StringFixed(2)},
+		{},
+		{"交易号", "商户订单号", "订单名称", "类型", "状态", "金额", "交易时间", "退款时间"},
+	}
+	if err := writer.WriteAll(summary); err != nil {
+		return nil, err
+	}
+
+	start, end := dayRange(statement.StatementDate)
+	var orders []model.Order
+	if err := db.DB(ctx).
+		Where("client_id = ? AND is_sandbox = ?", statement.ClientID, false).
+		Where("((trade_time >= ? AND trade_time < ?) OR (refunded_at >= ? AND refunded_at < ?))", start, end, start, end).
+		Where("status <> ?", model.OrderStatusPending).
+		Order("id ASC").
+		FindInBatches(&orders, detailBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, order := range orders {
+				if err := writer.Write([]string{
+					strconv.FormatUint(order.ID, 10),
+					util.DerefString(order.MerchantOrderNo),
+					order.OrderName,
+					string(order.Type),
+					string(order.Status),
+					order.Amount.StringFixed(2),
+					order.TradeTime.Format(time.DateTime),
+					formatRefundedAt(order.RefundedAt),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatRefundedAt 格式化退款时间，未退款时为空
+func formatRefundedAt(refundedAt *time.Time) string {
+	if refundedAt == nil {
+		return ""
+	}
+	return refundedAt.Format(time.DateTime)
+}
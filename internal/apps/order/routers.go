@@ -25,6 +25,7 @@ import (
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
 )
 
 type TransactionListRequest struct {
@@ -40,6 +41,7 @@ type TransactionListRequest struct {
 	PayerUsername       string                    `json:"payer_username" form:"payer_username" binding:"omitempty"`
 	PayeeUsername       string                    `json:"payee_username" form:"payee_username" binding:"omitempty"`
 	PayeeTransferStatus model.OrderTransferStatus `json:"payee_transfer_status" form:"payee_transfer_status" binding:"omitempty,oneof=pending completed"`
+	Sandbox             bool                      `json:"sandbox" form:"sandbox"`
 }
 
 type TransactionListResponse struct {
@@ -78,13 +80,53 @@ func ListTransactions(c *gin.Context) {
 
 	user, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
 
-	baseQuery := db.DB(c.Request.Context()).Model(&model.Order{}).
+	baseQuery, err := buildTransactionQuery(db.DB(c.Request.Context()), user, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	var total int64
+	if err := baseQuery.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	response := &TransactionListResponse{
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}
+
+	offset := (req.Page - 1) * req.PageSize
+	if err := baseQuery.Order("orders.created_at DESC").Offset(offset).Limit(req.PageSize).Find(&response.Orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	// 转换订单类型：从收款方视角看，payment 订单应该显示为 receive
+	// 并更新 Payee_transfer_status，兼容为空的场景
+	for i := range response.Orders {
+		if response.Orders[i].PayeeTransferStatus == "" {
+			response.Orders[i].PayeeTransferStatus = string(model.OrderTransferStatusCompleted)
+		}
+		if response.Orders[i].Type == model.OrderTypePayment && response.Orders[i].PayeeUserID == user.ID {
+			response.Orders[i].Type = model.OrderTypeReceive
+		}
+	}
+
+	c.JSON(http.StatusOK, util.OK(response))
+}
+
+// buildTransactionQuery 构建交易列表查询，到账状态仅关联订单主收款方的到账记录，分账订单不会重复出现
+func buildTransactionQuery(tx *gorm.DB, user *model.User, req *TransactionListRequest) (*gorm.DB, error) {
+	baseQuery := tx.Model(&model.Order{}).
 		Select("orders.*, merchant_api_keys.app_name, merchant_api_keys.app_homepage_url, merchant_api_keys.app_description, merchant_api_keys.redirect_uri, disputes.id as dispute_id, payer_user.username as payer_username, payee_user.username as payee_username, payer_user.avatar_url as payer_avatar_url, payee_user.avatar_url as payee_avatar_url, order_transfers.status as payee_transfer_status, order_transfers.transfer_at as payee_transfer_at").
 		Joins("LEFT JOIN merchant_api_keys ON orders.client_id = merchant_api_keys.client_id").
 		Joins("LEFT JOIN disputes ON orders.id = disputes.order_id").
 		Joins("LEFT JOIN users as payer_user ON orders.payer_user_id = payer_user.id").
 		Joins("LEFT JOIN users as payee_user ON orders.payee_user_id = payee_user.id").
-		Joins("LEFT JOIN order_transfers ON orders.id = order_transfers.order_id")
+		Joins("LEFT JOIN order_transfers ON orders.id = order_transfers.order_id AND order_transfers.payee_user_id = orders.payee_user_id")
 
 	clientIDHandled := false
 	if len(req.Types) > 0 {
@@ -109,11 +151,10 @@ func ListTransactions(c *gin.Context) {
 				if req.ClientID != "" {
 					clientIDHandled = true
 					var count int64
-					if err := db.DB(c.Request.Context()).Model(&model.MerchantAPIKey{}).
+					if err := tx.Model(&model.MerchantAPIKey{}).
 						Where("client_id = ? AND user_id = ?", req.ClientID, user.ID).
 						Count(&count).Error; err != nil {
-						c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-						return
+						return nil, err
 					}
 					if count > 0 {
 						conditions = append(conditions, "(orders.type = ? AND orders.client_id = ?)")
@@ -156,6 +197,9 @@ func ListTransactions(c *gin.Context) {
 		baseQuery = baseQuery.Where("orders.status IN ?", req.Statuses)
 	}
 
+	// 沙箱订单与正式订单分开查询
+	baseQuery = baseQuery.Where("orders.is_sandbox = ?", req.Sandbox)
+
 	if req.ClientID != "" && !clientIDHandled {
 		baseQuery = baseQuery.Where("orders.client_id = ?", req.ClientID)
 	}
@@ -186,34 +230,5 @@ func ListTransactions(c *gin.Context) {
 		baseQuery = baseQuery.Where("orders.created_at <= ?", req.EndTime)
 	}
 
-	var total int64
-	if err := baseQuery.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
-	}
-
-	response := &TransactionListResponse{
-		Total:    total,
-		Page:     req.Page,
-		PageSize: req.PageSize,
-	}
-
-	offset := (req.Page - 1) * req.PageSize
-	if err := baseQuery.Order("orders.created_at DESC").Offset(offset).Limit(req.PageSize).Find(&response.Orders).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
-	}
-
-	// 转换订单类型：从收款方视角看，payment 订单应该显示为 receive
-	// 并更新 Payee_transfer_status，兼容为空的场景
-	for i := range response.Orders {
-		if response.Orders[i].PayeeTransferStatus == "" {
-			response.Orders[i].PayeeTransferStatus = string(model.OrderTransferStatusCompleted)
-		}
-		if response.Orders[i].Type == model.OrderTypePayment && response.Orders[i].PayeeUserID == user.ID {
-			response.Orders[i].Type = model.OrderTypeReceive
-		}
-	}
-
-	c.JSON(http.StatusOK, util.OK(response))
+	return baseQuery, nil
 }
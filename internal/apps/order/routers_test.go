@@ -0,0 +1,119 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package order
+
+import (
+	"testing"
+	"time"
+
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	tx, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := tx.AutoMigrate(&model.User{}, &model.MerchantAPIKey{}, &model.Order{}, &model.OrderTransfer{}, &model.Dispute{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return tx
+}
+
+// TestBuildTransactionQuerySplitOrder 分账订单存在多条到账记录时，列表只返回一次且使用主收款方的到账状态
+func TestBuildTransactionQuerySplitOrder(t *testing.T) {
+	tx := newTestDB(t)
+
+	owner := &model.User{ID: 1, Username: "owner", SignKey: "k1"}
+	partner := &model.User{ID: 2, Username: "partner", SignKey: "k2"}
+	payer := &model.User{ID: 3, Username: "payer", SignKey: "k3"}
+	for _, u := range []*model.User{owner, partner, payer} {
+		if err := tx.Create(u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	now := time.Now()
+	order := &model.Order{
+		ID:          1001,
+		OrderName:   "split",
+		PayerUserID: payer.ID,
+		PayeeUserID: owner.ID,
+		Amount:      decimal.NewFromInt(100),
+		Status:      model.OrderStatusSuccess,
+		Type:        model.OrderTypePayment,
+		TradeTime:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	if err := tx.Create(order).Error; err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	transfers := []model.OrderTransfer{
+		{OrderID: order.ID, PayeeUserID: owner.ID, Amount: decimal.NewFromInt(70), Status: model.OrderTransferStatusCompleted, TransferAt: now},
+		{OrderID: order.ID, PayeeUserID: partner.ID, Amount: decimal.NewFromInt(30), Status: model.OrderTransferStatusPending, TransferAt: now.Add(24 * time.Hour)},
+	}
+	if err := tx.Create(&transfers).Error; err != nil {
+		t.Fatalf("create transfers: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		user   *model.User
+		status model.OrderTransferStatus
+		want   int64
+	}{
+		{name: "payer", user: payer, want: 1},
+		{name: "owner", user: owner, want: 1},
+		{name: "owner completed", user: owner, status: model.OrderTransferStatusCompleted, want: 1},
+		{name: "owner pending", user: owner, status: model.OrderTransferStatusPending, want: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &TransactionListRequest{Page: 1, PageSize: 20, PayeeTransferStatus: tc.status}
+			query, err := buildTransactionQuery(tx, tc.user, req)
+			if err != nil {
+				t.Fatalf("build query: %v", err)
+			}
+
+			var total int64
+			if err := query.Count(&total).Error; err != nil {
+				t.Fatalf("count: %v", err)
+			}
+			if total != tc.want {
+				t.Fatalf("total = %d, want %d", total, tc.want)
+			}
+
+			var resp TransactionListResponse
+			if err := query.Find(&resp.Orders).Error; err != nil {
+				t.Fatalf("find: %v", err)
+			}
+			if int64(len(resp.Orders)) != tc.want {
+				t.Fatalf("rows = %d, want %d", len(resp.Orders), tc.want)
+			}
+			for _, o := range resp.Orders {
+				if o.PayeeTransferStatus != string(model.OrderTransferStatusCompleted) {
+					t.Fatalf("payee_transfer_status = %q, want %q", o.PayeeTransferStatus, model.OrderTransferStatusCompleted)
+				}
+			}
+		})
+	}
+}
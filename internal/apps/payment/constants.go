@@ -27,3 +27,13 @@ const (
 	// OrderExpireKeyFormat Redis key 格式，用于订单过期监听，key中包含订单ID
 	OrderExpireKeyFormat = "payment:order:expire:%d"
 )
+
+const (
+	// MaxAttachLength 商户附加数据最大字节数
+	MaxAttachLength = 1024
+)
+
+const (
+	// OrderQRCodeURLFormat 订单收银台二维码图片地址，经前端 /epay/pay 代理到后端
+	OrderQRCodeURLFormat = "%s/epay/pay/qrcode.php?order_no=%s"
+)
@@ -27,4 +27,14 @@ const (
 	PayConfigNotFound      = "支付配置不存在"
 	InvalidPublicKeyFormat = "公钥格式错误"
 	InvalidPublicKeyLength = "公钥长度必须为32字节"
+	IPNotAllowed           = "请求 IP 不在白名单内"
+	AttachTooLong          = "附加数据长度不能超过 1024 字节"
+	AttachInvalid          = "附加数据必须为 JSON 对象"
+	SignTypeUnsupported    = "不支持的签名类型"
+	MD5SignDisabled        = "该商户已禁用 MD5 签名"
+	RSAPublicKeyMissing    = "商户未配置 RSA 公钥"
+	InvalidRSAPublicKey    = "RSA 公钥格式错误"
+	SignVerifyFailed       = "签名验证失败"
+	OrderTimeoutInvalid    = "订单超时时间格式错误"
+	APIKeySuspended        = "该 API Key 因争议或退款率过高已被暂停使用，请联系管理员"
 )
@@ -31,13 +31,18 @@ import (
 
 // CreateOrderRequest 商户创建订单统一请求
 type CreateOrderRequest struct {
-	OrderName       string          `json:"order_name" binding:"required,max=64"`
-	MerchantOrderNo *string         `json:"merchant_order_no" binding:"omitempty,min=1,max=64"`
-	Amount          decimal.Decimal `json:"amount" binding:"required"`
-	Remark          string          `json:"remark" binding:"max=100"`
-	PaymentType     string          `json:"payment_type"`
-	NotifyURL       string          `json:"notify_url" binding:"omitempty,max=100,url"`
-	ReturnURL       string          `json:"return_url" binding:"omitempty,max=100,url"`
+	OrderName       string           `json:"order_name" binding:"required,max=64"`
+	MerchantOrderNo *string          `json:"merchant_order_no" binding:"omitempty,min=1,max=64"`
+	Amount          decimal.Decimal  `json:"amount" binding:"required"`
+	Remark          string           `json:"remark" binding:"max=100"`
+	PaymentType     string           `json:"payment_type"`
+	NotifyURL       string           `json:"notify_url" binding:"omitempty,max=100,url"`
+	ReturnURL       string           `json:"return_url" binding:"omitempty,max=100,url"`
+	Attach          string           `json:"attach"`
+	SplitRules      model.SplitRules `json:"split_rules"`
+	SignType        string           `json:"sign_type"`
+	// ExpireMinutes 商户指定的订单过期时间（分钟），0 表示使用系统配置
+	ExpireMinutes int `json:"expire_minutes"`
 }
 
 // EPayRequest 易支付请求
@@ -52,6 +57,10 @@ type EPayRequest struct {
 	Sign            string          `form:"sign" binding:"required"`
 	PayType         string          `form:"type" binding:"required"`
 	SignType        string          `form:"sign_type"`
+	Attach          string          `form:"attach"`
+	Splits          string          `form:"splits"`
+	TimeoutExpress  string          `form:"timeout_express"`
+	ExpireMinutes   string          `form:"expire_minutes"`
 }
 
 // LDCPayRequest LDC支付请求
@@ -64,10 +73,14 @@ type LDCPayRequest struct {
 	ReturnURL       string          `form:"return_url" binding:"omitempty,max=100,url"`
 	PayType         string          `form:"type" binding:"required"`
 	Sign            string          `form:"sign" binding:"required"`
+	Attach          string          `form:"attach"`
+	Splits          string          `form:"splits"`
+	TimeoutExpress  string          `form:"timeout_express"`
+	ExpireMinutes   string          `form:"expire_minutes"`
 }
 
 // NewCreateOrderRequest 从支付请求创建通用订单请求
-func NewCreateOrderRequest(orderName string, merchantOrderNo *string, amount decimal.Decimal, payType string, notifyURL string, returnURL string) *CreateOrderRequest {
+func NewCreateOrderRequest(orderName string, merchantOrderNo *string, amount decimal.Decimal, payType string, notifyURL string, returnURL string, attach string, splitRules model.SplitRules) *CreateOrderRequest {
 	return &CreateOrderRequest{
 		OrderName:       orderName,
 		MerchantOrderNo: merchantOrderNo,
@@ -75,6 +88,8 @@ func NewCreateOrderRequest(orderName string, merchantOrderNo *string, amount dec
 		PaymentType:     payType,
 		NotifyURL:       notifyURL,
 		ReturnURL:       returnURL,
+		Attach:          attach,
+		SplitRules:      splitRules,
 	}
 }
 
@@ -120,12 +135,39 @@ func RequireMerchantAuth() gin.HandlerFunc {
 			return
 		}
 
+		if !CheckAPIKeyIP(c, &apiKey) {
+			c.AbortWithStatusJSON(http.StatusForbidden, util.Err(IPNotAllowed))
+			return
+		}
+
+		if apiKey.IsSuspended() {
+			c.AbortWithStatusJSON(http.StatusForbidden, util.Err(APIKeySuspended))
+			return
+		}
+
 		util.SetToContext(c, APIKeyObjKey, &apiKey)
 
 		c.Next()
 	}
 }
 
+// LoadEPayAPIKey 按 pid 与 key 识别易支付兼容接口的 API Key 并写入上下文，供认证后的按商户限流使用
+// 识别失败时不中断请求，认证与错误响应仍由处理函数完成
+func LoadEPayAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, clientSecret := c.Request.FormValue("pid"), c.Request.FormValue("key")
+		if clientID != "" && clientSecret != "" {
+			var apiKey model.MerchantAPIKey
+			if err := db.DB(c.Request.Context()).
+				Where("client_secret = ? AND client_id = ?", clientSecret, clientID).
+				First(&apiKey).Error; err == nil {
+				util.SetToContext(c, APIKeyObjKey, &apiKey)
+			}
+		}
+		c.Next()
+	}
+}
+
 // RequireSignatureAuth 验证签名
 func RequireSignatureAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -143,7 +185,7 @@ func RequireSignatureAuth() gin.HandlerFunc {
 				return
 			}
 		case common.PayTypeEPay:
-			createOrderReq, err = VerifySignatureMD5(c, &apiKey)
+			createOrderReq, err = VerifySignatureEPay(c, &apiKey)
 			if err != nil {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, util.Err(err.Error()))
 				return
@@ -153,6 +195,11 @@ func RequireSignatureAuth() gin.HandlerFunc {
 			return
 		}
 
+		if apiKey.IsSuspended() {
+			c.AbortWithStatusJSON(http.StatusForbidden, util.Err(APIKeySuspended))
+			return
+		}
+
 		util.SetToContext(c, CreateOrderRequestKey, createOrderReq)
 		util.SetToContext(c, APIKeyObjKey, &apiKey)
 
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/linux-do/credit/internal/apps/oauth"
@@ -102,21 +103,86 @@ func CreateMerchantOrder(c *gin.Context) {
 	req, _ := util.GetFromContext[*CreateOrderRequest](c, CreateOrderRequestKey)
 	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, APIKeyObjKey)
 
+	result, status, err := createMerchantOrder(c, req, apiKey)
+	if err != nil {
+		c.JSON(status, util.Err(err.Error()))
+		return
+	}
+
+	c.Redirect(http.StatusFound, result.PayURL)
+}
+
+// CreateMerchantOrderAPIResponse API 模式下单响应
+type CreateMerchantOrderAPIResponse struct {
+	Code       int    `json:"code" example:"1"`
+	Msg        string `json:"msg" example:"下单成功"`
+	TradeNo    string `json:"trade_no" example:"123456"`
+	OutTradeNo string `json:"out_trade_no" example:"M202312080001"`
+	PayURL     string `json:"payurl" example:"https://credit.linux.do/paying?order_no=xxx"`
+	QRCode     string `json:"qrcode" example:"https://credit.linux.do/paying?order_no=xxx"`
+	Img        string `json:"img" example:"https://credit.linux.do/epay/pay/qrcode.php?order_no=xxx"`
+}
+
+// CreateMerchantOrderAPI 商户 API 模式创建订单接口（易支付 mapi），以 JSON 返回支付链接
+// @Tags payment
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param request body CreateOrderRequest true "request body"
+// @Success 200 {object} CreateMerchantOrderAPIResponse
+// @Router /pay/mapi.php [post]
+// @Router /pay/mapi.php [get]
+func CreateMerchantOrderAPI(c *gin.Context) {
+	req, _ := util.GetFromContext[*CreateOrderRequest](c, CreateOrderRequestKey)
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, APIKeyObjKey)
+
+	result, status, err := createMerchantOrder(c, req, apiKey)
+	if err != nil {
+		c.JSON(status, gin.H{"code": -1, "msg": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, CreateMerchantOrderAPIResponse{
+		Code:       1,
+		Msg:        "下单成功",
+		TradeNo:    strconv.FormatUint(result.OrderID, 10),
+		OutTradeNo: util.DerefString(req.MerchantOrderNo),
+		PayURL:     result.PayURL,
+		QRCode:     result.PayURL,
+		Img:        fmt.Sprintf(OrderQRCodeURLFormat, strings.TrimRight(config.Config.App.FrontendURL, "/"), url.QueryEscape(result.OrderNo)),
+	})
+}
+
+// createdMerchantOrder 已创建的商户订单
+type createdMerchantOrder struct {
+	OrderID uint64
+	// OrderNo 加密后的收银台订单号
+	OrderNo string
+	PayURL  string
+}
+
+// createMerchantOrder 创建商户订单并写入收银台订单号映射，失败时返回对应的 HTTP 状态码
+func createMerchantOrder(c *gin.Context, req *CreateOrderRequest, apiKey *model.MerchantAPIKey) (*createdMerchantOrder, int, error) {
 	// 获取商户用户信息
 	var merchantUser model.User
 	if err := db.DB(c.Request.Context()).Where("id = ? AND is_active = ?", apiKey.UserID, true).First(&merchantUser).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(MerchantInfoNotFound))
-		return
+		return nil, http.StatusInternalServerError, errors.New(MerchantInfoNotFound)
+	}
+
+	// 校验订单级分账规则
+	if err := service.ValidateSplitRules(db.DB(c.Request.Context()), merchantUser.ID, req.SplitRules, &req.Amount); err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 
 	// 获取商家订单过期时间（分钟）
 	expireMinutes, errGet := model.GetIntByKey(c.Request.Context(), model.ConfigKeyMerchantOrderExpireMinutes)
 	if errGet != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(errGet.Error()))
-		return
+		return nil, http.StatusInternalServerError, errGet
+	}
+	if req.ExpireMinutes > 0 {
+		expireMinutes = model.ClampMerchantOrderExpireMinutes(c.Request.Context(), req.ExpireMinutes)
 	}
 
-	var payURL string
+	var result createdMerchantOrder
 
 	if err := db.DB(c.Request.Context()).Transaction(
 		func(tx *gorm.DB) error {
@@ -134,6 +200,10 @@ func CreateMerchantOrder(c *gin.Context) {
 				RedirectURI:     req.ReturnURL,
 				NotifyURL:       req.NotifyURL,
 				ExpiresAt:       time.Now().Add(time.Duration(expireMinutes) * time.Minute),
+				IsSandbox:       apiKey.TestMode,
+				Attach:          req.Attach,
+				SplitRules:      req.SplitRules,
+				SignType:        req.SignType,
 			}
 			if err := tx.Create(&order).Error; err != nil {
 				return err
@@ -154,15 +224,18 @@ func CreateMerchantOrder(c *gin.Context) {
 				return fmt.Errorf("failed to set order expire key: %w", errSet)
 			}
 
-			payURL = fmt.Sprintf("%s?order_no=%s", config.Config.App.FrontendPayURL, url.QueryEscape(encryptString))
+			result = createdMerchantOrder{
+				OrderID: order.ID,
+				OrderNo: encryptString,
+				PayURL:  fmt.Sprintf("%s?order_no=%s", config.Config.App.FrontendPayURL, url.QueryEscape(encryptString)),
+			}
 			return nil
 		},
 	); err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
+		return nil, http.StatusInternalServerError, err
 	}
 
-	c.Redirect(http.StatusFound, payURL)
+	return &result, http.StatusOK, nil
 }
 
 // QueryMerchantOrderResponse 查询订单响应
@@ -178,6 +251,7 @@ type QueryMerchantOrderResponse struct {
 	Name       string `json:"name" example:"商品名称"`
 	Money      string `json:"money" example:"10.00"`
 	Status     int    `json:"status" example:"1"`
+	Attach     string `json:"attach" example:"{\"user_id\":\"1001\"}"`
 }
 
 // QueryMerchantOrder 商户主动查询订单状态接口
@@ -227,6 +301,7 @@ func QueryMerchantOrder(c *gin.Context) {
 		"name":         order.OrderName,
 		"money":        order.Amount.Truncate(2).StringFixed(2),
 		"status":       statusInt,
+		"attach":       order.Attach,
 	})
 }
 
@@ -261,10 +336,16 @@ func RefundMerchantOrder(c *gin.Context) {
 		return
 	}
 
+	if !CheckAPIKeyIP(c, &apiKey) {
+		c.JSON(http.StatusForbidden, gin.H{"code": -1, "msg": IPNotAllowed})
+		return
+	}
+
 	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 		var order model.Order
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("id = ? AND client_id = ? AND status = ? AND amount = ? AND type IN ?", req.TradeNo, req.ClientID, model.OrderStatusSuccess, req.Amount, []model.OrderType{model.OrderTypePayment, model.OrderTypeOnline}).
+			Where("id = ? AND client_id = ? AND status = ? AND amount = ?", req.TradeNo, req.ClientID, model.OrderStatusSuccess, req.Amount).
+			Where("type IN ? OR (is_sandbox = ? AND type = ?)", []model.OrderType{model.OrderTypePayment, model.OrderTypeOnline}, true, model.OrderTypeTest).
 			First(&order).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return errors.New(OrderNotFound)
@@ -272,11 +353,6 @@ func RefundMerchantOrder(c *gin.Context) {
 			return err
 		}
 
-		var payerUser model.User
-		if err := payerUser.GetByID(tx, order.PayerUserID); err != nil {
-			return err
-		}
-
 		var merchantUser model.User
 		if err := tx.Where("id = ? AND is_active = ?", apiKey.UserID, true).First(&merchantUser).Error; err != nil {
 			return err
@@ -287,30 +363,37 @@ func RefundMerchantOrder(c *gin.Context) {
 			return err
 		}
 
-		merchantScoreDecrease := order.Amount.Mul(merchantPayConfig.ScoreRate).Round(0).IntPart()
-		if err := tx.Model(&model.User{}).
-			Where("id = ?", merchantUser.ID).
-			UpdateColumns(map[string]interface{}{
-				"available_balance": gorm.Expr("available_balance - ?", order.Amount),
-				"total_receive":     gorm.Expr("total_receive - ?", order.Amount),
-				"pay_score":         gorm.Expr("pay_score - ?", merchantScoreDecrease),
-			}).Error; err != nil {
+		// 沙箱订单：仅退回模拟余额，按支付时记录的手续费扣回商户净额
+		if order.IsSandbox {
+			if err := service.ApplySandboxRefund(tx, apiKey.ID, &order, merchantPayConfig.FeeRate); err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Order{}).
+				Where("id = ?", order.ID).
+				Updates(map[string]interface{}{
+					"status":      model.OrderStatusRefund,
+					"refunded_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+			return service.EnqueueMerchantEventNotify(order.ID, order.ClientID, common.TradeStatusRefund)
+		}
+
+		var payerUser model.User
+		if err := payerUser.GetByID(tx, order.PayerUserID); err != nil {
 			return err
 		}
 
-		if err := tx.Model(&model.User{}).
-			Where("id = ?", payerUser.ID).
-			UpdateColumns(map[string]interface{}{
-				"available_balance": gorm.Expr("available_balance + ?", order.Amount),
-				"total_payment":     gorm.Expr("total_payment - ?", order.Amount),
-				"pay_score":         gorm.Expr("pay_score - ?", order.Amount.Round(0).IntPart()),
-			}).Error; err != nil {
+		if err := service.RefundOrderAmount(tx, &order, &merchantPayConfig, order.Amount); err != nil {
 			return err
 		}
 
 		if err := tx.Model(&model.Order{}).
 			Where("id = ?", order.ID).
-			Update("status", model.OrderStatusRefund).Error; err != nil {
+			Updates(map[string]interface{}{
+				"status":      model.OrderStatusRefund,
+				"refunded_at": time.Now(),
+			}).Error; err != nil {
 			return err
 		}
 
@@ -381,6 +464,29 @@ func MerchantDistribute(c *gin.Context) {
 			return errors.New(CannotTransferToSelf)
 		}
 
+		// 测试模式：仅扣减沙箱模拟余额，不影响真实用户
+		if apiKey.TestMode {
+			order := model.Order{
+				OrderName:       "商户分发",
+				ClientID:        apiKey.ClientID,
+				MerchantOrderNo: req.MerchantOrderNo,
+				PayerUserID:     merchantUser.ID,
+				PayeeUserID:     recipient.ID,
+				Amount:          req.Amount,
+				Status:          model.OrderStatusSuccess,
+				Type:            model.OrderTypeDistribute,
+				Remark:          common.TestModeOrderRemark,
+				TradeTime:       time.Now(),
+				ExpiresAt:       time.Now().Add(24 * time.Hour),
+				IsSandbox:       true,
+			}
+			if err := tx.Create(&order).Error; err != nil {
+				return err
+			}
+			orderID = order.ID
+			return service.ApplySandboxDistribute(tx, apiKey.ID, req.Amount)
+		}
+
 		// 获取商户支付配置
 		var merchantPayConfig model.UserPayConfig
 		if err := merchantPayConfig.GetByPayScore(tx, merchantUser.PayScore); err != nil {
@@ -572,7 +678,7 @@ func PayMerchantOrder(c *gin.Context) {
 			}
 
 			// 计算手续费
-			_, merchantAmount, feePercent := service.CalculateFee(order.Amount, orderCtx.MerchantPayConfig.FeeRate)
+			_, _, feePercent := service.CalculateFee(order.Amount, orderCtx.MerchantPayConfig.FeeRate)
 
 			// 更新订单状态
 			order.Status = model.OrderStatusSuccess
@@ -582,6 +688,7 @@ func PayMerchantOrder(c *gin.Context) {
 			if isTestMode {
 				order.Type = model.OrderTypeTest
 				order.Remark = common.TestModeOrderRemark
+				order.IsSandbox = true
 			} else {
 				feeRemark := fmt.Sprintf("[系统]: 收取商家%d%%手续费", feePercent)
 				if order.Remark != "" {
@@ -595,6 +702,13 @@ func PayMerchantOrder(c *gin.Context) {
 				return err
 			}
 
+			// 测试模式：仅变动沙箱模拟余额
+			if isTestMode {
+				if err := service.ApplySandboxPayment(tx, orderCtx.MerchantAPIKey.ID, &order, orderCtx.MerchantPayConfig.FeeRate); err != nil {
+					return err
+				}
+			}
+
 			// 非测试模式：扣减用户余额和增加商户余额
 			if !isTestMode {
 				// 扣用户
@@ -609,29 +723,12 @@ func PayMerchantOrder(c *gin.Context) {
 					return err
 				}
 
-				// 加给商家
-				merchantScoreIncrease := order.Amount.Mul(orderCtx.MerchantPayConfig.ScoreRate).Round(0).IntPart()
-				if err := service.UpdateBalance(tx, service.BalanceUpdateOptions{
-					UserID:        orderCtx.MerchantUser.ID,
-					Amount:        merchantAmount,
-					Operation:     service.BalanceAdd,
-					ScoreChange:   merchantScoreIncrease,
-					TotalField:    "total_receive",
-					CheckBalance:  false,
-					AsyncTransfer: true,
-				}); err != nil {
-					return err
-				}
-
-				// 异步到账任务
-				orderTransfer := model.OrderTransfer{
-					OrderID:     order.ID,
-					PayeeUserID: order.PayeeUserID,
-					Amount:      merchantAmount,
-					Status:      model.OrderTransferStatusPending,
-					TransferAt:  model.GetRandomSettleAt(c.Request.Context()),
+				// 按分账规则加给商家及各收款方，订单未指定时沿用 API Key 的分账规则
+				splitRules := order.SplitRules
+				if splitRules == nil {
+					splitRules = orderCtx.MerchantAPIKey.SplitRules
 				}
-				if err := tx.Create(&orderTransfer).Error; err != nil {
+				if err := service.CreditMerchantPayees(tx, &order, orderCtx.MerchantPayConfig, splitRules); err != nil {
 					return err
 				}
 			}
@@ -646,7 +743,7 @@ func PayMerchantOrder(c *gin.Context) {
 	); err != nil {
 		errMsg := err.Error()
 		switch errMsg {
-		case common.InsufficientBalance, OrderExpired, common.DailyLimitExceeded:
+		case common.InsufficientBalance, OrderExpired, common.DailyLimitExceeded, common.SplitAmountExceeded, common.SplitPayeeInvalid:
 			c.JSON(http.StatusBadRequest, util.Err(errMsg))
 		case OrderNotFound:
 			c.JSON(http.StatusNotFound, util.Err(errMsg))
@@ -42,17 +42,25 @@ import (
 func HandleMerchantPaymentNotify(ctx context.Context, t *asynq.Task) error {
 	// 解析任务参数
 	var payload struct {
-		OrderID  uint64 `json:"order_id"`
-		ClientID string `json:"client_id"`
+		OrderID     uint64 `json:"order_id"`
+		ClientID    string `json:"client_id"`
+		TradeStatus string `json:"trade_status"`
 	}
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
 		logger.ErrorF(ctx, "解析商户回调任务参数失败: %v", err)
 		return fmt.Errorf("解析任务参数失败: %w", err)
 	}
 
-	// 查询订单信息
+	// 兼容旧任务：未携带交易状态时视为支付成功回调
+	tradeStatus := cmp.Or(payload.TradeStatus, common.TradeStatusSuccess)
+
+	// 查询订单信息，支付成功回调要求订单仍为成功状态
+	orderQuery := db.DB(ctx).Where("id = ?", payload.OrderID)
+	if tradeStatus == common.TradeStatusSuccess {
+		orderQuery = orderQuery.Where("status = ?", model.OrderStatusSuccess)
+	}
 	var order model.Order
-	if err := db.DB(ctx).Where("id = ? AND status = ?", payload.OrderID, model.OrderStatusSuccess).First(&order).Error; err != nil {
+	if err := orderQuery.First(&order).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.ErrorF(ctx, "订单[ID:%d]不存在，跳过回调", payload.OrderID)
 			return nil
@@ -83,24 +91,33 @@ func HandleMerchantPaymentNotify(ctx context.Context, t *asynq.Task) error {
 		"type":         common.PayTypeEPay,
 		"name":         order.OrderName,
 		"money":        order.Amount.Truncate(2).StringFixed(2),
-		"trade_status": "TRADE_SUCCESS",
+		"trade_status": tradeStatus,
+	}
+	if order.IsSandbox {
+		callbackParams["sandbox"] = "1"
+	}
+	if order.PayerMessage != "" {
+		callbackParams["message"] = order.PayerMessage
+	}
+	if order.Attach != "" {
+		callbackParams["attach"] = order.Attach
 	}
-	callbackParams["sign"] = GenerateSignature(callbackParams, apiKey.ClientSecret, true)
+	SignCallbackParams(callbackParams, &apiKey, order.SignType)
 
 	// 回调
-	if err := sendCallbackRequest(ctx, callbackURL, callbackParams); err != nil {
+	if err := SendCallbackRequest(ctx, callbackURL, callbackParams); err != nil {
 		retried, _ := asynq.GetRetryCount(ctx)
 		logger.ErrorF(ctx, "商户回调失败: 订单[ID:%d] 重试次数[%d] 错误: %v",
 			payload.OrderID, retried+1, err)
 		return err
 	}
 
-	logger.InfoF(ctx, "商户回调成功: 订单[ID:%d] ClientID[%s]", payload.OrderID, payload.ClientID)
+	logger.InfoF(ctx, "商户回调成功: 订单[ID:%d] ClientID[%s] 状态[%s]", payload.OrderID, payload.ClientID, tradeStatus)
 	return nil
 }
 
-// sendCallbackRequest 发送HTTP回调请求
-func sendCallbackRequest(ctx context.Context, callbackURL string, params map[string]string) error {
+// SendCallbackRequest 发送HTTP回调请求
+func SendCallbackRequest(ctx context.Context, callbackURL string, params map[string]string) error {
 	vals := url.Values{}
 	for k, v := range params {
 		vals.Add(k, v)
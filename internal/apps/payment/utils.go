@@ -18,7 +18,10 @@ package payment
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -30,6 +33,7 @@ import (
 	"github.com/linux-do/credit/internal/apps/oauth"
 	"github.com/linux-do/credit/internal/common"
 	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/service"
 	"github.com/linux-do/credit/internal/util"
@@ -139,7 +143,7 @@ func ParseOrderNo(c *gin.Context, orderNo string) (*OrderContext, error) {
 
 	// 获取商家的支付配置（用于手续费倍率）
 	var merchantPayConfig model.UserPayConfig
-	if err := merchantPayConfig.GetByPayScore(db.DB(c.Request.Context()), merchantUser.PayScore); err != nil {
+	if err := merchantPayConfig.GetByMerchant(db.DB(c.Request.Context()), &merchantUser); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New(PayConfigNotFound)
 		}
@@ -150,6 +154,27 @@ func ParseOrderNo(c *gin.Context, orderNo string) (*OrderContext, error) {
 	return ctx, nil
 }
 
+// CheckAPIKeyIP 校验请求 IP 是否在 API Key 白名单内，拒绝时记录访问日志供商户查看
+func CheckAPIKeyIP(c *gin.Context, apiKey *model.MerchantAPIKey) bool {
+	clientIP := c.ClientIP()
+	if apiKey.IsIPAllowed(clientIP) {
+		return true
+	}
+
+	denial := model.MerchantAPIKeyDenial{
+		MerchantAPIKeyID: apiKey.ID,
+		ClientIP:         clientIP,
+		Method:           c.Request.Method,
+		Path:             util.TruncateString(c.Request.URL.Path, 255),
+		UserAgent:        util.TruncateString(c.Request.UserAgent(), 255),
+	}
+	if err := db.DB(c.Request.Context()).Create(&denial).Error; err != nil {
+		logger.ErrorF(c.Request.Context(), "记录商户[ClientID:%s]IP拒绝日志失败: %v", apiKey.ClientID, err)
+	}
+
+	return false
+}
+
 // GenerateSignature 生成签名
 func GenerateSignature(params map[string]string, secret string, isMD5 bool) string {
 	// 按key排序
@@ -188,47 +213,141 @@ func GenerateSignature(params map[string]string, secret string, isMD5 bool) stri
 	return builder.String()
 }
 
-// VerifySignatureMD5 验证MD5签名
-func VerifySignatureMD5(c *gin.Context, apiKey *model.MerchantAPIKey) (*CreateOrderRequest, error) {
+// VerifySignatureEPay 验证易支付签名，按 sign_type 选择 MD5、SHA-256 或 RSA-SHA256，缺省为 MD5
+func VerifySignatureEPay(c *gin.Context, apiKey *model.MerchantAPIKey) (*CreateOrderRequest, error) {
 	var req EPayRequest
 	if err := c.ShouldBind(&req); err != nil {
 		return nil, err
 	}
 
+	signType, err := NormalizeSignType(req.SignType)
+	if err != nil {
+		return nil, err
+	}
+
 	// 验证金额
 	if err := util.ValidateAmount(req.Amount); err != nil {
 		return nil, err
 	}
 
+	// 验证附加数据
+	if err := ValidateAttach(req.Attach); err != nil {
+		return nil, err
+	}
+
+	// 解析分账规则
+	splitRules, err := ParseSplitRules(req.Splits)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析订单超时时间
+	expireMinutes, err := ParseOrderTimeout(req.TimeoutExpress, req.ExpireMinutes)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := apiKey.GetByClientID(db.DB(c.Request.Context()), req.ClientID); err != nil {
 		return nil, err
 	}
 
+	if signType == common.SignTypeMD5 && apiKey.DisableMD5 {
+		return nil, errors.New(MD5SignDisabled)
+	}
+
 	// 构建签名参数
 	params := map[string]string{
-		"pid":          req.ClientID,
-		"type":         req.PayType,
-		"out_trade_no": util.DerefString(req.MerchantOrderNo),
-		"notify_url":   req.NotifyURL,
-		"return_url":   req.ReturnURL,
-		"name":         req.OrderName,
-		"device":       req.Device,
+		"pid":             req.ClientID,
+		"type":            req.PayType,
+		"out_trade_no":    util.DerefString(req.MerchantOrderNo),
+		"notify_url":      req.NotifyURL,
+		"return_url":      req.ReturnURL,
+		"name":            req.OrderName,
+		"device":          req.Device,
+		"attach":          req.Attach,
+		"splits":          req.Splits,
+		"timeout_express": req.TimeoutExpress,
+		"expire_minutes":  req.ExpireMinutes,
 	}
 
-	params["money"] = req.Amount.Truncate(2).StringFixed(2)
-	expectedSignFixed := GenerateSignature(params, apiKey.ClientSecret, true)
+	// 金额兼容两位小数与去除末尾零两种写法
+	var verified bool
+	for _, money := range []string{req.Amount.Truncate(2).StringFixed(2), req.Amount.Truncate(2).String()} {
+		params["money"] = money
+		ok, errVerify := verifyEPaySign(params, apiKey, signType, req.Sign)
+		if errVerify != nil {
+			return nil, errVerify
+		}
+		if ok {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New(SignVerifyFailed)
+	}
+
+	createOrderReq := NewCreateOrderRequest(req.OrderName, req.MerchantOrderNo, req.Amount, req.PayType, req.NotifyURL, req.ReturnURL, req.Attach, splitRules)
+	createOrderReq.SignType = signType
+	createOrderReq.ExpireMinutes = expireMinutes
+	return createOrderReq, nil
+}
 
-	params["money"] = req.Amount.Truncate(2).String()
-	expectedSignTrimmed := GenerateSignature(params, apiKey.ClientSecret, true)
+// NormalizeSignType 规范化 sign_type，未传时视为 MD5
+func NormalizeSignType(signType string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(signType)) {
+	case "", common.SignTypeMD5:
+		return common.SignTypeMD5, nil
+	case common.SignTypeSHA256, "SHA-256":
+		return common.SignTypeSHA256, nil
+	case common.SignTypeRSA:
+		return common.SignTypeRSA, nil
+	default:
+		return "", errors.New(SignTypeUnsupported)
+	}
+}
 
-	matchFixed := subtle.ConstantTimeCompare([]byte(strings.ToLower(expectedSignFixed)), []byte(strings.ToLower(req.Sign))) == 1
-	matchTrimmed := subtle.ConstantTimeCompare([]byte(strings.ToLower(expectedSignTrimmed)), []byte(strings.ToLower(req.Sign))) == 1
+// verifyEPaySign 按签名类型校验易支付签名
+func verifyEPaySign(params map[string]string, apiKey *model.MerchantAPIKey, signType string, sign string) (bool, error) {
+	switch signType {
+	case common.SignTypeRSA:
+		if apiKey.RSAPublicKey == "" {
+			return false, errors.New(RSAPublicKeyMissing)
+		}
+		publicKey, err := util.ParseRSAPublicKey(apiKey.RSAPublicKey)
+		if err != nil {
+			return false, errors.New(InvalidRSAPublicKey)
+		}
+		signatureBytes, err := util.Base64Decode(sign)
+		if err != nil {
+			return false, errors.New("签名格式错误")
+		}
+		// RSA 签名原文不拼接商户密钥
+		return util.RSAVerifySHA256(publicKey, []byte(GenerateSignature(params, "", false)), signatureBytes), nil
+	default:
+		expected := SignParams(params, apiKey.ClientSecret, signType)
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(sign))) == 1, nil
+	}
+}
 
-	if !matchFixed && !matchTrimmed {
-		return nil, errors.New("签名验证失败")
+// SignParams 生成 MD5 或 SHA-256 摘要签名（小写十六进制）
+func SignParams(params map[string]string, secret string, signType string) string {
+	if signType == common.SignTypeSHA256 {
+		hash := sha256.Sum256([]byte(GenerateSignature(params, secret, false)))
+		return hex.EncodeToString(hash[:])
 	}
+	return GenerateSignature(params, secret, true)
+}
 
-	return NewCreateOrderRequest(req.OrderName, req.MerchantOrderNo, req.Amount, req.PayType, req.NotifyURL, req.ReturnURL), nil
+// SignCallbackParams 为商户回调参数签名并写入 sign 与 sign_type
+// 回调由平台发起，RSA 订单与禁用 MD5 的商户统一使用 SHA-256 签名
+func SignCallbackParams(params map[string]string, apiKey *model.MerchantAPIKey, orderSignType string) {
+	signType := common.SignTypeMD5
+	if orderSignType == common.SignTypeSHA256 || orderSignType == common.SignTypeRSA || apiKey.DisableMD5 {
+		signType = common.SignTypeSHA256
+	}
+	params["sign"] = SignParams(params, apiKey.ClientSecret, signType)
+	params["sign_type"] = signType
 }
 
 // VerifySignatureEd25519 验证 Ed25519 签名
@@ -243,6 +362,23 @@ func VerifySignatureEd25519(c *gin.Context, apiKey *model.MerchantAPIKey) (*Crea
 		return nil, err
 	}
 
+	// 验证附加数据
+	if err := ValidateAttach(req.Attach); err != nil {
+		return nil, err
+	}
+
+	// 解析分账规则
+	splitRules, err := ParseSplitRules(req.Splits)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析订单超时时间
+	expireMinutes, err := ParseOrderTimeout(req.TimeoutExpress, req.ExpireMinutes)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := apiKey.GetByClientID(db.DB(c.Request.Context()), req.ClientID); err != nil {
 		return nil, err
 	}
@@ -258,21 +394,85 @@ func VerifySignatureEd25519(c *gin.Context, apiKey *model.MerchantAPIKey) (*Crea
 
 	// 构建签名参数
 	params := map[string]string{
-		"client_id":    req.ClientID,
-		"type":         req.PayType,
-		"out_trade_no": util.DerefString(req.MerchantOrderNo),
-		"order_name":   req.OrderName,
-		"notify_url":   req.NotifyURL,
-		"return_url":   req.ReturnURL,
-		"money":        req.Amount.Truncate(2).StringFixed(2),
+		"client_id":       req.ClientID,
+		"type":            req.PayType,
+		"out_trade_no":    util.DerefString(req.MerchantOrderNo),
+		"order_name":      req.OrderName,
+		"notify_url":      req.NotifyURL,
+		"return_url":      req.ReturnURL,
+		"money":           req.Amount.Truncate(2).StringFixed(2),
+		"attach":          req.Attach,
+		"splits":          req.Splits,
+		"timeout_express": req.TimeoutExpress,
+		"expire_minutes":  req.ExpireMinutes,
 	}
 
 	signatureParam := GenerateSignature(params, apiKey.ClientSecret, false)
 	validTrimmed := util.Ed25519Verify(apiKey.PublicKey, []byte(signatureParam), signatureBytes)
 
 	if !validTrimmed {
-		return nil, errors.New("签名验证失败")
+		return nil, errors.New(SignVerifyFailed)
 	}
 
-	return NewCreateOrderRequest(req.OrderName, req.MerchantOrderNo, req.Amount, req.PayType, req.NotifyURL, req.ReturnURL), nil
+	createOrderReq := NewCreateOrderRequest(req.OrderName, req.MerchantOrderNo, req.Amount, req.PayType, req.NotifyURL, req.ReturnURL, req.Attach, splitRules)
+	createOrderReq.ExpireMinutes = expireMinutes
+	return createOrderReq, nil
+}
+
+// ParseOrderTimeout 解析订单超时时间，返回分钟数，未指定时返回 0
+// expire_minutes 为整数分钟，优先于 timeout_express；timeout_express 支持 m（分钟）、h（小时）、d（天）单位，如 30m、2h、3d
+func ParseOrderTimeout(timeoutExpress string, expireMinutes string) (int, error) {
+	if expireMinutes != "" {
+		minutes, err := strconv.Atoi(expireMinutes)
+		if err != nil || minutes <= 0 {
+			return 0, errors.New(OrderTimeoutInvalid)
+		}
+		return minutes, nil
+	}
+	if timeoutExpress == "" {
+		return 0, nil
+	}
+
+	unit := timeoutExpress[len(timeoutExpress)-1]
+	value, err := strconv.Atoi(timeoutExpress[:len(timeoutExpress)-1])
+	if err != nil || value <= 0 {
+		return 0, errors.New(OrderTimeoutInvalid)
+	}
+	switch unit {
+	case 'm':
+		return value, nil
+	case 'h':
+		return value * 60, nil
+	case 'd':
+		return value * 24 * 60, nil
+	default:
+		return 0, errors.New(OrderTimeoutInvalid)
+	}
+}
+
+// ValidateAttach 验证商户附加数据，须为不超过长度限制的 JSON 对象
+func ValidateAttach(attach string) error {
+	if attach == "" {
+		return nil
+	}
+	if len(attach) > MaxAttachLength {
+		return errors.New(AttachTooLong)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(attach), &obj); err != nil || obj == nil {
+		return errors.New(AttachInvalid)
+	}
+	return nil
+}
+
+// ParseSplitRules 解析订单级分账规则（JSON 数组），未传时返回 nil 表示沿用 API Key 的分账规则
+func ParseSplitRules(raw string) (model.SplitRules, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var rules model.SplitRules
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, errors.New(common.SplitRulesInvalid)
+	}
+	return rules, nil
 }
@@ -0,0 +1,39 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package qrcode
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+const (
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 1024
+	DefaultMargin = 4
+	MaxMargin     = 16
+)
+
+const (
+	// storageKeyFormat 二维码缓存在对象存储中的路径
+	storageKeyFormat = "qrcode/%s.%s"
+	// paymentLinkURLFormat 支付链接收银台地址
+	paymentLinkURLFormat = "%s/online?token=%s"
+	// orderPayURLFormat 订单收银台地址
+	orderPayURLFormat = "%s?order_no=%s"
+)
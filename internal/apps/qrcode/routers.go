@@ -0,0 +1,130 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/linux-do/credit/internal/apps/merchant"
+	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/config"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// QRCodeRequest 二维码渲染参数
+type QRCodeRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=png svg"`
+	Size   int    `form:"size" binding:"omitempty,min=64,max=1024"`
+	Margin *int   `form:"margin" binding:"omitempty,min=0,max=16"`
+}
+
+// OrderQRCodeRequest 订单二维码请求
+type OrderQRCodeRequest struct {
+	QRCodeRequest
+	OrderNo string `form:"order_no" binding:"required"`
+}
+
+// toOptions 填充默认值
+func (r *QRCodeRequest) toOptions() renderOptions {
+	opts := renderOptions{
+		Format: FormatPNG,
+		Size:   DefaultSize,
+		Margin: DefaultMargin,
+	}
+	if r.Format != "" {
+		opts.Format = r.Format
+	}
+	if r.Size > 0 {
+		opts.Size = r.Size
+	}
+	if r.Margin != nil {
+		opts.Margin = *r.Margin
+	}
+	return opts
+}
+
+// GetPaymentLinkQRCode 获取支付链接二维码
+// @Tags merchant
+// @Produce png
+// @Produce image/svg+xml
+// @Param token path string true "支付链接 Token"
+// @Param request query QRCodeRequest false "渲染参数"
+// @Success 200
+// @Router /api/v1/merchant/payment-links/{token}/qrcode [get]
+func GetPaymentLinkQRCode(c *gin.Context) {
+	var req QRCodeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	paymentLink, _ := util.GetFromContext[*model.MerchantPaymentLink](c, merchant.PaymentLinkObjKey)
+
+	content := fmt.Sprintf(paymentLinkURLFormat, strings.TrimRight(config.Config.App.FrontendPayURL, "/"), url.QueryEscape(paymentLink.Token))
+	writeQRCode(c, content, req.toOptions())
+}
+
+// GetOrderQRCode 获取待支付订单的收银台二维码
+// @Tags payment
+// @Produce png
+// @Produce image/svg+xml
+// @Param request query OrderQRCodeRequest true "订单号及渲染参数"
+// @Success 200
+// @Router /api/v1/merchant/payment/order/qrcode [get]
+// @Router /pay/qrcode.php [get]
+func GetOrderQRCode(c *gin.Context) {
+	var req OrderQRCodeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	// 仅待支付订单存在该缓存 key
+	if err := db.Redis.Get(c.Request.Context(), db.PrefixedKey(fmt.Sprintf(payment.OrderMerchantIDCacheKeyFormat, req.OrderNo))).Err(); err != nil {
+		if errors.Is(err, redis.Nil) {
+			c.JSON(http.StatusNotFound, util.Err(OrderNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	content := fmt.Sprintf(orderPayURLFormat, config.Config.App.FrontendPayURL, url.QueryEscape(req.OrderNo))
+	writeQRCode(c, content, req.toOptions())
+}
+
+// writeQRCode 渲染并输出二维码
+func writeQRCode(c *gin.Context, content string, opts renderOptions) {
+	data, err := getOrRender(c.Request.Context(), content, opts)
+	if err != nil {
+		logger.ErrorF(c.Request.Context(), "生成二维码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, util.Err(QRCodeGenerateError))
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, opts.contentType(), data)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package qrcode
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/storage"
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// renderOptions 二维码渲染参数
+type renderOptions struct {
+	Format string
+	Size   int
+	Margin int
+}
+
+// contentType 返回渲染格式对应的 Content-Type
+func (o renderOptions) contentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// getOrRender 优先从对象存储读取缓存的二维码，不存在时渲染并写入缓存
+func getOrRender(ctx context.Context, content string, opts renderOptions) ([]byte, error) {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", content, opts.Size, opts.Margin)))
+	key := storage.BuildKey(fmt.Sprintf(storageKeyFormat, hex.EncodeToString(sum[:]), opts.Format))
+
+	if storage.IsEnabled() {
+		if obj, err := storage.GetObjectViaCache(ctx, key); err == nil {
+			if data, errRead := readObject(obj); errRead == nil {
+				return data, nil
+			}
+		}
+	}
+
+	data, err := render(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if storage.IsEnabled() {
+		if err := storage.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)), opts.contentType()); err != nil {
+			logger.ErrorF(ctx, "缓存二维码失败: key=%s, error=%v", key, err)
+		}
+	}
+
+	return data, nil
+}
+
+// readObject 读取存储对象内容
+func readObject(obj *storage.ObjectInfo) ([]byte, error) {
+	if obj.CachePath != "" {
+		return os.ReadFile(obj.CachePath)
+	}
+	defer obj.Body.Close()
+	return io.ReadAll(obj.Body)
+}
+
+// render 按参数渲染二维码
+func render(content string, opts renderOptions) ([]byte, error) {
+	qr, err := goqrcode.New(content, goqrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	qr.DisableBorder = true
+	bitmap := qr.Bitmap()
+
+	if opts.Format == FormatSVG {
+		return renderSVG(bitmap, opts), nil
+	}
+	return renderPNG(bitmap, opts)
+}
+
+// moduleLayout 计算单个模块像素大小与起始偏移，保证二维码在画布中居中
+func moduleLayout(modules int, opts renderOptions) (scale int, offset int) {
+	total := modules + 2*opts.Margin
+	scale = max(opts.Size/total, 1)
+	offset = (opts.Size - scale*modules) / 2
+	return scale, max(offset, 0)
+}
+
+func renderPNG(bitmap [][]bool, opts renderOptions) ([]byte, error) {
+	scale, offset := moduleLayout(len(bitmap), opts)
+	size := max(opts.Size, scale*len(bitmap))
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bitmap [][]bool, opts renderOptions) []byte {
+	modules := len(bitmap)
+	viewBox := modules + 2*opts.Margin
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, viewBox, viewBox)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, viewBox, viewBox)
+	fmt.Fprintf(&buf, `<path fill="#000" d="%s"/>`, path.String())
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/model"
+)
+
+const (
+	rateLimitKeyFormat = "rate_limit:%s:%s"
+)
+
+// KeyType 限流维度
+type KeyType string
+
+const (
+	KeyByClientID KeyType = "client" // 按商户 ClientID 限流
+	KeyByUser     KeyType = "user"   // 按登录用户限流
+	KeyByIP       KeyType = "ip"     // 按客户端 IP 限流
+)
+
+// Policy 路由限流策略，实际限额优先读取 system_configs，读取失败时使用默认值
+type Policy struct {
+	Name          string        // 策略名称，用于区分 Redis 计数 key
+	ConfigKey     string        // 对应的系统配置 key，值格式为 "次数/秒数"
+	KeyType       KeyType       // 限流维度
+	DefaultRate   int           // 默认周期内允许的请求数
+	DefaultPeriod time.Duration // 默认周期
+	EPayResponse  bool          // 是否使用易支付风格的响应体
+}
+
+var (
+	// PaySubmitPolicy 商户创建订单
+	PaySubmitPolicy = Policy{
+		Name:          "pay_submit",
+		ConfigKey:     model.ConfigKeyRateLimitPaySubmit,
+		KeyType:       KeyByClientID,
+		DefaultRate:   120,
+		DefaultPeriod: time.Minute,
+		EPayResponse:  true,
+	}
+	// PaySubmitIPPolicy 商户创建订单，认证前按 IP 限流
+	PaySubmitIPPolicy = Policy{
+		Name:          "pay_submit_ip",
+		ConfigKey:     model.ConfigKeyRateLimitPaySubmitIP,
+		KeyType:       KeyByIP,
+		DefaultRate:   60,
+		DefaultPeriod: time.Minute,
+		EPayResponse:  true,
+	}
+	// MerchantAPIPolicy 商户查询订单与退款
+	MerchantAPIPolicy = Policy{
+		Name:          "merchant_api",
+		ConfigKey:     model.ConfigKeyRateLimitMerchantAPI,
+		KeyType:       KeyByClientID,
+		DefaultRate:   60,
+		DefaultPeriod: time.Minute,
+		EPayResponse:  true,
+	}
+	// MerchantAPIIPPolicy 商户查询订单、退款与订单二维码，认证前按 IP 限流
+	MerchantAPIIPPolicy = Policy{
+		Name:          "merchant_api_ip",
+		ConfigKey:     model.ConfigKeyRateLimitMerchantAPIIP,
+		KeyType:       KeyByIP,
+		DefaultRate:   30,
+		DefaultPeriod: time.Minute,
+		EPayResponse:  true,
+	}
+	// DistributePolicy 商户分发
+	DistributePolicy = Policy{
+		Name:          "distribute",
+		ConfigKey:     model.ConfigKeyRateLimitDistribute,
+		KeyType:       KeyByClientID,
+		DefaultRate:   60,
+		DefaultPeriod: time.Minute,
+	}
+	// DistributeIPPolicy 商户分发与红包接口，认证前按 IP 限流
+	DistributeIPPolicy = Policy{
+		Name:          "distribute_ip",
+		ConfigKey:     model.ConfigKeyRateLimitDistributeIP,
+		KeyType:       KeyByIP,
+		DefaultRate:   30,
+		DefaultPeriod: time.Minute,
+	}
+	// TransferPolicy 用户转账
+	TransferPolicy = Policy{
+		Name:          "transfer",
+		ConfigKey:     model.ConfigKeyRateLimitTransfer,
+		KeyType:       KeyByUser,
+		DefaultRate:   10,
+		DefaultPeriod: time.Minute,
+	}
+	// RedEnvelopeClaimPolicy 领取红包
+	RedEnvelopeClaimPolicy = Policy{
+		Name:          "red_envelope_claim",
+		ConfigKey:     model.ConfigKeyRateLimitRedEnvelopeClaim,
+		KeyType:       KeyByUser,
+		DefaultRate:   30,
+		DefaultPeriod: time.Minute,
+	}
+)
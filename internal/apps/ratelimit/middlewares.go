@@ -0,0 +1,148 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/util"
+)
+
+var limiter *redis_rate.Limiter
+
+func init() {
+	limiter = redis_rate.NewLimiter(db.Redis)
+}
+
+// RateLimit 按策略限流，并返回 RateLimit-* 响应头
+func RateLimit(policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		limit, enabled := loadLimit(ctx, policy)
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		key := db.PrefixedKey(fmt.Sprintf(rateLimitKeyFormat, policy.Name, resolveKey(c, policy.KeyType)))
+		res, err := limiter.Allow(ctx, key, limit)
+		if err != nil {
+			// Redis 异常时放行，避免限流组件影响支付主流程
+			logger.ErrorF(ctx, "[RateLimit] 策略[%s]限流检查失败: %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, limit, res)
+
+		if res.Allowed == 0 {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			if policy.EPayResponse {
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"code": -1, "msg": TooManyRequests})
+			} else {
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, util.Err(TooManyRequests))
+			}
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// loadLimit 读取策略限额，配置值为 "次数/秒数"，次数为 0 表示不限流
+func loadLimit(ctx context.Context, policy Policy) (redis_rate.Limit, bool) {
+	rate, period := policy.DefaultRate, policy.DefaultPeriod
+
+	var sc model.SystemConfig
+	if err := sc.GetByKey(ctx, policy.ConfigKey); err == nil {
+		if r, p, errParse := parseLimitValue(sc.Value); errParse == nil {
+			rate, period = r, p
+		} else {
+			logger.ErrorF(ctx, "[RateLimit] 配置 %s 的值 '%s' 无效，使用默认限额: %v", policy.ConfigKey, sc.Value, errParse)
+		}
+	}
+
+	if rate <= 0 {
+		return redis_rate.Limit{}, false
+	}
+
+	return redis_rate.Limit{Rate: rate, Burst: rate, Period: period}, true
+}
+
+// parseLimitValue 解析 "次数/秒数" 格式的限额配置
+func parseLimitValue(value string) (int, time.Duration, error) {
+	rateStr, periodStr, found := strings.Cut(strings.TrimSpace(value), "/")
+	rate, err := strconv.Atoi(strings.TrimSpace(rateStr))
+	if err != nil || rate < 0 {
+		return 0, 0, fmt.Errorf("次数格式错误")
+	}
+	if !found {
+		return rate, time.Minute, nil
+	}
+
+	seconds, err := strconv.Atoi(strings.TrimSpace(periodStr))
+	if err != nil || seconds <= 0 {
+		return 0, 0, fmt.Errorf("周期格式错误")
+	}
+	return rate, time.Duration(seconds) * time.Second, nil
+}
+
+// resolveKey 根据限流维度获取限流主体，无法识别时回退到客户端 IP
+// 按商户限流仅使用认证通过后写入上下文的 API Key，不信任请求中未经认证的 pid，避免他人耗尽商户配额
+func resolveKey(c *gin.Context, keyType KeyType) string {
+	switch keyType {
+	case KeyByClientID:
+		if apiKey, ok := util.GetFromContext[*model.MerchantAPIKey](c, payment.APIKeyObjKey); ok && apiKey != nil {
+			return "client:" + apiKey.ClientID
+		}
+	case KeyByUser:
+		if user, ok := util.GetFromContext[*model.User](c, oauth.UserObjKey); ok && user != nil {
+			return "user:" + strconv.FormatUint(user.ID, 10)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// setRateLimitHeaders 设置 RateLimit-* 响应头
+func setRateLimitHeaders(c *gin.Context, limit redis_rate.Limit, res *redis_rate.Result) {
+	periodSeconds := ceilSeconds(limit.Period)
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Rate, periodSeconds))
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Rate))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
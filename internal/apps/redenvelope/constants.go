@@ -0,0 +1,56 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redenvelope
+
+import "time"
+
+const (
+	// passphraseAttemptKeyFormat 口令错误次数计数 key：redenvelope:passphrase_attempts:<红包ID>:<用户ID>
+	passphraseAttemptKeyFormat = "redenvelope:passphrase_attempts:%d:%d"
+	// passphraseMaxAttempts 锁定窗口内单个用户对同一红包允许的口令错误次数
+	passphraseMaxAttempts = 5
+	// passphraseLockDuration 口令错误计数窗口，达到上限后需等待窗口结束
+	passphraseLockDuration = 10 * time.Minute
+	// maxOpenDelay 定时红包开启时间距创建时间的最大间隔
+	maxOpenDelay = 30 * 24 * time.Hour
+	// redEnvelopeValidDuration 红包有效期，定时红包从开启时间起算
+	redEnvelopeValidDuration = 24 * time.Hour
+	// claimAmountsKeyFormat 预拆分的待领取金额列表 key，使用哈希标签保证与已领取哈希位于同一槽位
+	claimAmountsKeyFormat = "redenvelope:{%d}:amounts"
+	// claimUsersKeyFormat 已领取用户哈希 key：用户ID -> 序号:领取金额
+	claimUsersKeyFormat = "redenvelope:{%d}:claimed"
+	// claimPoolTTLBuffer 领取池在红包过期后的保留时间，保证过期退款前仍可补写未落库的领取记录
+	claimPoolTTLBuffer = 48 * time.Hour
+	// persistClaimTaskIDFormat 领取记录补写任务 ID：redenvelope:persist_claim:<红包ID>:<用户ID>
+	persistClaimTaskIDFormat = "redenvelope:persist_claim:%d:%d"
+	// merchantNotifyTaskIDFormat 商户红包回调任务 ID：redenvelope:merchant_notify:<红包ID>:<事件>
+	merchantNotifyTaskIDFormat = "redenvelope:merchant_notify:%d:%s"
+)
+
+// 商户红包回调事件
+const (
+	EventRedEnvelopeFinished = "redenvelope.finished"
+	EventRedEnvelopeRefunded = "redenvelope.refunded"
+)
+
+// 领取脚本返回码
+const (
+	claimResultOK             = 0
+	claimResultAlreadyClaimed = 1
+	claimResultFinished       = 2
+	claimResultNotLoaded      = -1
+)
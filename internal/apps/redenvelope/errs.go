@@ -30,4 +30,16 @@ const (
 	InvalidRedEnvelopeID      = "红包ID格式错误"
 	InvalidCoverImage         = "无效的封面图片"
 	InvalidHeterotypicImage   = "无效的装饰图片"
+	PaidMerchantNotFound      = "指定的商户不存在"
+	TooManyUsernames          = "指定领取用户不能超过100人"
+	InvalidOpensAt            = "开启时间须晚于当前时间且不超过30天"
+	RedEnvelopeNotOpened      = "红包尚未开启，请在开启时间后领取"
+	PassphraseRequired        = "请输入红包口令"
+	PassphraseIncorrect       = "红包口令错误"
+	PassphraseTooManyAttempts = "口令错误次数过多，请稍后再试"
+	ClaimPersistConflict      = "红包剩余金额与领取记录不一致"
+	VerificationUnsupported   = "该红包不支持金额校验"
+	SeedNotRevealed           = "红包领完或过期后才可校验"
+	MerchantOrderNoExists     = "商户订单号已存在"
+	TestModeUnsupported       = "测试模式不支持创建红包"
 )
@@ -17,23 +17,26 @@ limitations under the License.
 package redenvelope
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/linux-do/credit/internal/apps/oauth"
+	"github.com/linux-do/credit/internal/apps/payment"
 	"github.com/linux-do/credit/internal/common"
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/linux-do/credit/internal/logger"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/service"
 	"github.com/linux-do/credit/internal/util"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // CreateRequest 创建红包请求
@@ -45,6 +48,17 @@ type CreateRequest struct {
 	PayKey              string                `json:"pay_key" binding:"required,max=10"`
 	CoverUploadID       *uint64               `json:"cover_upload_id,string" binding:"omitempty"`
 	HeterotypicUploadID *uint64               `json:"heterotypic_upload_id,string" binding:"omitempty"`
+	Passphrase          string                `json:"passphrase" binding:"omitempty,max=24"`
+	Eligibility         *EligibilityRequest   `json:"eligibility" binding:"omitempty"`
+	OpensAt             *time.Time            `json:"opens_at" binding:"omitempty"`
+}
+
+// EligibilityRequest 红包领取资格，各项条件需同时满足
+type EligibilityRequest struct {
+	Usernames            []string         `json:"usernames" binding:"omitempty,dive,min=1,max=64"`
+	MinTrustLevel        model.TrustLevel `json:"min_trust_level" binding:"omitempty,max=4"`
+	MinAccountAgeDays    int              `json:"min_account_age_days" binding:"omitempty,min=0,max=3650"`
+	PaidMerchantUsername string           `json:"paid_merchant_username" binding:"omitempty,max=64"`
 }
 
 // CreateResponse 创建红包响应
@@ -54,20 +68,97 @@ type CreateResponse struct {
 
 // ClaimRequest 领取红包请求
 type ClaimRequest struct {
-	ID uint64 `json:"id,string" binding:"required"`
+	ID         uint64 `json:"id,string" binding:"required"`
+	Passphrase string `json:"passphrase" binding:"omitempty,max=24"`
 }
 
 // ClaimResponse 领取红包响应
 type ClaimResponse struct {
 	Amount      decimal.Decimal   `json:"amount"`
+	Seq         int               `json:"seq"`
 	RedEnvelope model.RedEnvelope `json:"red_envelope"`
 }
 
 // DetailResponse 红包详情响应
 type DetailResponse struct {
-	RedEnvelope model.RedEnvelope        `json:"red_envelope"`
-	Claims      []model.RedEnvelopeClaim `json:"claims"`
-	UserClaimed *model.RedEnvelopeClaim  `json:"user_claimed,omitempty"`
+	RedEnvelope      model.RedEnvelope        `json:"red_envelope"`
+	Claims           []model.RedEnvelopeClaim `json:"claims"`
+	UserClaimed      *model.RedEnvelopeClaim  `json:"user_claimed,omitempty"`
+	OpensInSeconds   int64                    `json:"opens_in_seconds,omitempty"`
+	ServerSeed       string                   `json:"server_seed,omitempty"`
+	Eligible         bool                     `json:"eligible"`
+	IneligibleReason string                   `json:"ineligible_reason,omitempty"`
+}
+
+// VerifyAmount 单个红包的校验结果
+type VerifyAmount struct {
+	Seq           int              `json:"seq"`
+	Amount        decimal.Decimal  `json:"amount"`
+	UserID        uint64           `json:"user_id,string,omitempty"`
+	Username      string           `json:"username,omitempty"`
+	ClaimedAmount *decimal.Decimal `json:"claimed_amount,omitempty"`
+	Match         bool             `json:"match"`
+}
+
+// VerifyResponse 红包金额校验响应
+type VerifyResponse struct {
+	ServerSeed     string         `json:"server_seed"`
+	ServerSeedHash string         `json:"server_seed_hash"`
+	SeedMatch      bool           `json:"seed_match"`
+	Verified       bool           `json:"verified"`
+	Amounts        []VerifyAmount `json:"amounts"`
+}
+
+// merchantSource 商户通过 API 创建红包时的来源信息
+type merchantSource struct {
+	ClientID        string
+	MerchantOrderNo *string
+	NotifyURL       string
+}
+
+// MerchantCreateRequest 商户创建红包请求
+type MerchantCreateRequest struct {
+	Type            model.RedEnvelopeType `json:"type" binding:"required,oneof=fixed random"`
+	TotalAmount     decimal.Decimal       `json:"total_amount" binding:"required"`
+	TotalCount      int                   `json:"total_count" binding:"required,min=1"`
+	Greeting        string                `json:"greeting" binding:"max=100"`
+	Passphrase      string                `json:"passphrase" binding:"omitempty,max=24"`
+	Eligibility     *EligibilityRequest   `json:"eligibility" binding:"omitempty"`
+	OpensAt         *time.Time            `json:"opens_at" binding:"omitempty"`
+	MerchantOrderNo *string               `json:"out_trade_no" binding:"omitempty,min=1,max=64"`
+	NotifyURL       string                `json:"notify_url" binding:"omitempty,url,max=100"`
+}
+
+// MerchantCreateResponse 商户创建红包响应
+type MerchantCreateResponse struct {
+	ID             uint64     `json:"id,string"`
+	OutTradeNo     *string    `json:"out_trade_no"`
+	Status         string     `json:"status"`
+	ServerSeedHash string     `json:"server_seed_hash"`
+	OpensAt        *time.Time `json:"opens_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+}
+
+// MerchantDetailResponse 商户查询红包响应
+type MerchantDetailResponse struct {
+	RedEnvelope   model.RedEnvelope `json:"red_envelope"`
+	ClaimedCount  int               `json:"claimed_count"`
+	ClaimedAmount decimal.Decimal   `json:"claimed_amount"`
+	ServerSeed    string            `json:"server_seed,omitempty"`
+}
+
+// MerchantClaimsRequest 商户查询领取记录请求
+type MerchantClaimsRequest struct {
+	Page     int `form:"page" binding:"min=1"`
+	PageSize int `form:"page_size" binding:"min=1,max=100"`
+}
+
+// MerchantClaimsResponse 商户查询领取记录响应
+type MerchantClaimsResponse struct {
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+	Claims   []model.RedEnvelopeClaim `json:"claims"`
 }
 
 // ListRequest 红包列表请求
@@ -99,80 +190,89 @@ func Create(c *gin.Context) {
 		return
 	}
 
-	if err := util.ValidateAmount(req.TotalAmount); err != nil {
-		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	if !currentUser.VerifyPayKey(req.PayKey) {
+		c.JSON(http.StatusBadRequest, util.Err(common.PayKeyIncorrect))
+		return
+	}
+
+	redEnvelope, err := createRedEnvelope(c.Request.Context(), currentUser, &req, nil)
+	if err != nil {
+		if isCreateRejected(err) {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
 		return
 	}
 
+	c.JSON(http.StatusOK, util.OK(CreateResponse{
+		ID: redEnvelope.ID,
+	}))
+}
+
+// createRedEnvelope 校验红包限额配置，从创建者余额扣除红包金额与手续费后创建红包
+// merchant 不为空时为商户通过 API 创建，记录来源 ClientID 与回调地址
+func createRedEnvelope(ctx context.Context, creator *model.User, req *CreateRequest, merchant *merchantSource) (model.RedEnvelope, error) {
+	var redEnvelope model.RedEnvelope
+
+	if err := util.ValidateAmount(req.TotalAmount); err != nil {
+		return redEnvelope, err
+	}
+
 	// 检查红包最低金额限制（1 LDC）
 	if req.TotalAmount.LessThan(decimal.NewFromInt(1)) {
-		c.JSON(http.StatusBadRequest, util.Err(common.RedEnvelopeMinAmountRequired))
-		return
+		return redEnvelope, errors.New(common.RedEnvelopeMinAmountRequired)
 	}
 
 	// 检查单个红包最大金额限制
-	maxAmount, err := model.GetDecimalByKey(c.Request.Context(), model.ConfigKeyRedEnvelopeMaxAmount, 2)
+	maxAmount, err := model.GetDecimalByKey(ctx, model.ConfigKeyRedEnvelopeMaxAmount, 2)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
+		return redEnvelope, err
 	}
 	if req.TotalAmount.GreaterThan(maxAmount) {
-		c.JSON(http.StatusBadRequest, util.Err(common.RedEnvelopeAmountExceeded))
-		return
+		return redEnvelope, errors.New(common.RedEnvelopeAmountExceeded)
 	}
 
 	// 检查红包最大领取人数限制
-	maxRecipients, err := model.GetIntByKey(c.Request.Context(), model.ConfigKeyRedEnvelopeMaxRecipients)
+	maxRecipients, err := model.GetIntByKey(ctx, model.ConfigKeyRedEnvelopeMaxRecipients)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
+		return redEnvelope, err
 	}
 	if req.TotalCount > maxRecipients {
-		c.JSON(http.StatusBadRequest, util.Err(common.RedEnvelopeRecipientsExceeded))
-		return
+		return redEnvelope, errors.New(common.RedEnvelopeRecipientsExceeded)
 	}
 
 	// 检查每个红包平均金额不能小于0.01（避免前面领取者获得0 LDC）
 	perAmount := req.TotalAmount.Div(decimal.NewFromInt(int64(req.TotalCount)))
 	if perAmount.LessThan(decimal.NewFromFloat(0.01)) {
-		c.JSON(http.StatusBadRequest, util.Err(AmountTooSmall))
-		return
+		return redEnvelope, errors.New(AmountTooSmall)
 	}
 
-	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
-
 	// 检查每日红包发送数量限制
-	dailyLimit, err := model.GetIntByKey(c.Request.Context(), model.ConfigKeyRedEnvelopeDailyLimit)
+	dailyLimit, err := model.GetIntByKey(ctx, model.ConfigKeyRedEnvelopeDailyLimit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
+		return redEnvelope, err
 	}
 
 	// 查询今日已发送的红包数量
 	var todayCount int64
 	today := time.Now().Truncate(24 * time.Hour)
-	if err := db.DB(c.Request.Context()).Model(&model.RedEnvelope{}).
-		Where("creator_id = ? AND created_at >= ?", currentUser.ID, today).
+	if err := db.DB(ctx).Model(&model.RedEnvelope{}).
+		Where("creator_id = ? AND created_at >= ?", creator.ID, today).
 		Count(&todayCount).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
+		return redEnvelope, err
 	}
 
 	if todayCount >= int64(dailyLimit) {
-		c.JSON(http.StatusBadRequest, util.Err(common.RedEnvelopeDailyLimitExceeded))
-		return
-	}
-
-	if !currentUser.VerifyPayKey(req.PayKey) {
-		c.JSON(http.StatusBadRequest, util.Err(common.PayKeyIncorrect))
-		return
+		return redEnvelope, errors.New(common.RedEnvelopeDailyLimitExceeded)
 	}
 
 	// 获取红包手续费率并计算手续费
-	feeRate, err := model.GetDecimalByKey(c.Request.Context(), model.ConfigKeyRedEnvelopeFeeRate, 2)
+	feeRate, err := model.GetDecimalByKey(ctx, model.ConfigKeyRedEnvelopeFeeRate, 2)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
-		return
+		return redEnvelope, err
 	}
 
 	// 计算手续费（红包金额 * 费率）
@@ -182,20 +282,48 @@ func Create(c *gin.Context) {
 	totalDeduction := req.TotalAmount.Add(feeAmount)
 
 	// 提前检查余额，避免不必要的事务
-	if currentUser.AvailableBalance.LessThan(totalDeduction) {
-		c.JSON(http.StatusBadRequest, util.Err(common.InsufficientBalance))
-		return
+	if creator.AvailableBalance.LessThan(totalDeduction) {
+		return redEnvelope, errors.New(common.InsufficientBalance)
 	}
 
-	var redEnvelope model.RedEnvelope
+	// 定时红包：创建时扣款，开启前不可领取，有效期从开启时间起算
+	now := time.Now()
+	status := model.RedEnvelopeStatusActive
+	expiresAt := now.Add(redEnvelopeValidDuration)
+	if req.OpensAt != nil {
+		if !req.OpensAt.After(now) || req.OpensAt.After(now.Add(maxOpenDelay)) {
+			return redEnvelope, errors.New(InvalidOpensAt)
+		}
+		status = model.RedEnvelopeStatusScheduled
+		expiresAt = req.OpensAt.Add(redEnvelopeValidDuration)
+	}
+
+	eligibility, err := buildEligibility(db.DB(ctx), req.Eligibility)
+	if err != nil {
+		return redEnvelope, err
+	}
 
-	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+	// 口令红包仅保存口令哈希
+	var passphraseHash string
+	if strings.TrimSpace(req.Passphrase) != "" {
+		if passphraseHash, err = hashPassphrase(req.Passphrase); err != nil {
+			return redEnvelope, err
+		}
+	}
+
+	// 服务端种子决定全部红包金额，创建时仅公开哈希，领完或过期后公开种子
+	serverSeed, serverSeedHash, err := generateServerSeed()
+	if err != nil {
+		return redEnvelope, err
+	}
+
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
 		var coverUploadID *uint64
 		var heterotypicUploadID *uint64
 
 		if req.CoverUploadID != nil {
 			var coverUpload model.Upload
-			if err := tx.Where("id = ? AND status IN (?, ?) AND user_id = ? AND type = ?", *req.CoverUploadID, model.UploadStatusPending, model.UploadStatusUsed, currentUser.ID, model.UploadTypeCover).
+			if err := tx.Where("id = ? AND status IN (?, ?) AND user_id = ? AND type = ?", *req.CoverUploadID, model.UploadStatusPending, model.UploadStatusUsed, creator.ID, model.UploadTypeCover).
 				First(&coverUpload).Error; err != nil {
 				return errors.New(InvalidCoverImage)
 			}
@@ -212,7 +340,7 @@ func Create(c *gin.Context) {
 
 		if req.HeterotypicUploadID != nil {
 			var heterotypicUpload model.Upload
-			if err := tx.Where("id = ? AND status IN (?, ?) AND user_id = ? AND type = ?", *req.HeterotypicUploadID, model.UploadStatusPending, model.UploadStatusUsed, currentUser.ID, model.UploadTypeHeterotypic).
+			if err := tx.Where("id = ? AND status IN (?, ?) AND user_id = ? AND type = ?", *req.HeterotypicUploadID, model.UploadStatusPending, model.UploadStatusUsed, creator.ID, model.UploadTypeHeterotypic).
 				First(&heterotypicUpload).Error; err != nil {
 				return errors.New(InvalidHeterotypicImage)
 			}
@@ -229,7 +357,7 @@ func Create(c *gin.Context) {
 
 		// 扣减发送者余额并更新total_payment
 		if err := service.UpdateBalance(tx, service.BalanceUpdateOptions{
-			UserID:       currentUser.ID,
+			UserID:       creator.ID,
 			Amount:       totalDeduction,
 			Operation:    service.BalanceDeduct,
 			TotalField:   "total_payment",
@@ -241,17 +369,28 @@ func Create(c *gin.Context) {
 		// 创建红包
 		redEnvelope = model.RedEnvelope{
 			ID:                  idgen.NextUint64ID(),
-			CreatorID:           currentUser.ID,
+			CreatorID:           creator.ID,
 			Type:                req.Type,
 			TotalAmount:         req.TotalAmount,
 			RemainingAmount:     req.TotalAmount,
 			TotalCount:          req.TotalCount,
 			RemainingCount:      req.TotalCount,
 			Greeting:            req.Greeting,
-			Status:              model.RedEnvelopeStatusActive,
+			HasPassphrase:       passphraseHash != "",
+			PassphraseHash:      passphraseHash,
+			Eligibility:         eligibility,
+			ServerSeed:          serverSeed,
+			ServerSeedHash:      serverSeedHash,
+			Status:              status,
 			CoverUploadID:       coverUploadID,
 			HeterotypicUploadID: heterotypicUploadID,
-			ExpiresAt:           time.Now().Add(24 * time.Hour),
+			OpensAt:             req.OpensAt,
+			ExpiresAt:           expiresAt,
+		}
+		if merchant != nil {
+			redEnvelope.ClientID = merchant.ClientID
+			redEnvelope.MerchantOrderNo = merchant.MerchantOrderNo
+			redEnvelope.NotifyURL = merchant.NotifyURL
 		}
 
 		if err := tx.Create(&redEnvelope).Error; err != nil {
@@ -269,7 +408,7 @@ func Create(c *gin.Context) {
 
 		order := model.Order{
 			OrderName:   "红包支出",
-			PayerUserID: currentUser.ID,
+			PayerUserID: creator.ID,
 			PayeeUserID: 0,
 			Amount:      totalDeduction,
 			Status:      model.OrderStatusSuccess,
@@ -278,22 +417,41 @@ func Create(c *gin.Context) {
 			TradeTime:   time.Now(),
 			ExpiresAt:   time.Now().Add(24 * time.Hour),
 		}
+		if merchant != nil {
+			order.ClientID = merchant.ClientID
+			order.MerchantOrderNo = merchant.MerchantOrderNo
+		}
 
-		return tx.Create(&order).Error
-	}); err != nil {
-		if err.Error() == common.InsufficientBalance {
-			c.JSON(http.StatusBadRequest, util.Err(common.InsufficientBalance))
-		} else if err.Error() == InvalidCoverImage || err.Error() == InvalidHeterotypicImage {
-			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
-		} else {
-			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		if err := tx.Create(&order).Error; err != nil {
+			if merchant != nil && strings.Contains(err.Error(), "SQLSTATE 23505") {
+				return errors.New(MerchantOrderNoExists)
+			}
+			return err
 		}
-		return
+		return nil
+	}); err != nil {
+		return redEnvelope, err
 	}
 
-	c.JSON(http.StatusOK, util.OK(CreateResponse{
-		ID: redEnvelope.ID,
-	}))
+	// 预拆分红包金额写入领取池，失败时在首次领取时按需加载
+	if err := loadClaimPool(ctx, redEnvelope.ID, false); err != nil {
+		logger.ErrorF(ctx, "红包ID:%d 预拆分领取池失败: %v", redEnvelope.ID, err)
+	}
+
+	return redEnvelope, nil
+}
+
+// isCreateRejected 判断创建红包的错误是否由请求参数或余额、限额不满足导致
+func isCreateRejected(err error) bool {
+	switch err.Error() {
+	case common.AmountMustBeGreaterThanZero, common.AmountDecimalPlacesExceeded,
+		common.RedEnvelopeMinAmountRequired, common.RedEnvelopeAmountExceeded,
+		common.RedEnvelopeRecipientsExceeded, common.RedEnvelopeDailyLimitExceeded,
+		common.InsufficientBalance, AmountTooSmall, InvalidOpensAt, PaidMerchantNotFound, TooManyUsernames,
+		InvalidCoverImage, InvalidHeterotypicImage, MerchantOrderNoExists:
+		return true
+	}
+	return false
 }
 
 // Claim 领取红包
@@ -312,117 +470,80 @@ func Claim(c *gin.Context) {
 
 	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
 
-	var claimedAmount decimal.Decimal
+	// 领取前先校验红包状态、领取资格与口令，不满足条件的请求不进入领取池
 	var redEnvelope model.RedEnvelope
-
-	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
-		// 使用 FOR UPDATE 锁定红包记录，防止并发领取
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "NOWAIT"}).
-			Where("id = ?", req.ID).First(&redEnvelope).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New(RedEnvelopeNotFound)
-			}
-			// 捕获锁等待超时错误，返回友好提示
-			return errors.New(RedEnvelopeTooPopular)
-		}
-
-		// 检查红包状态
-		if redEnvelope.Status == model.RedEnvelopeStatusExpired || redEnvelope.ExpiresAt.Before(time.Now()) {
-			return errors.New(RedEnvelopeExpired)
-		}
-
-		if redEnvelope.Status == model.RedEnvelopeStatusFinished || redEnvelope.RemainingCount <= 0 {
-			return errors.New(RedEnvelopeFinished)
-		}
-
-		// 检查是否已领取
-		var existingClaim model.RedEnvelopeClaim
-		if err := tx.Where("red_envelope_id = ? AND user_id = ?", redEnvelope.ID, currentUser.ID).
-			First(&existingClaim).Error; err == nil {
-			return errors.New(RedEnvelopeAlreadyClaimed)
+	if err := db.DB(c.Request.Context()).Where("id = ?", req.ID).First(&redEnvelope).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(RedEnvelopeNotFound))
+			return
 		}
-
-		// 计算领取金额
-		if redEnvelope.Type == model.RedEnvelopeTypeFixed {
-			// 固定金额：如果是最后一个，给全部剩余金额（避免舍入误差）
-			if redEnvelope.RemainingCount == 1 {
-				claimedAmount = redEnvelope.RemainingAmount
-			} else {
-				claimedAmount = redEnvelope.TotalAmount.Div(decimal.NewFromInt(int64(redEnvelope.TotalCount))).Round(2)
-			}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	if redEnvelope.Status == model.RedEnvelopeStatusExpired || redEnvelope.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, util.Err(RedEnvelopeExpired))
+		return
+	}
+	if redEnvelope.Status == model.RedEnvelopeStatusFinished {
+		c.JSON(http.StatusBadRequest, util.Err(RedEnvelopeFinished))
+		return
+	}
+	if redEnvelope.OpensAt != nil && time.Now().Before(*redEnvelope.OpensAt) {
+		c.JSON(http.StatusBadRequest, util.Err(RedEnvelopeNotOpened))
+		return
+	}
+	if err := redEnvelope.Eligibility.Check(db.DB(c.Request.Context()), currentUser); err != nil {
+		if isIneligible(err) {
+			c.JSON(http.StatusForbidden, util.Err(err.Error()))
 		} else {
-			// 拼手气红包：使用二倍均值算法
-			claimedAmount = calculateRandomAmount(redEnvelope.RemainingAmount, redEnvelope.RemainingCount)
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		}
+		return
+	}
 
-		// 创建领取记录
-		claim := model.RedEnvelopeClaim{
-			ID:            idgen.NextUint64ID(),
-			RedEnvelopeID: redEnvelope.ID,
-			UserID:        currentUser.ID,
-			Amount:        claimedAmount,
-		}
-		if err := tx.Create(&claim).Error; err != nil {
-			return err
+	// 口令红包错误次数过多时在计数窗口内拒绝继续尝试
+	if redEnvelope.HasPassphrase {
+		if strings.TrimSpace(req.Passphrase) == "" {
+			c.JSON(http.StatusBadRequest, util.Err(PassphraseRequired))
+			return
 		}
-
-		// 更新红包状态
-		newRemainingCount := redEnvelope.RemainingCount - 1
-		newRemainingAmount := redEnvelope.RemainingAmount.Sub(claimedAmount)
-		newStatus := redEnvelope.Status
-		if newRemainingCount <= 0 {
-			newStatus = model.RedEnvelopeStatusFinished
+		if !acquirePassphraseAttempt(c.Request.Context(), req.ID, currentUser.ID) {
+			c.JSON(http.StatusTooManyRequests, util.Err(PassphraseTooManyAttempts))
+			return
 		}
-
-		if err := tx.Model(&model.RedEnvelope{}).Where("id = ?", redEnvelope.ID).
-			Updates(map[string]interface{}{
-				"remaining_count":  newRemainingCount,
-				"remaining_amount": newRemainingAmount,
-				"status":           newStatus,
-			}).Error; err != nil {
-			return err
+		if !verifyPassphrase(redEnvelope.PassphraseHash, req.Passphrase) {
+			c.JSON(http.StatusBadRequest, util.Err(PassphraseIncorrect))
+			return
 		}
+		releasePassphraseAttempt(c.Request.Context(), req.ID, currentUser.ID)
+	}
 
-		// 更新红包对象用于返回
-		redEnvelope.RemainingCount = newRemainingCount
-		redEnvelope.RemainingAmount = newRemainingAmount
-		redEnvelope.Status = newStatus
-
-		// 增加领取者余额并更新total_receive
-		if err := service.UpdateBalance(tx, service.BalanceUpdateOptions{
-			UserID:     currentUser.ID,
-			Amount:     claimedAmount,
-			Operation:  service.BalanceAdd,
-			TotalField: "total_receive",
-		}); err != nil {
-			return err
+	// 从 Redis 领取池原子弹出预拆分金额，领取去重由脚本完成，不再锁定红包行
+	slot, err := popClaimSlot(c.Request.Context(), redEnvelope.ID, currentUser.ID)
+	if err != nil {
+		switch err.Error() {
+		case RedEnvelopeFinished, RedEnvelopeAlreadyClaimed:
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		}
+		return
+	}
 
-		// 创建订单记录（红包收入）
-		order := model.Order{
-			OrderName:   "红包收入",
-			PayerUserID: redEnvelope.CreatorID,
-			PayeeUserID: currentUser.ID,
-			Amount:      claimedAmount,
-			Status:      model.OrderStatusSuccess,
-			Type:        model.OrderTypeRedEnvelopeReceive,
-			Remark:      fmt.Sprintf("祝福语: %s", redEnvelope.Greeting),
-			TradeTime:   time.Now(),
-			ExpiresAt:   time.Now().Add(24 * time.Hour),
+	// 领取结果落库：失败时下发补写任务，领取已在领取池中生效
+	if err := persistClaim(c.Request.Context(), redEnvelope.ID, currentUser.ID, slot); err != nil {
+		if err.Error() == ClaimPersistConflict {
+			if err := revokeClaim(c.Request.Context(), redEnvelope.ID, currentUser.ID); err != nil {
+				logger.ErrorF(c.Request.Context(), "红包ID:%d 撤销用户ID:%d 领取失败: %v", redEnvelope.ID, currentUser.ID, err)
+			}
+			c.JSON(http.StatusBadRequest, util.Err(RedEnvelopeFinished))
+			return
 		}
 
-		return tx.Create(&order).Error
-	}); err != nil {
-		errMsg := err.Error()
-		switch errMsg {
-		case RedEnvelopeNotFound:
-			c.JSON(http.StatusNotFound, util.Err(errMsg))
-		case RedEnvelopeExpired, RedEnvelopeFinished, RedEnvelopeAlreadyClaimed, CannotClaimOwnRedEnvelope:
-			c.JSON(http.StatusBadRequest, util.Err(errMsg))
-		default:
-			c.JSON(http.StatusInternalServerError, util.Err(errMsg))
+		logger.ErrorF(c.Request.Context(), "红包ID:%d 用户ID:%d 领取记录落库失败，转为异步补写: %v", redEnvelope.ID, currentUser.ID, err)
+		if err := enqueuePersistClaim(redEnvelope.ID, currentUser.ID, slot); err != nil {
+			logger.ErrorF(c.Request.Context(), "红包ID:%d 用户ID:%d 下发领取补写任务失败: %v", redEnvelope.ID, currentUser.ID, err)
 		}
-		return
 	}
 
 	var redEnvelopeView model.RedEnvelope
@@ -434,9 +555,12 @@ func Claim(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		return
 	}
+	redEnvelopeView.ResolveStatus(time.Now())
+	redEnvelopeView.RedactEligibility(currentUser)
 
 	c.JSON(http.StatusOK, util.OK(ClaimResponse{
-		Amount:      claimedAmount,
+		Amount:      slot.Amount,
+		Seq:         slot.Seq,
 		RedEnvelope: redEnvelopeView,
 	}))
 }
@@ -470,6 +594,7 @@ func GetDetail(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
 		return
 	}
+	redEnvelope.ResolveStatus(time.Now())
 
 	var claims []model.RedEnvelopeClaim
 	db.DB(c.Request.Context()).
@@ -479,7 +604,19 @@ func GetDetail(c *gin.Context) {
 		Order("red_envelope_claims.claimed_at DESC").
 		Find(&claims)
 
+	var opensInSeconds int64
+	if redEnvelope.OpensAt != nil {
+		opensInSeconds = max(int64(time.Until(*redEnvelope.OpensAt).Seconds()), 0)
+	}
+
+	var serverSeed string
+	if redEnvelope.SeedRevealed() {
+		serverSeed = redEnvelope.ServerSeed
+	}
+
 	var userClaimed *model.RedEnvelopeClaim
+	eligible := true
+	var ineligibleReason string
 	if currentUser != nil {
 		for i := range claims {
 			if claims[i].UserID == currentUser.ID {
@@ -487,12 +624,102 @@ func GetDetail(c *gin.Context) {
 				break
 			}
 		}
+
+		if err := redEnvelope.Eligibility.Check(db.DB(c.Request.Context()), currentUser); err != nil {
+			if !isIneligible(err) {
+				c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+				return
+			}
+			eligible = false
+			ineligibleReason = err.Error()
+		}
 	}
+	redEnvelope.RedactEligibility(currentUser)
 
 	c.JSON(http.StatusOK, util.OK(DetailResponse{
-		RedEnvelope: redEnvelope,
-		Claims:      claims,
-		UserClaimed: userClaimed,
+		RedEnvelope:      redEnvelope,
+		Claims:           claims,
+		UserClaimed:      userClaimed,
+		OpensInSeconds:   opensInSeconds,
+		ServerSeed:       serverSeed,
+		Eligible:         eligible,
+		IneligibleReason: ineligibleReason,
+	}))
+}
+
+// Verify 校验红包金额：由公开的服务端种子重新推导全部红包金额并与领取记录比对
+// @Tags redenvelope
+// @Produce json
+// @Param id path string true "红包ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/redenvelope/{id}/verify [get]
+func Verify(c *gin.Context) {
+	redEnvelopeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(InvalidRedEnvelopeID))
+		return
+	}
+
+	var redEnvelope model.RedEnvelope
+	if err := db.DB(c.Request.Context()).Where("id = ?", redEnvelopeID).First(&redEnvelope).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(RedEnvelopeNotFound))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	if redEnvelope.ServerSeed == "" {
+		c.JSON(http.StatusBadRequest, util.Err(VerificationUnsupported))
+		return
+	}
+	if !redEnvelope.SeedRevealed() {
+		c.JSON(http.StatusBadRequest, util.Err(SeedNotRevealed))
+		return
+	}
+
+	var claims []model.RedEnvelopeClaim
+	if err := db.DB(c.Request.Context()).
+		Select("red_envelope_claims.*, users.username").
+		Joins("LEFT JOIN users ON red_envelope_claims.user_id = users.id").
+		Where("red_envelope_claims.red_envelope_id = ?", redEnvelope.ID).
+		Find(&claims).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+	claimsBySeq := make(map[int]*model.RedEnvelopeClaim, len(claims))
+	for i := range claims {
+		claimsBySeq[claims[i].Seq] = &claims[i]
+	}
+
+	seedMatch := hashServerSeed(redEnvelope.ServerSeed) == redEnvelope.ServerSeedHash
+	verified := seedMatch && len(claimsBySeq) == len(claims)
+	derived := deriveAmounts(&redEnvelope)
+	amounts := make([]VerifyAmount, 0, len(derived))
+	for i, amount := range derived {
+		item := VerifyAmount{Seq: i + 1, Amount: amount, Match: true}
+		if claim, ok := claimsBySeq[item.Seq]; ok {
+			item.UserID = claim.UserID
+			item.Username = claim.Username
+			item.ClaimedAmount = &claim.Amount
+			item.Match = claim.Amount.Equal(amount)
+		}
+		verified = verified && item.Match
+		amounts = append(amounts, item)
+	}
+	// 每条领取记录都须对应一个推导出的序号
+	for seq := range claimsBySeq {
+		if seq < 1 || seq > len(derived) {
+			verified = false
+		}
+	}
+
+	c.JSON(http.StatusOK, util.OK(VerifyResponse{
+		ServerSeed:     redEnvelope.ServerSeed,
+		ServerSeedHash: redEnvelope.ServerSeedHash,
+		SeedMatch:      seedMatch,
+		Verified:       verified,
+		Amounts:        amounts,
 	}))
 }
 
@@ -533,6 +760,11 @@ func List(c *gin.Context) {
 		Offset((req.Page - 1) * req.PageSize).
 		Limit(req.PageSize).
 		Find(&redEnvelopes)
+	now := time.Now()
+	for i := range redEnvelopes {
+		redEnvelopes[i].ResolveStatus(now)
+		redEnvelopes[i].RedactEligibility(currentUser)
+	}
 
 	c.JSON(http.StatusOK, util.OK(ListResponse{
 		Total:        total,
@@ -541,3 +773,182 @@ func List(c *gin.Context) {
 		RedEnvelopes: redEnvelopes,
 	}))
 }
+
+// MerchantCreate 商户通过 API 创建红包，从 API Key 所属商户余额扣款
+// @Tags redenvelope
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Basic Auth (base64(client_id:client_secret))"
+// @Param request body MerchantCreateRequest true "创建红包请求"
+// @Success 200 {object} util.ResponseAny
+// @Router /pay/redenvelope [post]
+func MerchantCreate(c *gin.Context) {
+	var req MerchantCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, payment.APIKeyObjKey)
+	if apiKey.TestMode {
+		c.JSON(http.StatusBadRequest, util.Err(TestModeUnsupported))
+		return
+	}
+
+	var merchantUser model.User
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND is_active = ?", apiKey.UserID, true).
+		First(&merchantUser).Error; err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(payment.MerchantInfoNotFound))
+		return
+	}
+
+	redEnvelope, err := createRedEnvelope(c.Request.Context(), &merchantUser, &CreateRequest{
+		Type:        req.Type,
+		TotalAmount: req.TotalAmount,
+		TotalCount:  req.TotalCount,
+		Greeting:    req.Greeting,
+		Passphrase:  req.Passphrase,
+		Eligibility: req.Eligibility,
+		OpensAt:     req.OpensAt,
+	}, &merchantSource{
+		ClientID:        apiKey.ClientID,
+		MerchantOrderNo: req.MerchantOrderNo,
+		NotifyURL:       req.NotifyURL,
+	})
+	if err != nil {
+		if isCreateRejected(err) {
+			c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(MerchantCreateResponse{
+		ID:             redEnvelope.ID,
+		OutTradeNo:     redEnvelope.MerchantOrderNo,
+		Status:         string(redEnvelope.Status),
+		ServerSeedHash: redEnvelope.ServerSeedHash,
+		OpensAt:        redEnvelope.OpensAt,
+		ExpiresAt:      redEnvelope.ExpiresAt,
+	}))
+}
+
+// MerchantGetDetail 商户查询通过 API 创建的红包状态
+// @Tags redenvelope
+// @Produce json
+// @Param Authorization header string true "Basic Auth (base64(client_id:client_secret))"
+// @Param id path string true "红包ID"
+// @Success 200 {object} util.ResponseAny
+// @Router /pay/redenvelope/{id} [get]
+func MerchantGetDetail(c *gin.Context) {
+	redEnvelope, ok := merchantRedEnvelope(c)
+	if !ok {
+		return
+	}
+
+	var serverSeed string
+	if redEnvelope.SeedRevealed() {
+		serverSeed = redEnvelope.ServerSeed
+	}
+
+	claimedCount := redEnvelope.TotalCount - redEnvelope.RemainingCount
+	claimedAmount := redEnvelope.TotalAmount.Sub(redEnvelope.RemainingAmount)
+	if redEnvelope.Status == model.RedEnvelopeStatusExpired {
+		// 过期退款会清零剩余，以领取记录为准
+		var summary struct {
+			Count  int
+			Amount decimal.Decimal
+		}
+		if err := db.DB(c.Request.Context()).Model(&model.RedEnvelopeClaim{}).
+			Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+			Where("red_envelope_id = ?", redEnvelope.ID).
+			Scan(&summary).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+			return
+		}
+		claimedCount, claimedAmount = summary.Count, summary.Amount
+	}
+
+	c.JSON(http.StatusOK, util.OK(MerchantDetailResponse{
+		RedEnvelope:   *redEnvelope,
+		ClaimedCount:  claimedCount,
+		ClaimedAmount: claimedAmount,
+		ServerSeed:    serverSeed,
+	}))
+}
+
+// MerchantListClaims 商户查询红包领取记录
+// @Tags redenvelope
+// @Produce json
+// @Param Authorization header string true "Basic Auth (base64(client_id:client_secret))"
+// @Param id path string true "红包ID"
+// @Param request query MerchantClaimsRequest true "分页参数"
+// @Success 200 {object} util.ResponseAny
+// @Router /pay/redenvelope/{id}/claims [get]
+func MerchantListClaims(c *gin.Context) {
+	var req MerchantClaimsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(err.Error()))
+		return
+	}
+
+	redEnvelope, ok := merchantRedEnvelope(c)
+	if !ok {
+		return
+	}
+
+	query := db.DB(c.Request.Context()).Model(&model.RedEnvelopeClaim{}).
+		Where("red_envelope_claims.red_envelope_id = ?", redEnvelope.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	var claims []model.RedEnvelopeClaim
+	if err := query.
+		Select("red_envelope_claims.*, users.username, users.avatar_url").
+		Joins("LEFT JOIN users ON red_envelope_claims.user_id = users.id").
+		Order("red_envelope_claims.claimed_at ASC").
+		Offset((req.Page - 1) * req.PageSize).
+		Limit(req.PageSize).
+		Find(&claims).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(MerchantClaimsResponse{
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+		Claims:   claims,
+	}))
+}
+
+// merchantRedEnvelope 查询当前 API Key 创建的红包，不存在时直接写入错误响应
+func merchantRedEnvelope(c *gin.Context) (*model.RedEnvelope, bool) {
+	redEnvelopeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(InvalidRedEnvelopeID))
+		return nil, false
+	}
+
+	apiKey, _ := util.GetFromContext[*model.MerchantAPIKey](c, payment.APIKeyObjKey)
+
+	var redEnvelope model.RedEnvelope
+	if err := db.DB(c.Request.Context()).
+		Where("id = ? AND client_id = ?", redEnvelopeID, apiKey.ClientID).
+		First(&redEnvelope).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, util.Err(RedEnvelopeNotFound))
+		} else {
+			c.JSON(http.StatusInternalServerError, util.Err(err.Error()))
+		}
+		return nil, false
+	}
+	redEnvelope.ResolveStatus(time.Now())
+	return &redEnvelope, true
+}
@@ -17,16 +17,25 @@ limitations under the License.
 package redenvelope
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/config"
 	"github.com/linux-do/credit/internal/db"
 	"github.com/linux-do/credit/internal/logger"
 	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/service"
+	"github.com/linux-do/credit/internal/util"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // HandleRefundExpiredRedEnvelopes 处理过期红包退款的定时任务
@@ -44,10 +53,11 @@ func refundExpiredRedEnvelopes(ctx context.Context) {
 	var totalProcessed int = 0
 
 	for {
-		// 使用游标分页查询过期红包
+		// 使用游标分页查询过期红包，包括开启后无人领取、仍处于待开启状态的定时红包
 		var expiredEnvelopes []model.RedEnvelope
 		if err := db.DB(ctx).
-			Where("id > ? AND status = ? AND expires_at < ? AND remaining_amount > 0", lastID, model.RedEnvelopeStatusActive, time.Now()).
+			Where("id > ? AND status IN ? AND expires_at < ? AND remaining_amount > 0", lastID,
+				[]model.RedEnvelopeStatus{model.RedEnvelopeStatusActive, model.RedEnvelopeStatusScheduled}, time.Now()).
 			Order("id ASC").
 			Limit(batchSize).
 			Find(&expiredEnvelopes).Error; err != nil {
@@ -64,14 +74,35 @@ func refundExpiredRedEnvelopes(ctx context.Context) {
 
 		// 处理每个过期红包
 		for _, envelope := range expiredEnvelopes {
+			// 退款前补写过期前已从领取池领取但尚未落库的记录
+			if _, _, err := persistPendingClaims(ctx, envelope.ID); err != nil {
+				logger.ErrorF(ctx, "红包ID:%d 补写领取记录失败，跳过退款: %v", envelope.ID, err)
+				lastID = envelope.ID
+				continue
+			}
+
+			refunded := false
 			if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
-				// 更新红包状态为已过期
+				// 锁定红包并读取补写后的剩余金额，避免与并发落库的领取记录冲突
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					Where("id = ? AND status IN ? AND remaining_amount > 0", envelope.ID,
+						[]model.RedEnvelopeStatus{model.RedEnvelopeStatusActive, model.RedEnvelopeStatusScheduled}).
+					First(&envelope).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return nil
+					}
+					return err
+				}
+				refunded = true
+
+				// 更新红包状态为已过期，商户红包同时标记待下发退款回调
 				if err := tx.Model(&model.RedEnvelope{}).
-					Where("id = ? AND status = ?", envelope.ID, model.RedEnvelopeStatusActive).
+					Where("id = ?", envelope.ID).
 					Updates(map[string]interface{}{
 						"status":           model.RedEnvelopeStatusExpired,
 						"remaining_amount": 0,
 						"remaining_count":  0,
+						"notify_pending":   envelope.ClientID != "",
 					}).Error; err != nil {
 					return err
 				}
@@ -104,6 +135,7 @@ func refundExpiredRedEnvelopes(ctx context.Context) {
 						Remark:      remarkMsg,
 						TradeTime:   time.Now(),
 						ExpiresAt:   time.Now().Add(24 * time.Hour),
+						ClientID:    envelope.ClientID,
 					}
 
 					if err := tx.Create(&order).Error; err != nil {
@@ -116,8 +148,16 @@ func refundExpiredRedEnvelopes(ctx context.Context) {
 				return nil
 			}); err != nil {
 				logger.ErrorF(ctx, "红包ID:%d 退款失败: %v", envelope.ID, err)
-			} else {
+			} else if refunded {
 				totalProcessed++
+				if err := clearClaimPool(ctx, envelope.ID); err != nil {
+					logger.ErrorF(ctx, "红包ID:%d 清理领取池失败: %v", envelope.ID, err)
+				}
+				if envelope.ClientID != "" {
+					if err := notifyMerchant(ctx, envelope.ID, EventRedEnvelopeRefunded); err != nil {
+						logger.ErrorF(ctx, "红包ID:%d 下发商户回调任务失败，等待核对任务补发: %v", envelope.ID, err)
+					}
+				}
 			}
 
 			// 更新游标
@@ -131,3 +171,185 @@ func refundExpiredRedEnvelopes(ctx context.Context) {
 		logger.InfoF(ctx, "没有需要退款的过期红包")
 	}
 }
+
+// HandlePersistRedEnvelopeClaim 补写同步落库失败的红包领取记录
+func HandlePersistRedEnvelopeClaim(ctx context.Context, t *asynq.Task) error {
+	var payload struct {
+		RedEnvelopeID uint64          `json:"red_envelope_id"`
+		UserID        uint64          `json:"user_id"`
+		Seq           int             `json:"seq"`
+		Amount        decimal.Decimal `json:"amount"`
+	}
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w: %w", err, asynq.SkipRetry)
+	}
+
+	if err := persistClaim(ctx, payload.RedEnvelopeID, payload.UserID, claimSlot{Seq: payload.Seq, Amount: payload.Amount}); err != nil {
+		if err.Error() == ClaimPersistConflict {
+			if err := revokeClaim(ctx, payload.RedEnvelopeID, payload.UserID); err != nil {
+				logger.ErrorF(ctx, "红包ID:%d 撤销用户ID:%d 领取失败: %v", payload.RedEnvelopeID, payload.UserID, err)
+			}
+			return fmt.Errorf("红包ID:%d 用户ID:%d 领取记录无法落库: %w", payload.RedEnvelopeID, payload.UserID, asynq.SkipRetry)
+		}
+		return err
+	}
+
+	logger.InfoF(ctx, "红包ID:%d 用户ID:%d 领取记录补写完成，金额:%s", payload.RedEnvelopeID, payload.UserID, payload.Amount.String())
+	return nil
+}
+
+// HandleReconcileRedEnvelopes 核对进行中红包的 Redis 领取池与数据库
+func HandleReconcileRedEnvelopes(ctx context.Context, t *asynq.Task) error {
+	const batchSize = 100
+	var lastID uint64 = 0
+	var totalReconciled int = 0
+
+	for {
+		var redEnvelopeIDs []uint64
+		if err := db.DB(ctx).Model(&model.RedEnvelope{}).
+			Where("id > ? AND status IN ? AND (opens_at IS NULL OR opens_at <= ?)", lastID,
+				[]model.RedEnvelopeStatus{model.RedEnvelopeStatusActive, model.RedEnvelopeStatusScheduled}, time.Now()).
+			Order("id ASC").
+			Limit(batchSize).
+			Pluck("id", &redEnvelopeIDs).Error; err != nil {
+			return fmt.Errorf("查询进行中红包失败: %w", err)
+		}
+
+		if len(redEnvelopeIDs) == 0 {
+			break
+		}
+
+		for _, redEnvelopeID := range redEnvelopeIDs {
+			if err := reconcileClaimPool(ctx, redEnvelopeID); err != nil {
+				logger.ErrorF(ctx, "红包ID:%d 领取池核对失败: %v", redEnvelopeID, err)
+			} else {
+				totalReconciled++
+			}
+			lastID = redEnvelopeID
+		}
+	}
+
+	logger.InfoF(ctx, "红包领取核对任务完成，共核对 %d 个红包", totalReconciled)
+
+	retryPendingMerchantNotifies(ctx)
+	return nil
+}
+
+// retryPendingMerchantNotifies 补发状态已变更但回调任务下发失败的商户红包事件
+func retryPendingMerchantNotifies(ctx context.Context) {
+	const batchSize = 100
+	var lastID uint64 = 0
+	var totalRetried int = 0
+
+	for {
+		var redEnvelopes []model.RedEnvelope
+		if err := db.DB(ctx).Select("id, status").
+			Where("id > ? AND notify_pending = ?", lastID, true).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&redEnvelopes).Error; err != nil {
+			logger.ErrorF(ctx, "查询待补发回调的红包失败: %v", err)
+			return
+		}
+
+		if len(redEnvelopes) == 0 {
+			break
+		}
+
+		for _, redEnvelope := range redEnvelopes {
+			lastID = redEnvelope.ID
+			event, ok := merchantNotifyEvent(redEnvelope.Status)
+			if !ok {
+				continue
+			}
+			if err := notifyMerchant(ctx, redEnvelope.ID, event); err != nil {
+				logger.ErrorF(ctx, "红包ID:%d 补发商户回调任务失败: %v", redEnvelope.ID, err)
+				continue
+			}
+			totalRetried++
+		}
+	}
+
+	if totalRetried > 0 {
+		logger.InfoF(ctx, "补发商户红包回调任务完成，共 %d 个", totalRetried)
+	}
+}
+
+// HandleRedEnvelopeMerchantNotify 向商户回调地址推送红包领完或过期退款事件
+func HandleRedEnvelopeMerchantNotify(ctx context.Context, t *asynq.Task) error {
+	var payload struct {
+		RedEnvelopeID uint64 `json:"red_envelope_id"`
+		Event         string `json:"event"`
+	}
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("解析任务参数失败: %w: %w", err, asynq.SkipRetry)
+	}
+
+	var redEnvelope model.RedEnvelope
+	if err := db.DB(ctx).Where("id = ?", payload.RedEnvelopeID).First(&redEnvelope).Error; err != nil {
+		return fmt.Errorf("查询红包失败: %w", err)
+	}
+	if redEnvelope.ClientID == "" {
+		return nil
+	}
+
+	var apiKey model.MerchantAPIKey
+	if err := apiKey.GetByClientID(db.DB(ctx), redEnvelope.ClientID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.InfoF(ctx, "商户[ClientID:%s]已删除，跳过红包回调", redEnvelope.ClientID)
+			return nil
+		}
+		return fmt.Errorf("查询商户信息失败: %w", err)
+	}
+
+	callbackURL := cmp.Or(redEnvelope.NotifyURL, apiKey.NotifyURL)
+	if callbackURL == "" || (config.Config.App.IsProduction() && util.IsLocalhost(callbackURL)) {
+		return nil
+	}
+
+	// 过期退款会清零剩余，领取数据以领取记录为准
+	var summary struct {
+		Count  int
+		Amount decimal.Decimal
+	}
+	if err := db.DB(ctx).Model(&model.RedEnvelopeClaim{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("red_envelope_id = ?", redEnvelope.ID).
+		Scan(&summary).Error; err != nil {
+		return fmt.Errorf("统计红包领取记录失败: %w", err)
+	}
+
+	refundAmount := decimal.Zero
+	if redEnvelope.Status == model.RedEnvelopeStatusExpired {
+		refundAmount = redEnvelope.TotalAmount.Sub(summary.Amount)
+	}
+
+	var serverSeed string
+	if redEnvelope.SeedRevealed() {
+		serverSeed = redEnvelope.ServerSeed
+	}
+
+	callbackParams := map[string]string{
+		"pid":             redEnvelope.ClientID,
+		"event":           payload.Event,
+		"red_envelope_id": strconv.FormatUint(redEnvelope.ID, 10),
+		"out_trade_no":    util.DerefString(redEnvelope.MerchantOrderNo),
+		"status":          string(redEnvelope.Status),
+		"total_amount":    redEnvelope.TotalAmount.StringFixed(2),
+		"total_count":     strconv.Itoa(redEnvelope.TotalCount),
+		"claimed_count":   strconv.Itoa(summary.Count),
+		"claimed_amount":  summary.Amount.StringFixed(2),
+		"refund_amount":   refundAmount.StringFixed(2),
+		"server_seed":     serverSeed,
+	}
+	payment.SignCallbackParams(callbackParams, &apiKey, "")
+
+	if err := payment.SendCallbackRequest(ctx, callbackURL, callbackParams); err != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		logger.ErrorF(ctx, "红包回调失败: 红包[ID:%d] 事件[%s] 重试次数[%d] 错误: %v", redEnvelope.ID, payload.Event, retried+1, err)
+		return err
+	}
+
+	logger.InfoF(ctx, "红包回调成功: 红包[ID:%d] 事件[%s] ClientID[%s]", redEnvelope.ID, payload.Event, redEnvelope.ClientID)
+	return nil
+}
@@ -17,13 +17,134 @@ limitations under the License.
 package redenvelope
 
 import (
+	"context"
+	"crypto/hmac"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/credit/internal/common"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/linux-do/credit/internal/logger"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
+	"github.com/linux-do/credit/internal/task"
+	"github.com/linux-do/credit/internal/task/scheduler"
+	"github.com/redis/go-redis/v9"
 	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// calculateRandomAmount 二倍均值算法计算随机红包金额
-func calculateRandomAmount(remaining decimal.Decimal, count int) decimal.Decimal {
+// buildEligibility 将领取资格请求转换为红包资格限制，用户名去重，指定商户按用户名解析为用户 ID
+func buildEligibility(tx *gorm.DB, req *EligibilityRequest) (model.RedEnvelopeEligibility, error) {
+	var eligibility model.RedEnvelopeEligibility
+	if req == nil {
+		return eligibility, nil
+	}
+
+	for _, username := range req.Usernames {
+		username = strings.TrimSpace(username)
+		if username == "" || slices.ContainsFunc(eligibility.Usernames, func(u string) bool { return strings.EqualFold(u, username) }) {
+			continue
+		}
+		if len(eligibility.Usernames) >= model.MaxRedEnvelopeUsernames {
+			return eligibility, errors.New(TooManyUsernames)
+		}
+		eligibility.Usernames = append(eligibility.Usernames, username)
+	}
+	eligibility.MinTrustLevel = req.MinTrustLevel
+	eligibility.MinAccountAgeDays = req.MinAccountAgeDays
+
+	if merchantUsername := strings.TrimSpace(req.PaidMerchantUsername); merchantUsername != "" {
+		var merchant model.User
+		if err := tx.Where("username = ?", merchantUsername).First(&merchant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return eligibility, errors.New(PaidMerchantNotFound)
+			}
+			return eligibility, err
+		}
+		eligibility.PaidMerchantUserID = merchant.ID
+		eligibility.PaidMerchantUsername = merchant.Username
+	}
+	return eligibility, nil
+}
+
+// isIneligible 判断错误是否为不满足领取资格
+func isIneligible(err error) bool {
+	switch err.Error() {
+	case common.RedEnvelopeNotInUserList, common.RedEnvelopeTrustLevelTooLow,
+		common.RedEnvelopeAccountTooNew, common.RedEnvelopeMerchantNotPaid:
+		return true
+	}
+	return false
+}
+
+// hashPassphrase 生成口令的 bcrypt 哈希，口令首尾空白不计入
+func hashPassphrase(passphrase string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(strings.TrimSpace(passphrase)), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// verifyPassphrase 校验口令是否与哈希匹配
+func verifyPassphrase(hash string, passphrase string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(strings.TrimSpace(passphrase))) == nil
+}
+
+// passphraseAttemptKey 用户对指定红包的口令错误计数 key
+func passphraseAttemptKey(redEnvelopeID uint64, userID uint64) string {
+	return db.PrefixedKey(fmt.Sprintf(passphraseAttemptKeyFormat, redEnvelopeID, userID))
+}
+
+// passphraseAttemptScript 原子占用一次口令尝试次数，计数 key 缺少过期时间时补设计数窗口
+// KEYS[1]: 口令错误计数 key ARGV[1]: 计数窗口秒数
+// 返回占用后的计数
+var passphraseAttemptScript = redis.NewScript(`
+local attempts = redis.call('INCR', KEYS[1])
+if redis.call('TTL', KEYS[1]) < 0 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+return attempts
+`)
+
+// passphraseReleaseScript 口令正确时归还占用的尝试次数，计数 key 已过期时不做处理
+// KEYS[1]: 口令错误计数 key
+var passphraseReleaseScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('DECR', KEYS[1])
+end
+return 1
+`)
+
+// acquirePassphraseAttempt 先占用一次尝试次数再校验口令，计数窗口内超过上限时拒绝，Redis 异常时不拦截
+func acquirePassphraseAttempt(ctx context.Context, redEnvelopeID uint64, userID uint64) bool {
+	key := passphraseAttemptKey(redEnvelopeID, userID)
+	attempts, err := passphraseAttemptScript.Run(ctx, db.Redis, []string{key}, int(passphraseLockDuration.Seconds())).Int()
+	return err != nil || attempts <= passphraseMaxAttempts
+}
+
+// releasePassphraseAttempt 口令正确时归还占用的尝试次数，仅口令错误计入上限
+func releasePassphraseAttempt(ctx context.Context, redEnvelopeID uint64, userID uint64) {
+	passphraseReleaseScript.Run(ctx, db.Redis, []string{passphraseAttemptKey(redEnvelopeID, userID)})
+}
+
+// calculateRandomAmount 二倍均值算法计算随机红包金额，roll 返回 [0, n) 内的随机整数
+func calculateRandomAmount(remaining decimal.Decimal, count int, roll func(n int64) int64) decimal.Decimal {
 	// 如果是最后一个红包，返回所有剩余金额（避免舍入误差）
 	if count == 1 {
 		return remaining
@@ -65,9 +186,499 @@ func calculateRandomAmount(remaining decimal.Decimal, count int) decimal.Decimal
 		return minAmount
 	}
 
-	randCents := rand.Int63n(diffCents + 1) // [0, diffCents]
+	randCents := roll(diffCents + 1) // [0, diffCents]
 	randAmount := decimal.NewFromInt(randCents).Div(decimal.NewFromInt(100))
 	amount := minAmount.Add(randAmount)
 
 	return amount.Round(2)
 }
+
+// claimScript 原子完成领取去重与金额弹出
+// KEYS[1]: 待领取金额列表 KEYS[2]: 已领取用户哈希 ARGV[1]: 用户ID
+// 返回 {返回码, 序号:金额}；两个 key 均不存在说明领取池尚未加载
+var claimScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 and redis.call('EXISTS', KEYS[2]) == 0 then
+	return {-1, ''}
+end
+local claimed = redis.call('HGET', KEYS[2], ARGV[1])
+if claimed then
+	return {1, claimed}
+end
+local ttl = redis.call('TTL', KEYS[1])
+local amount = redis.call('LPOP', KEYS[1])
+if not amount then
+	return {2, ''}
+end
+redis.call('HSET', KEYS[2], ARGV[1], amount)
+if ttl > 0 and redis.call('TTL', KEYS[2]) < 0 then
+	redis.call('EXPIRE', KEYS[2], ttl)
+end
+return {0, amount}
+`)
+
+// loadClaimPoolScript 写入领取池
+// KEYS[1]: 待领取金额列表 KEYS[2]: 已领取用户哈希
+// ARGV[1]: 过期秒数 ARGV[2]: 是否覆盖已有领取池 ARGV[3]: 已领取记录数 n
+// ARGV[4..3+2n]: 用户ID/序号:金额，其余为待领取的 序号:金额
+var loadClaimPoolScript = redis.NewScript(`
+if ARGV[2] == '1' then
+	redis.call('DEL', KEYS[1], KEYS[2])
+elseif redis.call('EXISTS', KEYS[1]) == 1 or redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+local n = tonumber(ARGV[3])
+for i = 4, 3 + n * 2, 2 do
+	redis.call('HSET', KEYS[2], ARGV[i], ARGV[i + 1])
+end
+for i = 4 + n * 2, #ARGV do
+	redis.call('RPUSH', KEYS[1], ARGV[i])
+end
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+redis.call('EXPIRE', KEYS[2], ARGV[1])
+return 1
+`)
+
+// claimPoolKeys 红包领取池的待领取金额列表与已领取用户哈希 key
+func claimPoolKeys(redEnvelopeID uint64) []string {
+	return []string{
+		db.PrefixedKey(fmt.Sprintf(claimAmountsKeyFormat, redEnvelopeID)),
+		db.PrefixedKey(fmt.Sprintf(claimUsersKeyFormat, redEnvelopeID)),
+	}
+}
+
+// generateServerSeed 生成服务端种子及其承诺哈希，创建时仅公开哈希
+func generateServerSeed() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := cryptorand.Read(buf); err != nil {
+		return "", "", err
+	}
+	seed := hex.EncodeToString(buf)
+	return seed, hashServerSeed(seed), nil
+}
+
+// hashServerSeed 服务端种子的承诺哈希：SHA-256(种子字符串) 的十六进制
+func hashServerSeed(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// seededRoll 由种子与领取序号确定性地生成随机数：HMAC-SHA256(种子, 序号) 前 8 字节按大端取模
+func seededRoll(seed string, seq int) func(n int64) int64 {
+	return func(n int64) int64 {
+		mac := hmac.New(sha256.New, []byte(seed))
+		mac.Write([]byte(strconv.Itoa(seq)))
+		return int64(binary.BigEndian.Uint64(mac.Sum(nil)[:8]) % uint64(n))
+	}
+}
+
+// deriveAmounts 由服务端种子按领取序号推导全部红包金额，下标 i 对应序号 i+1
+func deriveAmounts(redEnvelope *model.RedEnvelope) []decimal.Decimal {
+	amounts := make([]decimal.Decimal, 0, redEnvelope.TotalCount)
+	remaining := redEnvelope.TotalAmount
+	for seq := 1; seq <= redEnvelope.TotalCount; seq++ {
+		amount := nextAmount(redEnvelope, remaining, redEnvelope.TotalCount-seq+1, seededRoll(redEnvelope.ServerSeed, seq))
+		amounts = append(amounts, amount)
+		remaining = remaining.Sub(amount)
+	}
+	return amounts
+}
+
+// nextAmount 计算剩余 count 个红包中下一个红包的金额
+func nextAmount(redEnvelope *model.RedEnvelope, remaining decimal.Decimal, count int, roll func(n int64) int64) decimal.Decimal {
+	switch {
+	case count == 1:
+		// 最后一个红包给全部剩余金额（避免舍入误差）
+		return remaining
+	case redEnvelope.Type == model.RedEnvelopeTypeFixed:
+		return redEnvelope.TotalAmount.Div(decimal.NewFromInt(int64(redEnvelope.TotalCount))).Round(2)
+	default:
+		// 拼手气红包：使用二倍均值算法
+		return calculateRandomAmount(remaining, count, roll)
+	}
+}
+
+// claimSlot 预拆分的单个红包，Seq 为领取序号（从 1 开始）
+type claimSlot struct {
+	Seq    int
+	Amount decimal.Decimal
+}
+
+// String 领取池中的存储格式：序号:金额
+func (s claimSlot) String() string {
+	return fmt.Sprintf("%d:%s", s.Seq, s.Amount.String())
+}
+
+// parseClaimSlot 解析领取池中的 序号:金额
+func parseClaimSlot(value string) (claimSlot, error) {
+	seqStr, amountStr, ok := strings.Cut(value, ":")
+	if !ok {
+		return claimSlot{}, fmt.Errorf("无效的领取池数据: %s", value)
+	}
+	seq, err := strconv.Atoi(seqStr)
+	if err != nil {
+		return claimSlot{}, err
+	}
+	amount, err := decimal.NewFromString(amountStr)
+	if err != nil {
+		return claimSlot{}, err
+	}
+	return claimSlot{Seq: seq, Amount: amount}, nil
+}
+
+// splitSlots 计算尚未领取的红包
+// 有服务端种子时按种子推导并排除已领取的序号；早期红包没有种子，按剩余金额随机拆分
+func splitSlots(redEnvelope *model.RedEnvelope, claims []model.RedEnvelopeClaim) []claimSlot {
+	slots := make([]claimSlot, 0, redEnvelope.RemainingCount)
+	if redEnvelope.ServerSeed != "" {
+		claimedSeqs := make(map[int]bool, len(claims))
+		for _, claim := range claims {
+			claimedSeqs[claim.Seq] = true
+		}
+		for i, amount := range deriveAmounts(redEnvelope) {
+			if !claimedSeqs[i+1] {
+				slots = append(slots, claimSlot{Seq: i + 1, Amount: amount})
+			}
+		}
+		return slots
+	}
+
+	remaining := redEnvelope.RemainingAmount
+	for count := redEnvelope.RemainingCount; count > 0; count-- {
+		amount := nextAmount(redEnvelope, remaining, count, rand.Int63n)
+		slots = append(slots, claimSlot{Seq: len(claims) + len(slots) + 1, Amount: amount})
+		remaining = remaining.Sub(amount)
+	}
+	return slots
+}
+
+// loadClaimPool 按数据库中的剩余金额与已落库的领取记录构建 Redis 领取池
+// overwrite 为 false 时领取池已存在则不做修改
+func loadClaimPool(ctx context.Context, redEnvelopeID uint64, overwrite bool) error {
+	var redEnvelope model.RedEnvelope
+	if err := db.DB(ctx).Where("id = ?", redEnvelopeID).First(&redEnvelope).Error; err != nil {
+		return err
+	}
+
+	var claims []model.RedEnvelopeClaim
+	if err := db.DB(ctx).Select("user_id, amount, seq").
+		Where("red_envelope_id = ?", redEnvelopeID).
+		Find(&claims).Error; err != nil {
+		return err
+	}
+
+	ttl := max(int64((time.Until(redEnvelope.ExpiresAt) + claimPoolTTLBuffer).Seconds()), 60)
+	args := []interface{}{ttl, 0, len(claims)}
+	if overwrite {
+		args[1] = 1
+	}
+	for _, claim := range claims {
+		args = append(args, strconv.FormatUint(claim.UserID, 10), claimSlot{Seq: claim.Seq, Amount: claim.Amount}.String())
+	}
+	for _, slot := range splitSlots(&redEnvelope, claims) {
+		args = append(args, slot.String())
+	}
+
+	return loadClaimPoolScript.Run(ctx, db.Redis, claimPoolKeys(redEnvelopeID), args...).Err()
+}
+
+// clearClaimPool 删除红包领取池
+func clearClaimPool(ctx context.Context, redEnvelopeID uint64) error {
+	return db.Redis.Del(ctx, claimPoolKeys(redEnvelopeID)...).Err()
+}
+
+// popClaimSlot 从领取池弹出红包，领取池未加载时先从数据库恢复
+func popClaimSlot(ctx context.Context, redEnvelopeID uint64, userID uint64) (claimSlot, error) {
+	for loaded := false; ; loaded = true {
+		result, err := claimScript.Run(ctx, db.Redis, claimPoolKeys(redEnvelopeID), strconv.FormatUint(userID, 10)).Slice()
+		if err != nil {
+			return claimSlot{}, err
+		}
+
+		code, _ := result[0].(int64)
+		switch code {
+		case claimResultOK:
+			value, _ := result[1].(string)
+			return parseClaimSlot(value)
+		case claimResultAlreadyClaimed:
+			return claimSlot{}, errors.New(RedEnvelopeAlreadyClaimed)
+		case claimResultFinished:
+			return claimSlot{}, errors.New(RedEnvelopeFinished)
+		case claimResultNotLoaded:
+			if loaded {
+				return claimSlot{}, errors.New(RedEnvelopeFinished)
+			}
+			if err := loadClaimPool(ctx, redEnvelopeID, false); err != nil {
+				return claimSlot{}, err
+			}
+		default:
+			return claimSlot{}, fmt.Errorf("未知的领取结果: %d", code)
+		}
+	}
+}
+
+// revokeClaim 撤销领取池中无法落库的领取记录
+func revokeClaim(ctx context.Context, redEnvelopeID uint64, userID uint64) error {
+	return db.Redis.HDel(ctx, claimPoolKeys(redEnvelopeID)[1], strconv.FormatUint(userID, 10)).Err()
+}
+
+// persistClaim 将领取池中的领取结果落库：写入领取记录、增加领取者余额、扣减红包剩余
+// 领取记录已存在时视为已落库，可重复调用
+func persistClaim(ctx context.Context, redEnvelopeID uint64, userID uint64, slot claimSlot) error {
+	amount := slot.Amount
+	finished := false
+	if err := db.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var redEnvelope model.RedEnvelope
+		if err := tx.Select("id, creator_id, greeting, client_id").Where("id = ?", redEnvelopeID).First(&redEnvelope).Error; err != nil {
+			return err
+		}
+
+		// 创建领取记录
+		claim := model.RedEnvelopeClaim{
+			ID:            idgen.NextUint64ID(),
+			RedEnvelopeID: redEnvelopeID,
+			UserID:        userID,
+			Amount:        amount,
+			Seq:           slot.Seq,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		// 增加领取者余额并更新total_receive
+		if err := service.UpdateBalance(tx, service.BalanceUpdateOptions{
+			UserID:     userID,
+			Amount:     amount,
+			Operation:  service.BalanceAdd,
+			TotalField: "total_receive",
+		}); err != nil {
+			return err
+		}
+
+		// 创建订单记录（红包收入）
+		order := model.Order{
+			OrderName:   "红包收入",
+			PayerUserID: redEnvelope.CreatorID,
+			PayeeUserID: userID,
+			Amount:      amount,
+			Status:      model.OrderStatusSuccess,
+			Type:        model.OrderTypeRedEnvelopeReceive,
+			Remark:      fmt.Sprintf("祝福语: %s", redEnvelope.Greeting),
+			TradeTime:   time.Now(),
+			ExpiresAt:   time.Now().Add(24 * time.Hour),
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		// 最后原子扣减红包剩余，缩短红包行锁的持有时间；已过期退款或余量不足时拒绝落库
+		result = tx.Model(&model.RedEnvelope{}).
+			Where("id = ? AND status IN ? AND remaining_count > 0 AND remaining_amount >= ?", redEnvelopeID,
+				[]model.RedEnvelopeStatus{model.RedEnvelopeStatusActive, model.RedEnvelopeStatusScheduled}, amount).
+			Updates(map[string]interface{}{
+				"remaining_count":  gorm.Expr("remaining_count - 1"),
+				"remaining_amount": gorm.Expr("remaining_amount - ?", amount),
+				"status": gorm.Expr("CASE WHEN remaining_count <= 1 THEN ? ELSE ? END",
+					model.RedEnvelopeStatusFinished, model.RedEnvelopeStatusActive),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(ClaimPersistConflict)
+		}
+
+		// 商户红包领完时通知商户，与领取记录同一事务标记待下发回调，下发失败由核对任务补发
+		if redEnvelope.ClientID != "" {
+			if err := tx.Select("status").Where("id = ?", redEnvelopeID).First(&redEnvelope).Error; err != nil {
+				return err
+			}
+			finished = redEnvelope.Status == model.RedEnvelopeStatusFinished
+			if finished {
+				if err := tx.Model(&model.RedEnvelope{}).Where("id = ?", redEnvelopeID).
+					Update("notify_pending", true).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if finished {
+		if err := notifyMerchant(ctx, redEnvelopeID, EventRedEnvelopeFinished); err != nil {
+			logger.ErrorF(ctx, "红包ID:%d 下发商户回调任务失败，等待核对任务补发: %v", redEnvelopeID, err)
+		}
+	}
+	return nil
+}
+
+// notifyMerchant 下发商户红包事件回调任务并清除待下发标记，下发失败时保留标记
+func notifyMerchant(ctx context.Context, redEnvelopeID uint64, event string) error {
+	if err := enqueueMerchantNotify(redEnvelopeID, event); err != nil {
+		return err
+	}
+	return db.DB(ctx).Model(&model.RedEnvelope{}).
+		Where("id = ?", redEnvelopeID).
+		Update("notify_pending", false).Error
+}
+
+// merchantNotifyEvent 按红包状态确定待补发的商户回调事件
+func merchantNotifyEvent(status model.RedEnvelopeStatus) (string, bool) {
+	switch status {
+	case model.RedEnvelopeStatusFinished:
+		return EventRedEnvelopeFinished, true
+	case model.RedEnvelopeStatusExpired:
+		return EventRedEnvelopeRefunded, true
+	default:
+		return "", false
+	}
+}
+
+// enqueueMerchantNotify 下发商户红包事件回调任务，同一红包同一事件仅保留一个任务
+func enqueueMerchantNotify(redEnvelopeID uint64, event string) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"red_envelope_id": redEnvelopeID,
+		"event":           event,
+	})
+	if _, err := scheduler.AsynqClient.Enqueue(
+		asynq.NewTask(task.RedEnvelopeMerchantNotifyTask, payload),
+		asynq.TaskID(fmt.Sprintf(merchantNotifyTaskIDFormat, redEnvelopeID, event)),
+		asynq.Queue(task.QueueWebhook),
+		asynq.MaxRetry(5),
+		asynq.Timeout(30*time.Second),
+	); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+	return nil
+}
+
+// enqueuePersistClaim 同步落库失败时下发补写任务，同一用户同一红包仅保留一个任务
+func enqueuePersistClaim(redEnvelopeID uint64, userID uint64, slot claimSlot) error {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"red_envelope_id": redEnvelopeID,
+		"user_id":         userID,
+		"seq":             slot.Seq,
+		"amount":          slot.Amount.String(),
+	})
+	if _, err := scheduler.AsynqClient.Enqueue(
+		asynq.NewTask(task.PersistRedEnvelopeClaimTask, payload),
+		asynq.TaskID(fmt.Sprintf(persistClaimTaskIDFormat, redEnvelopeID, userID)),
+		asynq.MaxRetry(10),
+	); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return err
+	}
+	return nil
+}
+
+// persistPendingClaims 补写领取池中已领取但尚未落库的记录，返回补写前的领取池快照
+func persistPendingClaims(ctx context.Context, redEnvelopeID uint64) (map[string]string, []string, error) {
+	keys := claimPoolKeys(redEnvelopeID)
+	var claimedCmd *redis.MapStringStringCmd
+	var amountsCmd *redis.StringSliceCmd
+	if _, err := db.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		amountsCmd = pipe.LRange(ctx, keys[0], 0, -1)
+		claimedCmd = pipe.HGetAll(ctx, keys[1])
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	claimed, amounts := claimedCmd.Val(), amountsCmd.Val()
+	if len(claimed) == 0 {
+		return claimed, amounts, nil
+	}
+
+	var persistedUserIDs []uint64
+	if err := db.DB(ctx).Model(&model.RedEnvelopeClaim{}).
+		Where("red_envelope_id = ?", redEnvelopeID).
+		Pluck("user_id", &persistedUserIDs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for userIDStr, value := range claimed {
+		userID, err := strconv.ParseUint(userIDStr, 10, 64)
+		if err != nil || slices.Contains(persistedUserIDs, userID) {
+			continue
+		}
+		slot, err := parseClaimSlot(value)
+		if err != nil {
+			continue
+		}
+
+		if err := persistClaim(ctx, redEnvelopeID, userID, slot); err != nil {
+			if err.Error() == ClaimPersistConflict {
+				logger.WarnF(ctx, "红包ID:%d 用户ID:%d 领取记录无法落库，已从领取池撤销", redEnvelopeID, userID)
+				if err := revokeClaim(ctx, redEnvelopeID, userID); err != nil {
+					logger.ErrorF(ctx, "红包ID:%d 撤销用户ID:%d 领取失败: %v", redEnvelopeID, userID, err)
+				}
+				continue
+			}
+			return nil, nil, err
+		}
+		logger.InfoF(ctx, "红包ID:%d 已补写用户ID:%d 的领取记录，金额:%s", redEnvelopeID, userID, slot.Amount.String())
+	}
+	return claimed, amounts, nil
+}
+
+// reconcileClaimPool 核对领取池与数据库，补写未落库的领取记录，剩余金额不一致时按数据库重建领取池
+func reconcileClaimPool(ctx context.Context, redEnvelopeID uint64) error {
+	claimed, amounts, err := persistPendingClaims(ctx, redEnvelopeID)
+	if err != nil {
+		return err
+	}
+	if len(claimed) == 0 && len(amounts) == 0 {
+		// 领取池未加载，领取时按需从数据库恢复
+		return nil
+	}
+
+	var redEnvelope model.RedEnvelope
+	if err := db.DB(ctx).Where("id = ?", redEnvelopeID).First(&redEnvelope).Error; err != nil {
+		return err
+	}
+	var persistedUserIDs []uint64
+	if err := db.DB(ctx).Model(&model.RedEnvelopeClaim{}).
+		Where("red_envelope_id = ?", redEnvelopeID).
+		Pluck("user_id", &persistedUserIDs).Error; err != nil {
+		return err
+	}
+
+	// 领取时先写 Redis 再落库，已落库但领取池中不存在的记录说明 Redis 数据丢失
+	if len(persistedUserIDs) > 0 {
+		fields := make([]string, 0, len(persistedUserIDs))
+		for _, userID := range persistedUserIDs {
+			fields = append(fields, strconv.FormatUint(userID, 10))
+		}
+		values, err := db.Redis.HMGet(ctx, claimPoolKeys(redEnvelopeID)[1], fields...).Result()
+		if err != nil {
+			return err
+		}
+		if slices.Contains(values, nil) {
+			logger.WarnF(ctx, "红包ID:%d 领取池缺少已落库的领取记录，按数据库重建", redEnvelopeID)
+			return loadClaimPool(ctx, redEnvelopeID, true)
+		}
+	}
+
+	// 快照之后又有领取落库时跳过，留待下次核对
+	if len(persistedUserIDs) != len(claimed) {
+		return nil
+	}
+
+	remaining := decimal.Zero
+	for _, value := range amounts {
+		slot, err := parseClaimSlot(value)
+		if err != nil {
+			return err
+		}
+		remaining = remaining.Add(slot.Amount)
+	}
+	if len(amounts) != redEnvelope.RemainingCount || !remaining.Equal(redEnvelope.RemainingAmount) {
+		logger.WarnF(ctx, "红包ID:%d 领取池剩余[%d个/%s]与数据库[%d个/%s]不一致，按数据库重建", redEnvelopeID,
+			len(amounts), remaining.String(), redEnvelope.RemainingCount, redEnvelope.RemainingAmount.String())
+		return loadClaimPool(ctx, redEnvelopeID, true)
+	}
+	return nil
+}
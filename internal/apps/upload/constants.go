@@ -24,5 +24,11 @@ const (
 
 // 最大文件大小
 const (
-	MaxFileSize = 2 * 1024 * 1024 // 2MB
+	MaxFileSize         = 2 * 1024 * 1024 // 2MB
+	MaxEvidenceFileSize = 5 * 1024 * 1024 // 5MB
 )
+
+// 争议凭证支持的文件类型
+const pdfMagic = "%PDF-"
+
+var evidenceImageFormats = []string{"jpeg", "png", "webp"}
@@ -29,4 +29,6 @@ const (
 	ErrInvalidFilePath               = "非法文件路径"
 	ErrSaveUploadRecordFailed        = "保存上传记录失败"
 	ErrQueryHistoryCoverFailed       = "查询历史封面失败"
+	ErrEvidenceTooLarge              = "凭证文件大小不能超过 5MB"
+	ErrUnsupportedEvidence           = "凭证只支持 JPG、PNG、WEBP 图片或 PDF 文件"
 )
@@ -42,9 +42,10 @@ func ServeFileByID(c *gin.Context) {
 		return
 	}
 
+	// 争议凭证仅能通过争议附件接口鉴权访问
 	var upload model.Upload
 	if err := db.DB(c.Request.Context()).
-		Where("id = ? AND status IN (?, ?)", uploadID, model.UploadStatusPending, model.UploadStatusUsed).
+		Where("id = ? AND status IN (?, ?) AND type <> ?", uploadID, model.UploadStatusPending, model.UploadStatusUsed, model.UploadTypeEvidence).
 		First(&upload).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.AbortWithStatus(http.StatusNotFound)
@@ -54,6 +55,11 @@ func ServeFileByID(c *gin.Context) {
 		return
 	}
 
+	ServeUpload(c, &upload)
+}
+
+// ServeUpload streams an uploaded file, callers are responsible for access control
+func ServeUpload(c *gin.Context, upload *model.Upload) {
 	// Retrieve file from S3 (via CDN if configured)
 	obj, err := storage.GetObjectViaCache(c.Request.Context(), upload.FilePath)
 	if err != nil {
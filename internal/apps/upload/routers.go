@@ -17,6 +17,7 @@ limitations under the License.
 package upload
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
@@ -26,7 +27,9 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -109,46 +112,151 @@ func UploadRedEnvelopeCover(c *gin.Context) {
 		return
 	}
 
-	// 重置文件指针
-	src.Close()
-	src, _ = file.Open()
+	safeExt := "." + format
+	if format == "jpeg" {
+		safeExt = ".jpg"
+	}
+
+	recordID, status, err := storeUpload(c, currentUser.ID, coverType, file, safeExt, "image/"+format)
+	if err != nil {
+		c.JSON(status, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(UploadResponse{
+		ID: recordID,
+	}))
+}
+
+// ListRedEnvelopeCovers 获取用户历史红包封面
+// @Tags redenvelope
+// @Produce json
+// @Param type query string true "封面类型 (cover/heterotypic)"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/redenvelope/covers [get]
+func ListRedEnvelopeCovers(c *gin.Context) {
+	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	coverType := c.Query("type")
+	if coverType != CoverTypeCover && coverType != CoverTypeHeterotypic {
+		c.JSON(http.StatusBadRequest, util.Err(ErrInvalidCoverType))
+		return
+	}
+
+	var uploads []model.Upload
+	if err := db.DB(c.Request.Context()).
+		Where("user_id = ? AND status = ? AND type = ?",
+			currentUser.ID, model.UploadStatusUsed, coverType).
+		Order("created_at DESC").
+		Limit(20).
+		Find(&uploads).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(ErrQueryHistoryCoverFailed))
+		return
+	}
+
+	var results []UploadResponse
+	for _, u := range uploads {
+		results = append(results, UploadResponse{
+			ID: u.ID,
+		})
+	}
+
+	c.JSON(http.StatusOK, util.OK(results))
+}
+
+// UploadDisputeEvidence 上传争议凭证
+// @Tags upload
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "凭证文件（图片或 PDF）"
+// @Success 200 {object} util.ResponseAny
+// @Router /api/v1/upload/dispute/evidence [post]
+func UploadDisputeEvidence(c *gin.Context) {
+	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.Err(ErrNoFileSelected))
+		return
+	}
+
+	if file.Size > int64(MaxEvidenceFileSize) {
+		c.JSON(http.StatusBadRequest, util.Err(ErrEvidenceTooLarge))
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.Err(ErrOpenFileFailed))
+		return
+	}
+	defer src.Close()
+
+	// 通过文件头识别类型，不信任客户端提交的文件名与 Content-Type
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(src, header)
+	header = header[:n]
+
+	var ext, contentType string
+	if bytes.HasPrefix(header, []byte(pdfMagic)) {
+		ext, contentType = ".pdf", "application/pdf"
+	} else {
+		_, format, errDecode := image.DecodeConfig(io.MultiReader(bytes.NewReader(header), src))
+		if errDecode != nil || !slices.Contains(evidenceImageFormats, format) {
+			c.JSON(http.StatusBadRequest, util.Err(ErrUnsupportedEvidence))
+			return
+		}
+		ext, contentType = "."+format, "image/"+format
+		if format == "jpeg" {
+			ext = ".jpg"
+		}
+	}
+
+	recordID, status, err := storeUpload(c, currentUser.ID, model.UploadTypeEvidence, file, ext, contentType)
+	if err != nil {
+		c.JSON(status, util.Err(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.OK(UploadResponse{
+		ID: recordID,
+	}))
+}
+
+// storeUpload 按内容 MD5 去重保存上传文件并创建上传记录，失败时返回对应的 HTTP 状态码
+func storeUpload(c *gin.Context, userID uint64, uploadType string, file *multipart.FileHeader, ext string, contentType string) (uint64, int, error) {
+	src, err := file.Open()
+	if err != nil {
+		return 0, http.StatusInternalServerError, errors.New(ErrOpenFileFailed)
+	}
 	defer src.Close()
 
 	// 计算文件 MD5 以避免重复上传
 	hash := md5.New()
 	if _, err := io.Copy(hash, src); err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(ErrProcessFileFailed))
-		return
+		return 0, http.StatusInternalServerError, errors.New(ErrProcessFileFailed)
 	}
 	md5Sum := hex.EncodeToString(hash.Sum(nil))
 
 	// 重置文件指针
-	src.Close()
-	src, _ = file.Open()
-	defer src.Close()
-
-	// 生成安全的文件名: 用户ID_类型_MD5.扩展名
-	// 使用完整 MD5 实现去重，同一用户上传相同图片会命中已有文件
-	safeExt := "." + format
-	if format == "jpeg" {
-		safeExt = ".jpg"
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return 0, http.StatusInternalServerError, errors.New(ErrProcessFileFailed)
 	}
-	filename := fmt.Sprintf("%s%s", md5Sum, safeExt)
+
+	// 生成安全的文件名: MD5.扩展名
+	// 使用完整 MD5 实现去重，同一用户上传相同文件会命中已有记录
+	filename := fmt.Sprintf("%s%s", md5Sum, ext)
 
 	// 构建 S3 object key: {prefix}{type}/{date}/{userID}/{filename}
 	now := time.Now()
-	objectPath := fmt.Sprintf("%s/%s/%d/%s", coverType, now.Format("2006/01/02"), currentUser.ID, filename)
+	objectPath := fmt.Sprintf("%s/%s/%d/%s", uploadType, now.Format("2006/01/02"), userID, filename)
 	s3Key := storage.BuildKey(objectPath)
 
 	// validate S3 key
 	if err := ValidateS3Key(s3Key); err != nil {
-		c.JSON(http.StatusBadRequest, util.Err(ErrInvalidFilePath))
-		return
+		return 0, http.StatusBadRequest, errors.New(ErrInvalidFilePath)
 	}
 
-	// Content type for S3
-	contentType := "image/" + format
-
 	var recordID uint64
 
 	if err := db.DB(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
@@ -167,10 +275,10 @@ func UploadRedEnvelopeCover(c *gin.Context) {
 
 		upload := model.Upload{
 			ID:       idgen.NextUint64ID(),
-			UserID:   currentUser.ID,
+			UserID:   userID,
 			FilePath: s3Key,
 			FileSize: file.Size,
-			Type:     coverType,
+			Type:     uploadType,
 			Status:   model.UploadStatusPending,
 		}
 
@@ -184,50 +292,10 @@ func UploadRedEnvelopeCover(c *gin.Context) {
 		return nil
 	}); err != nil {
 		if err.Error() == ErrSaveFileFailed {
-			c.JSON(http.StatusInternalServerError, util.Err(ErrSaveFileFailed))
-			return
+			return 0, http.StatusInternalServerError, errors.New(ErrSaveFileFailed)
 		}
-		c.JSON(http.StatusInternalServerError, util.Err(ErrSaveUploadRecordFailed))
-		return
-	}
-
-	c.JSON(http.StatusOK, util.OK(UploadResponse{
-		ID: recordID,
-	}))
-}
-
-// ListRedEnvelopeCovers 获取用户历史红包封面
-// @Tags redenvelope
-// @Produce json
-// @Param type query string true "封面类型 (cover/heterotypic)"
-// @Success 200 {object} util.ResponseAny
-// @Router /api/v1/redenvelope/covers [get]
-func ListRedEnvelopeCovers(c *gin.Context) {
-	currentUser, _ := util.GetFromContext[*model.User](c, oauth.UserObjKey)
-
-	coverType := c.Query("type")
-	if coverType != CoverTypeCover && coverType != CoverTypeHeterotypic {
-		c.JSON(http.StatusBadRequest, util.Err(ErrInvalidCoverType))
-		return
+		return 0, http.StatusInternalServerError, errors.New(ErrSaveUploadRecordFailed)
 	}
 
-	var uploads []model.Upload
-	if err := db.DB(c.Request.Context()).
-		Where("user_id = ? AND status = ? AND type = ?",
-			currentUser.ID, model.UploadStatusUsed, coverType).
-		Order("created_at DESC").
-		Limit(20).
-		Find(&uploads).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, util.Err(ErrQueryHistoryCoverFailed))
-		return
-	}
-
-	var results []UploadResponse
-	for _, u := range uploads {
-		results = append(results, UploadResponse{
-			ID: u.ID,
-		})
-	}
-
-	c.JSON(http.StatusOK, util.OK(results))
+	return recordID, http.StatusOK, nil
 }
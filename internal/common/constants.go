@@ -22,3 +22,23 @@ const (
 	// PayTypeEPay Epay 支付类型
 	PayTypeEPay = "epay"
 )
+
+const (
+	// SignTypeMD5 易支付签名类型：MD5
+	SignTypeMD5 = "MD5"
+	// SignTypeSHA256 易支付签名类型：SHA-256
+	SignTypeSHA256 = "SHA256"
+	// SignTypeRSA 易支付签名类型：RSA-SHA256，使用商户 RSA 公钥验签
+	SignTypeRSA = "RSA"
+)
+
+const (
+	// TradeStatusSuccess 商户回调：支付成功
+	TradeStatusSuccess = "TRADE_SUCCESS"
+	// TradeStatusFailed 商户回调：支付失败
+	TradeStatusFailed = "TRADE_FAILED"
+	// TradeStatusRefund 商户回调：已退款
+	TradeStatusRefund = "TRADE_REFUND"
+	// TradeStatusDispute 商户回调：发生争议
+	TradeStatusDispute = "TRADE_DISPUTE"
+)
@@ -34,6 +34,20 @@ const (
 	RedEnvelopeDailyLimitExceeded = "今日发红包数量已达上限"
 	RedEnvelopeRecipientsExceeded = "红包个数超过最大可领取人数上限"
 	RedEnvelopeMinAmountRequired  = "红包总金额不能低于1LDC"
+	RedEnvelopeNotInUserList      = "该红包仅限指定用户领取"
+	RedEnvelopeTrustLevelTooLow   = "您的信任等级未达到该红包的领取要求"
+	RedEnvelopeAccountTooNew      = "您的账号注册时间未达到该红包的领取要求"
+	RedEnvelopeMerchantNotPaid    = "该红包仅限在指定商户付款过的用户领取"
+	PaymentLinkPaused             = "该支付链接已暂停收款"
+	PaymentLinkNotStarted         = "该支付链接尚未开始"
+	PaymentLinkEnded              = "该支付链接已结束"
+	PaymentLinkSoldOut            = "该支付链接已售罄"
+	SplitRulesInvalid             = "分账规则无效，类型须为 fixed 或 percent 且金额须大于0并最多2位小数"
+	SplitRulesTooMany             = "分账规则最多10条"
+	SplitPayeeInvalid             = "分账收款人不存在或不可用"
+	SplitPayeeDuplicated          = "分账收款人重复"
+	SplitPercentExceeded          = "分账比例合计不能超过100%"
+	SplitAmountExceeded           = "分账金额合计超过订单金额"
 )
 
 const (
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"testing"
 
 	"github.com/spf13/viper"
 )
@@ -37,8 +38,12 @@ func init() {
 	viper.SetConfigFile(configPath)
 	viper.AutomaticEnv()
 
-	// 读取配置文件
+	// 读取配置文件，单元测试中缺少配置文件时使用零值配置，各存储组件均不启用
 	if err := viper.ReadInConfig(); err != nil {
+		if testing.Testing() {
+			Config = &configModel{Log: logConfig{Level: "error", Output: "stdout"}}
+			return
+		}
 		log.Fatalf("[Config] read config failed: %v\n", err)
 	}
 
@@ -35,20 +35,21 @@ type configModel struct {
 
 // appConfig 应用基本配置
 type appConfig struct {
-	AppName                 string `mapstructure:"app_name"`
-	Env                     string `mapstructure:"env"`
-	Addr                    string `mapstructure:"addr"`
-	NodeID                  int64  `mapstructure:"node_id"`
-	APIPrefix               string `mapstructure:"api_prefix"`
-	GracefulShutdownTimeout int    `mapstructure:"graceful_shutdown_timeout"`
-	FrontendURL             string `mapstructure:"frontend_url"`
-	FrontendPayURL          string `mapstructure:"frontend_pay_url"`
-	SessionCookieName       string `mapstructure:"session_cookie_name"`
-	SessionSecret           string `mapstructure:"session_secret"`
-	SessionDomain           string `mapstructure:"session_domain"`
-	SessionAge              int    `mapstructure:"session_age"`
-	SessionHttpOnly         bool   `mapstructure:"session_http_only"`
-	SessionSecure           bool   `mapstructure:"session_secure"`
+	AppName                 string   `mapstructure:"app_name"`
+	Env                     string   `mapstructure:"env"`
+	Addr                    string   `mapstructure:"addr"`
+	NodeID                  int64    `mapstructure:"node_id"`
+	APIPrefix               string   `mapstructure:"api_prefix"`
+	GracefulShutdownTimeout int      `mapstructure:"graceful_shutdown_timeout"`
+	FrontendURL             string   `mapstructure:"frontend_url"`
+	FrontendPayURL          string   `mapstructure:"frontend_pay_url"`
+	SessionCookieName       string   `mapstructure:"session_cookie_name"`
+	SessionSecret           string   `mapstructure:"session_secret"`
+	SessionDomain           string   `mapstructure:"session_domain"`
+	SessionAge              int      `mapstructure:"session_age"`
+	SessionHttpOnly         bool     `mapstructure:"session_http_only"`
+	SessionSecure           bool     `mapstructure:"session_secure"`
+	TrustedProxies          []string `mapstructure:"trusted_proxies"`
 }
 
 // IsProduction 检查当前环境是否为生产环境
@@ -153,8 +154,11 @@ type schedulerConfig struct {
 	AutoRefundExpiredDisputesTaskCron        string `mapstructure:"auto_refund_expired_disputes_task_cron"`
 	SyncOrdersToClickHouseTaskCron           string `mapstructure:"sync_orders_to_clickhouse_task_cron"`
 	RefundExpiredRedEnvelopesTaskCron        string `mapstructure:"refund_expired_red_envelopes_task_cron"`
+	ReconcileRedEnvelopesTaskCron            string `mapstructure:"reconcile_red_envelopes_task_cron"`
 	CleanupUnusedUploadsTaskCron             string `mapstructure:"cleanup_unused_uploads_task_cron"`
 	SettlePendingPaymentsTaskCron            string `mapstructure:"settle_pending_payments_task_cron"`
+	GenerateMerchantStatementsTaskCron       string `mapstructure:"generate_merchant_statements_task_cron"`
+	EvaluateMerchantRiskTaskCron             string `mapstructure:"evaluate_merchant_risk_task_cron"`
 }
 
 // workerConfig 工作配置
@@ -32,18 +32,29 @@ func Migrate() {
 		return
 	}
 
+	// 清理已废弃的索引
+	dropLegacyIndexes()
+
 	if err := db.DB(context.Background()).AutoMigrate(
 		&model.User{},
 		&model.UserPayConfig{},
 		&model.MerchantAPIKey{},
+		&model.MerchantAPIKeyDenial{},
+		&model.MerchantMember{},
+		&model.MerchantAuditLog{},
 		&model.MerchantPaymentLink{},
 		&model.Order{},
 		&model.OrderTransfer{},
+		&model.OrderSplit{},
 		&model.SystemConfig{},
 		&model.Dispute{},
+		&model.DisputeMessage{},
+		&model.MerchantRiskLog{},
 		&model.RedEnvelope{},
 		&model.RedEnvelopeClaim{},
 		&model.Upload{},
+		&model.SandboxAccount{},
+		&model.MerchantStatement{},
 	); err != nil {
 		log.Fatalf("[PostgreSQL] auto migrate failed: %v\n", err)
 	}
@@ -54,6 +65,73 @@ func Migrate() {
 
 	// 初始化用户支付配置数据
 	initUserPayConfigs()
+
+	// 回填支付链接库存
+	backfillPaymentLinkStock()
+
+	// 回填订单退款时间
+	backfillOrderRefundedAt()
+
+	// 回填争议拒绝时间
+	backfillDisputeRefusedAt()
+}
+
+// dropLegacyIndexes 删除已被新索引取代的旧索引
+func dropLegacyIndexes() {
+	// 分账后同一订单可存在多个收款方的到账记录，唯一约束调整为 (order_id, payee_user_id)
+	if err := db.DB(context.Background()).Exec("DROP INDEX IF EXISTS uk_order_id").Error; err != nil {
+		log.Printf("[PostgreSQL] failed to drop legacy index uk_order_id: %v\n", err)
+	}
+}
+
+// backfillPaymentLinkStock 为设置了总次数但尚无库存计数的支付链接回填剩余库存
+func backfillPaymentLinkStock() {
+	result := db.DB(context.Background()).Exec(`
+		UPDATE merchant_payment_links AS l
+		SET remaining_stock = GREATEST(l.total_limit - (
+			SELECT COUNT(*) FROM orders o WHERE o.payment_link_id = l.id AND o.status IN ?
+		), 0)
+		WHERE l.total_limit IS NOT NULL AND l.remaining_stock IS NULL`,
+		[]model.OrderStatus{model.OrderStatusSuccess, model.OrderStatusDisputing, model.OrderStatusRefused, model.OrderStatusPartialRefund})
+	if result.Error != nil {
+		log.Printf("[PostgreSQL] failed to backfill payment link stock: %v\n", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[PostgreSQL] backfilled stock for %d payment links\n", result.RowsAffected)
+	}
+}
+
+// backfillOrderRefundedAt 为新增退款时间字段前已退款的订单回填退款时间，以最后更新时间近似
+func backfillOrderRefundedAt() {
+	result := db.DB(context.Background()).Exec(`
+		UPDATE orders SET refunded_at = updated_at
+		WHERE status IN ? AND refunded_at IS NULL`,
+		[]model.OrderStatus{model.OrderStatusRefund, model.OrderStatusPartialRefund})
+	if result.Error != nil {
+		log.Printf("[PostgreSQL] failed to backfill order refunded_at: %v\n", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[PostgreSQL] backfilled refunded_at for %d orders\n", result.RowsAffected)
+	}
+}
+
+// backfillDisputeRefusedAt 为新增拒绝时间字段前被商家拒绝且未申诉的争议回填拒绝时间，以最后更新时间近似
+func backfillDisputeRefusedAt() {
+	result := db.DB(context.Background()).Exec(`
+		UPDATE disputes SET refused_at = disputes.updated_at
+		FROM orders
+		WHERE orders.id = disputes.order_id AND orders.status = ?
+			AND disputes.status = ? AND disputes.refused_at IS NULL AND disputes.appealed_at IS NULL`,
+		model.OrderStatusRefused, model.DisputeStatusClosed)
+	if result.Error != nil {
+		log.Printf("[PostgreSQL] failed to backfill dispute refused_at: %v\n", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[PostgreSQL] backfilled refused_at for %d disputes\n", result.RowsAffected)
+	}
 }
 
 // initSystemConfigs 初始化系统配置数据
@@ -86,6 +164,16 @@ func initSystemConfigs() {
 			Value:       "168",
 			Description: "商家争议时间窗口（小时）",
 		},
+		{
+			Key:         model.ConfigKeyDisputeAppealWindowHours,
+			Value:       "72",
+			Description: "商家拒绝退款后买家申诉仲裁的时间窗口（小时）",
+		},
+		{
+			Key:         model.ConfigKeyDisputeReminderHours,
+			Value:       "24,6,1",
+			Description: "争议处理截止前发送提醒的时间点（小时），逗号分隔，留空则不提醒",
+		},
 		{
 			Key:         model.ConfigKeyNewUserInitialCredit,
 			Value:       "0",
@@ -146,6 +234,96 @@ func initSystemConfigs() {
 			Value:       "14",
 			Description: "商户收款延迟到账最大天数（实际天数在min~max随机）",
 		},
+		{
+			Key:         model.ConfigKeyRateLimitPaySubmit,
+			Value:       "120/60",
+			Description: "商户创建订单限流，按 ClientID 计数（次数/秒数，次数为0表示不限流）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitPaySubmitIP,
+			Value:       "60/60",
+			Description: "商户创建订单认证前限流，按 IP 计数（次数/秒数，次数为0表示不限流）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitMerchantAPI,
+			Value:       "60/60",
+			Description: "商户查询订单与退款限流，按 ClientID 计数（次数/秒数）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitMerchantAPIIP,
+			Value:       "30/60",
+			Description: "商户查询订单、退款与订单二维码认证前限流，按 IP 计数（次数/秒数）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitDistribute,
+			Value:       "60/60",
+			Description: "商户分发限流，按 ClientID 计数（次数/秒数）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitDistributeIP,
+			Value:       "30/60",
+			Description: "商户分发与红包接口认证前限流，按 IP 计数（次数/秒数）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitTransfer,
+			Value:       "10/60",
+			Description: "用户转账限流，按用户计数（次数/秒数）",
+		},
+		{
+			Key:         model.ConfigKeyRateLimitRedEnvelopeClaim,
+			Value:       "30/60",
+			Description: "领取红包限流，按用户计数（次数/秒数）",
+		},
+		{
+			Key:         model.ConfigKeySandboxInitialBalance,
+			Value:       "10000",
+			Description: "沙箱账户初始模拟余额（买家与商户各自独立，不影响真实余额）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantOrderExpireMinMinutes,
+			Value:       "1",
+			Description: "商户通过 timeout_express/expire_minutes 自定义订单过期时间的下限（分钟）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantOrderExpireMaxMinutes,
+			Value:       "10080",
+			Description: "商户通过 timeout_express/expire_minutes 自定义订单过期时间的上限（分钟）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskWindowDays,
+			Value:       "7,30",
+			Description: "商户风控统计的滚动窗口（天），逗号分隔，任一窗口超过阈值即处置",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskMinOrders,
+			Value:       "20",
+			Description: "窗口内订单数达到该值才参与风控判定",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskDisputeRate,
+			Value:       "0.05",
+			Description: "商户争议率阈值（0-1之间的小数，0表示不判定）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskRefundRate,
+			Value:       "0.15",
+			Description: "商户退款率阈值（0-1之间的小数，0表示不判定）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskSuspendRate,
+			Value:       "0.30",
+			Description: "API Key 争议率或退款率达到该值时暂停使用（0表示不暂停）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskSettlementDelayDays,
+			Value:       "30",
+			Description: "超过阈值的商户收款延迟到账天数，应大于延迟到账最大天数（0表示不延长）",
+		},
+		{
+			Key:         model.ConfigKeyMerchantRiskFeeRate,
+			Value:       "0.10",
+			Description: "超过阈值的商户手续费率，低于原费率时不生效（0表示不调整）",
+		},
 	}
 
 	if err := tx.Create(&defaultConfigs).Error; err != nil {
@@ -0,0 +1,58 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/linux-do/credit/internal/util"
+	"gorm.io/gorm"
+)
+
+// DisputeParty 争议消息作者身份
+type DisputeParty string
+
+const (
+	DisputePartyBuyer    DisputeParty = "buyer"
+	DisputePartyMerchant DisputeParty = "merchant"
+	DisputePartyAdmin    DisputeParty = "admin"
+	// DisputePartySystem 系统消息，如处理截止提醒
+	DisputePartySystem DisputeParty = "system"
+)
+
+// MaxDisputeAttachments 单条争议消息最多附件数
+const MaxDisputeAttachments = 5
+
+// DisputeMessage 争议沟通消息
+type DisputeMessage struct {
+	ID             uint64           `json:"id,string" gorm:"primaryKey"`
+	DisputeID      uint64           `json:"dispute_id,string" gorm:"not null;index:idx_dispute_messages_dispute_created,priority:1"`
+	AuthorUserID   uint64           `json:"author_user_id,string" gorm:"not null"`
+	AuthorParty    DisputeParty     `json:"author_party" gorm:"type:varchar(20);not null"`
+	Body           string           `json:"body" gorm:"size:1000;not null"`
+	AttachmentIDs  util.StringArray `json:"attachment_ids" gorm:"type:jsonb"`
+	AuthorUsername string           `json:"author_username" gorm:"-:migration;->"`
+	CreatedAt      time.Time        `json:"created_at" gorm:"autoCreateTime;index:idx_dispute_messages_dispute_created,priority:2"`
+}
+
+func (m *DisputeMessage) BeforeCreate(*gorm.DB) error {
+	if m.ID == 0 {
+		m.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
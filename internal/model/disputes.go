@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -29,19 +30,40 @@ const (
 	DisputeStatusDisputing DisputeStatus = "disputing"
 	DisputeStatusRefund    DisputeStatus = "refund"
 	DisputeStatusClosed    DisputeStatus = "closed"
+	// DisputeStatusArbitrating 买家对商家拒绝结果提起申诉，等待管理员仲裁
+	DisputeStatusArbitrating DisputeStatus = "arbitrating"
+)
+
+// DisputeRuling 管理员仲裁结果
+type DisputeRuling string
+
+const (
+	DisputeRulingRefund        DisputeRuling = "refund"
+	DisputeRulingPartialRefund DisputeRuling = "partial_refund"
+	DisputeRulingReject        DisputeRuling = "reject"
 )
 
 type Dispute struct {
-	ID                uint64        `json:"id,string" gorm:"primaryKey"`
-	OrderID           uint64        `json:"order_id,string" gorm:"uniqueIndex:idx_dispute_order;index:idx_dispute_order_status,priority:1;not null"`
-	InitiatorUserID   uint64        `json:"initiator_user_id" gorm:"not null;index:idx_initiator_status_created,priority:1"`
-	Reason            string        `json:"reason" gorm:"size:500;not null"`
-	Status            DisputeStatus `json:"status" gorm:"type:varchar(20);index;index:idx_dispute_order_status,priority:2;index:idx_initiator_status_created,priority:2;not null;default:'disputing'"`
-	HandlerUserID     *uint64       `json:"handler_user_id" gorm:"index"`
-	InitiatorUsername string        `json:"initiator_username" gorm:"-:migration;->"`
-	HandlerUsername   string        `json:"handler_username" gorm:"-:migration;->"`
-	CreatedAt         time.Time     `json:"created_at" gorm:"autoCreateTime;index:idx_initiator_status_created,priority:3"`
-	UpdatedAt         time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+	ID                uint64           `json:"id,string" gorm:"primaryKey"`
+	OrderID           uint64           `json:"order_id,string" gorm:"uniqueIndex:idx_dispute_order;index:idx_dispute_order_status,priority:1;not null"`
+	InitiatorUserID   uint64           `json:"initiator_user_id" gorm:"not null;index:idx_initiator_status_created,priority:1"`
+	Reason            string           `json:"reason" gorm:"size:500;not null"`
+	Status            DisputeStatus    `json:"status" gorm:"type:varchar(20);index;index:idx_dispute_order_status,priority:2;index:idx_initiator_status_created,priority:2;not null;default:'disputing'"`
+	HandlerUserID     *uint64          `json:"handler_user_id" gorm:"index"`
+	RespondBy         *time.Time       `json:"respond_by" gorm:"index"`
+	RefundAmount      decimal.Decimal  `json:"refund_amount" gorm:"type:numeric(20,2);not null;default:0"`
+	OfferAmount       *decimal.Decimal `json:"offer_amount" gorm:"type:numeric(20,2);default:null"`
+	OfferedAt         *time.Time       `json:"offered_at"`
+	RefusedAt         *time.Time       `json:"refused_at"`
+	AppealReason      string           `json:"appeal_reason" gorm:"size:500"`
+	AppealedAt        *time.Time       `json:"appealed_at"`
+	Ruling            DisputeRuling    `json:"ruling" gorm:"type:varchar(20)"`
+	RulingReason      string           `json:"ruling_reason" gorm:"size:500"`
+	RuledAt           *time.Time       `json:"ruled_at"`
+	InitiatorUsername string           `json:"initiator_username" gorm:"-:migration;->"`
+	HandlerUsername   string           `json:"handler_username" gorm:"-:migration;->"`
+	CreatedAt         time.Time        `json:"created_at" gorm:"autoCreateTime;index:idx_initiator_status_created,priority:3"`
+	UpdatedAt         time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (d *Dispute) BeforeCreate(*gorm.DB) error {
@@ -0,0 +1,42 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"gorm.io/gorm"
+)
+
+// MerchantAPIKeyDenial 商户 API Key 被 IP 白名单拒绝的访问记录
+type MerchantAPIKeyDenial struct {
+	ID               uint64    `json:"id,string" gorm:"primaryKey"`
+	MerchantAPIKeyID uint64    `json:"merchant_api_key_id,string" gorm:"not null;index:idx_api_key_denials_key_created,priority:1"`
+	ClientIP         string    `json:"client_ip" gorm:"size:64;not null"`
+	Method           string    `json:"method" gorm:"size:10;not null"`
+	Path             string    `json:"path" gorm:"size:255;not null"`
+	UserAgent        string    `json:"user_agent" gorm:"size:255"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_api_key_denials_key_created,priority:2"`
+}
+
+func (d *MerchantAPIKeyDenial) BeforeCreate(*gorm.DB) error {
+	if d.ID == 0 {
+		d.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
@@ -17,27 +17,36 @@ limitations under the License.
 package model
 
 import (
+	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/linux-do/credit/internal/util"
 	"gorm.io/gorm"
 )
 
 type MerchantAPIKey struct {
-	ID             uint64         `json:"id,string" gorm:"primaryKey"`
-	UserID         uint64         `json:"user_id" gorm:"not null;index:idx_merchant_api_keys_user_created,priority:1"`
-	ClientID       string         `json:"client_id" gorm:"size:64;uniqueIndex;index:idx_client_credentials,priority:2;not null"`
-	ClientSecret   string         `json:"client_secret" gorm:"size:64;index:idx_client_credentials,priority:1;not null"`
-	AppName        string         `json:"app_name" gorm:"size:20;not null"`
-	AppHomepageURL string         `json:"app_homepage_url" gorm:"size:100;not null"`
-	AppDescription string         `json:"app_description" gorm:"size:100"`
-	RedirectURI    string         `json:"redirect_uri" gorm:"size:100"`
-	NotifyURL      string         `json:"notify_url" gorm:"size:100;not null"`
-	PublicKey      []byte         `json:"public_key" gorm:"type:bytea"`
-	TestMode       bool           `json:"test_mode" gorm:"default:false"`
-	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime;index:idx_merchant_api_keys_user_created,priority:2"`
-	UpdatedAt      time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	ID             uint64           `json:"id,string" gorm:"primaryKey"`
+	UserID         uint64           `json:"user_id" gorm:"not null;index:idx_merchant_api_keys_user_created,priority:1"`
+	ClientID       string           `json:"client_id" gorm:"size:64;uniqueIndex;index:idx_client_credentials,priority:2;not null"`
+	ClientSecret   string           `json:"client_secret" gorm:"size:64;index:idx_client_credentials,priority:1;not null"`
+	AppName        string           `json:"app_name" gorm:"size:20;not null"`
+	AppHomepageURL string           `json:"app_homepage_url" gorm:"size:100;not null"`
+	AppDescription string           `json:"app_description" gorm:"size:100"`
+	RedirectURI    string           `json:"redirect_uri" gorm:"size:100"`
+	NotifyURL      string           `json:"notify_url" gorm:"size:100;not null"`
+	PublicKey      []byte           `json:"public_key" gorm:"type:bytea"`
+	RSAPublicKey   string           `json:"rsa_public_key" gorm:"type:text"`
+	DisableMD5     bool             `json:"disable_md5" gorm:"default:false"`
+	TestMode       bool             `json:"test_mode" gorm:"default:false"`
+	IPAllowlist    util.StringArray `json:"ip_allowlist" gorm:"type:jsonb"`
+	SplitRules     SplitRules       `json:"split_rules" gorm:"type:jsonb"`
+	SuspendedAt    *time.Time       `json:"suspended_at"`
+	SuspendReason  string           `json:"suspend_reason" gorm:"size:255"`
+	CreatedAt      time.Time        `json:"created_at" gorm:"autoCreateTime;index:idx_merchant_api_keys_user_created,priority:2"`
+	UpdatedAt      time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt   `json:"deleted_at" gorm:"index"`
 }
 
 // GetByID 通过 ID 查询商户 API Key
@@ -50,6 +59,54 @@ func (m *MerchantAPIKey) GetByClientID(tx *gorm.DB, clientID string) error {
 	return tx.Where("client_id = ?", clientID).First(m).Error
 }
 
+// IsSuspended 是否因风控被暂停使用
+func (m *MerchantAPIKey) IsSuspended() bool {
+	return m.SuspendedAt != nil
+}
+
+// IsIPAllowed 校验 IP 是否在白名单内，未配置白名单时不做限制
+func (m *MerchantAPIKey) IsIPAllowed(ip string) bool {
+	if len(m.IPAllowlist) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, entry := range m.IPAllowlist {
+		prefix, err := ParseIPPrefix(entry)
+		if err != nil {
+			continue
+		}
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIPPrefix 解析 CIDR 或单个 IP，单个 IP 视为全长前缀
+func ParseIPPrefix(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
 func (m *MerchantAPIKey) BeforeCreate(*gorm.DB) error {
 	if m.ID == 0 {
 		m.ID = idgen.NextUint64ID()
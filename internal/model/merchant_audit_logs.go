@@ -0,0 +1,46 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"gorm.io/gorm"
+)
+
+// MerchantAuditLog 商户操作审计日志，记录由哪位成员执行了何种操作
+type MerchantAuditLog struct {
+	ID             uint64    `json:"id,string" gorm:"primaryKey"`
+	MerchantUserID uint64    `json:"merchant_user_id,string" gorm:"not null;index:idx_merchant_audit_logs_merchant_created,priority:1"`
+	ActorUserID    uint64    `json:"actor_user_id,string" gorm:"not null;index"`
+	ActorRole      string    `json:"actor_role" gorm:"type:varchar(20);not null"`
+	Action         string    `json:"action" gorm:"size:64;not null"`
+	Method         string    `json:"method" gorm:"size:10;not null"`
+	Path           string    `json:"path" gorm:"size:255;not null"`
+	TargetID       string    `json:"target_id" gorm:"size:64"`
+	ClientIP       string    `json:"client_ip" gorm:"size:64"`
+	ActorUsername  string    `json:"actor_username" gorm:"-:migration;->"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_merchant_audit_logs_merchant_created,priority:2"`
+}
+
+func (l *MerchantAuditLog) BeforeCreate(*gorm.DB) error {
+	if l.ID == 0 {
+		l.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
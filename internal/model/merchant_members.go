@@ -0,0 +1,105 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"gorm.io/gorm"
+)
+
+type MerchantRole string
+
+const (
+	MerchantRoleOwner     MerchantRole = "owner"
+	MerchantRoleAdmin     MerchantRole = "admin"
+	MerchantRoleDeveloper MerchantRole = "developer"
+	MerchantRoleSupport   MerchantRole = "support"
+	MerchantRoleFinance   MerchantRole = "finance"
+)
+
+type MerchantPermission string
+
+const (
+	// MerchantPermissionAPIKey API Key 与沙箱管理
+	MerchantPermissionAPIKey MerchantPermission = "api_key"
+	// MerchantPermissionPaymentLink 支付链接管理
+	MerchantPermissionPaymentLink MerchantPermission = "payment_link"
+	// MerchantPermissionDispute 争议处理与退款审核
+	MerchantPermissionDispute MerchantPermission = "dispute"
+	// MerchantPermissionReport 报表与统计查看
+	MerchantPermissionReport MerchantPermission = "report"
+	// MerchantPermissionMember 成员管理
+	MerchantPermissionMember MerchantPermission = "member"
+)
+
+// merchantRolePermissions 各角色拥有的权限，所有者拥有全部权限
+var merchantRolePermissions = map[MerchantRole][]MerchantPermission{
+	MerchantRoleAdmin: {
+		MerchantPermissionAPIKey,
+		MerchantPermissionPaymentLink,
+		MerchantPermissionDispute,
+		MerchantPermissionReport,
+		MerchantPermissionMember,
+	},
+	MerchantRoleDeveloper: {MerchantPermissionAPIKey, MerchantPermissionPaymentLink},
+	MerchantRoleSupport:   {MerchantPermissionDispute},
+	MerchantRoleFinance:   {MerchantPermissionReport},
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func (r MerchantRole) HasPermission(permission MerchantPermission) bool {
+	if r == MerchantRoleOwner {
+		return true
+	}
+	return slices.Contains(merchantRolePermissions[r], permission)
+}
+
+type MerchantMemberStatus string
+
+const (
+	MerchantMemberStatusPending MerchantMemberStatus = "pending"
+	MerchantMemberStatusActive  MerchantMemberStatus = "active"
+)
+
+type MerchantMember struct {
+	ID               uint64               `json:"id,string" gorm:"primaryKey"`
+	MerchantUserID   uint64               `json:"merchant_user_id,string" gorm:"not null;uniqueIndex:idx_merchant_members_merchant_user,priority:1"`
+	UserID           uint64               `json:"user_id,string" gorm:"not null;uniqueIndex:idx_merchant_members_merchant_user,priority:2;index"`
+	Role             MerchantRole         `json:"role" gorm:"type:varchar(20);not null"`
+	Status           MerchantMemberStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	InvitedByUserID  uint64               `json:"invited_by_user_id,string" gorm:"not null"`
+	Username         string               `json:"username" gorm:"-:migration;->"`
+	MerchantUsername string               `json:"merchant_username" gorm:"-:migration;->"`
+	CreatedAt        time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (m *MerchantMember) BeforeCreate(*gorm.DB) error {
+	if m.ID == 0 {
+		m.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
+
+// GetActiveMembership 查询用户在指定商户下的有效成员身份
+func (m *MerchantMember) GetActiveMembership(tx *gorm.DB, merchantUserID, userID uint64) error {
+	return tx.Where("merchant_user_id = ? AND user_id = ? AND status = ?", merchantUserID, userID, MerchantMemberStatusActive).
+		First(m).Error
+}
@@ -17,25 +17,45 @@ limitations under the License.
 package model
 
 import (
+	"errors"
 	"time"
 
+	"github.com/linux-do/credit/internal/common"
 	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/linux-do/credit/internal/util"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+type PaymentLinkAmountType string
+
+const (
+	PaymentLinkAmountTypeFixed  PaymentLinkAmountType = "fixed"  // 固定金额
+	PaymentLinkAmountTypeCustom PaymentLinkAmountType = "custom" // 付款人自定义金额（打赏/捐赠）
+)
+
 type MerchantPaymentLink struct {
-	ID               uint64          `json:"id,string" gorm:"primaryKey"`
-	MerchantAPIKeyID uint64          `json:"merchant_api_key_id,string" gorm:"not null;index"`
-	Token            string          `json:"token" gorm:"size:64;uniqueIndex;not null"`
-	Amount           decimal.Decimal `json:"amount" gorm:"type:numeric(20,2);not null"`
-	ProductName      string          `json:"product_name" gorm:"size:30;not null"`
-	Remark           string          `json:"remark" gorm:"size:100"`
-	TotalLimit       *uint           `json:"total_limit" gorm:"default:null"`
-	UserLimit        *uint           `json:"user_limit" gorm:"default:null"`
-	CreatedAt        time.Time       `json:"created_at" gorm:"autoCreateTime;index"`
-	UpdatedAt        time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt        gorm.DeletedAt  `json:"deleted_at" gorm:"index"`
+	ID               uint64                `json:"id,string" gorm:"primaryKey"`
+	MerchantAPIKeyID uint64                `json:"merchant_api_key_id,string" gorm:"not null;index"`
+	Token            string                `json:"token" gorm:"size:64;uniqueIndex;not null"`
+	Amount           decimal.Decimal       `json:"amount" gorm:"type:numeric(20,2);not null"`
+	AmountType       PaymentLinkAmountType `json:"amount_type" gorm:"type:varchar(20);not null;default:'fixed'"`
+	MinAmount        *decimal.Decimal      `json:"min_amount" gorm:"type:numeric(20,2);default:null"`
+	MaxAmount        *decimal.Decimal      `json:"max_amount" gorm:"type:numeric(20,2);default:null"`
+	PresetAmounts    util.DecimalArray     `json:"preset_amounts" gorm:"type:jsonb"`
+	AllowMessage     bool                  `json:"allow_message" gorm:"not null;default:false"`
+	ProductName      string                `json:"product_name" gorm:"size:30;not null"`
+	Remark           string                `json:"remark" gorm:"size:100"`
+	TotalLimit       *uint                 `json:"total_limit" gorm:"default:null"`
+	UserLimit        *uint                 `json:"user_limit" gorm:"default:null"`
+	RemainingStock   *uint                 `json:"remaining_stock" gorm:"default:null"`
+	StartsAt         *time.Time            `json:"starts_at" gorm:"default:null"`
+	EndsAt           *time.Time            `json:"ends_at" gorm:"default:null"`
+	IsPaused         bool                  `json:"is_paused" gorm:"not null;default:false"`
+	ViewCount        int64                 `json:"view_count" gorm:"not null;default:0"`
+	CreatedAt        time.Time             `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt        time.Time             `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt        `json:"deleted_at" gorm:"index"`
 }
 
 // GetByToken 通过 Token 查询支付链接
@@ -43,6 +63,75 @@ func (m *MerchantPaymentLink) GetByToken(tx *gorm.DB, token string) error {
 	return tx.Where("token = ?", token).First(m).Error
 }
 
+// CheckAvailable 检查支付链接当前是否可付款（暂停、售罄、不在有效时间段内）
+func (m *MerchantPaymentLink) CheckAvailable(now time.Time) error {
+	if m.IsPaused {
+		return errors.New(common.PaymentLinkPaused)
+	}
+	if m.StartsAt != nil && now.Before(*m.StartsAt) {
+		return errors.New(common.PaymentLinkNotStarted)
+	}
+	if m.EndsAt != nil && !now.Before(*m.EndsAt) {
+		return errors.New(common.PaymentLinkEnded)
+	}
+	if m.RemainingStock != nil && *m.RemainingStock == 0 {
+		return errors.New(common.PaymentLinkSoldOut)
+	}
+	return nil
+}
+
+// DecreaseStock 扣减库存，未设置库存时不做处理
+func (m *MerchantPaymentLink) DecreaseStock(tx *gorm.DB) error {
+	if m.RemainingStock == nil {
+		return nil
+	}
+
+	result := tx.Model(&MerchantPaymentLink{}).
+		Where("id = ? AND remaining_stock > 0", m.ID).
+		UpdateColumn("remaining_stock", gorm.Expr("remaining_stock - 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(common.PaymentLinkSoldOut)
+	}
+	return nil
+}
+
+// RestorePaymentLinkStock 订单全额退款后归还其支付链接的一份库存，未设置库存或库存已满时不做处理
+func RestorePaymentLinkStock(tx *gorm.DB, order *Order) error {
+	if order.PaymentLinkID == nil {
+		return nil
+	}
+
+	return tx.Model(&MerchantPaymentLink{}).
+		Where("id = ? AND remaining_stock IS NOT NULL AND (total_limit IS NULL OR remaining_stock < total_limit)", *order.PaymentLinkID).
+		UpdateColumn("remaining_stock", gorm.Expr("remaining_stock + 1")).Error
+}
+
+// IncreaseViewCount 增加浏览次数
+func (m *MerchantPaymentLink) IncreaseViewCount(tx *gorm.DB) error {
+	return tx.Model(&MerchantPaymentLink{}).
+		Where("id = ?", m.ID).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error
+}
+
+// IsCustomAmount 是否由付款人自定义金额
+func (m *MerchantPaymentLink) IsCustomAmount() bool {
+	return m.AmountType == PaymentLinkAmountTypeCustom
+}
+
+// IsAmountAllowed 校验自定义金额是否在链接允许的范围内
+func (m *MerchantPaymentLink) IsAmountAllowed(amount decimal.Decimal) bool {
+	if m.MinAmount != nil && amount.LessThan(*m.MinAmount) {
+		return false
+	}
+	if m.MaxAmount != nil && amount.GreaterThan(*m.MaxAmount) {
+		return false
+	}
+	return true
+}
+
 func (m *MerchantPaymentLink) BeforeCreate(*gorm.DB) error {
 	if m.ID == 0 {
 		m.ID = idgen.NextUint64ID()
@@ -0,0 +1,61 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// MerchantRiskAction 商户风控处置类型
+type MerchantRiskAction string
+
+const (
+	MerchantRiskActionSettlementDelay MerchantRiskAction = "settlement_delay"
+	MerchantRiskActionFeeRate         MerchantRiskAction = "fee_rate"
+	MerchantRiskActionSuspendAPIKey   MerchantRiskAction = "suspend_api_key"
+	// MerchantRiskActionLift 解除处置，由风控任务在指标恢复后或管理员手动执行
+	MerchantRiskActionLift MerchantRiskAction = "lift"
+)
+
+// MerchantRiskLog 商户风控处置记录，ClientID 为空表示按商户用户维度统计
+type MerchantRiskLog struct {
+	ID             uint64             `json:"id,string" gorm:"primaryKey"`
+	UserID         uint64             `json:"user_id" gorm:"not null;index:idx_merchant_risk_logs_user_created,priority:1"`
+	ClientID       string             `json:"client_id" gorm:"size:64;index"`
+	Action         MerchantRiskAction `json:"action" gorm:"type:varchar(20);not null;index"`
+	WindowDays     int                `json:"window_days" gorm:"not null;default:0"`
+	OrderCount     int64              `json:"order_count" gorm:"not null;default:0"`
+	DisputeCount   int64              `json:"dispute_count" gorm:"not null;default:0"`
+	RefundCount    int64              `json:"refund_count" gorm:"not null;default:0"`
+	DisputeRate    decimal.Decimal    `json:"dispute_rate" gorm:"type:numeric(5,4);not null;default:0"`
+	RefundRate     decimal.Decimal    `json:"refund_rate" gorm:"type:numeric(5,4);not null;default:0"`
+	Detail         string             `json:"detail" gorm:"size:255"`
+	OperatorUserID uint64             `json:"operator_user_id" gorm:"not null;default:0"`
+	Username       string             `json:"username" gorm:"-:migration;->"`
+	CreatedAt      time.Time          `json:"created_at" gorm:"autoCreateTime;index:idx_merchant_risk_logs_user_created,priority:2"`
+}
+
+func (l *MerchantRiskLog) BeforeCreate(*gorm.DB) error {
+	if l.ID == 0 {
+		l.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
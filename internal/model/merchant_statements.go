@@ -0,0 +1,53 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// MerchantStatement 商户日结对账单，按 client_id 每日生成一份
+type MerchantStatement struct {
+	ID             uint64          `json:"id,string" gorm:"primaryKey"`
+	ClientID       string          `json:"client_id" gorm:"size:64;not null;uniqueIndex:idx_merchant_statements_client_date,priority:1"`
+	MerchantUserID uint64          `json:"merchant_user_id,string" gorm:"not null;index"`
+	StatementDate  time.Time       `json:"statement_date" gorm:"type:date;not null;uniqueIndex:idx_merchant_statements_client_date,priority:2"`
+	PaymentCount   int64           `json:"payment_count" gorm:"not null;default:0"`
+	GrossPayments  decimal.Decimal `json:"gross_payments" gorm:"type:numeric(20,2);not null;default:0"`
+	FeesWithheld   decimal.Decimal `json:"fees_withheld" gorm:"type:numeric(20,2);not null;default:0"`
+	Refunds        decimal.Decimal `json:"refunds" gorm:"type:numeric(20,2);not null;default:0"`
+	Distributes    decimal.Decimal `json:"distributes" gorm:"type:numeric(20,2);not null;default:0"`
+	DisputeRefunds decimal.Decimal `json:"dispute_refunds" gorm:"type:numeric(20,2);not null;default:0"`
+	SettledAmount  decimal.Decimal `json:"settled_amount" gorm:"type:numeric(20,2);not null;default:0"`
+	ClosingBalance decimal.Decimal `json:"closing_balance" gorm:"type:numeric(20,2);not null;default:0"`
+	PendingBalance decimal.Decimal `json:"pending_balance" gorm:"type:numeric(20,2);not null;default:0"`
+	FilePath       string          `json:"-" gorm:"size:255"`
+	FileSize       int64           `json:"file_size"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (s *MerchantStatement) BeforeCreate(*gorm.DB) error {
+	if s.ID == 0 {
+		s.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
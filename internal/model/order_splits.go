@@ -0,0 +1,157 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linux-do/credit/internal/common"
+	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type SplitType string
+
+const (
+	SplitTypeFixed   SplitType = "fixed"
+	SplitTypePercent SplitType = "percent"
+)
+
+// MaxSplitRules 单个 API Key 或订单允许的最大分账规则数
+const MaxSplitRules = 10
+
+// SplitRule 分账规则，按固定金额或百分比将订单金额分给其他收款方，剩余部分归商户所有者
+type SplitRule struct {
+	UserID uint64          `json:"user_id,string"`
+	Type   SplitType       `json:"type"`
+	Value  decimal.Decimal `json:"value"`
+}
+
+// SplitRules 分账规则列表，以 jsonb 存储
+type SplitRules []SplitRule
+
+func (r *SplitRules) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return fmt.Errorf("invalid value: %v", value)
+	}
+}
+
+func (r SplitRules) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+// Validate 校验分账规则格式，ownerID 为商户所有者，不可作为分账收款方
+func (r SplitRules) Validate(ownerID uint64) error {
+	if len(r) > MaxSplitRules {
+		return errors.New(common.SplitRulesTooMany)
+	}
+
+	percentTotal := decimal.Zero
+	seen := make(map[uint64]struct{}, len(r))
+	for _, rule := range r {
+		if rule.UserID == 0 || rule.UserID == ownerID {
+			return errors.New(common.SplitPayeeInvalid)
+		}
+		if _, ok := seen[rule.UserID]; ok {
+			return errors.New(common.SplitPayeeDuplicated)
+		}
+		seen[rule.UserID] = struct{}{}
+
+		if !rule.Value.IsPositive() || rule.Value.Exponent() < -2 {
+			return errors.New(common.SplitRulesInvalid)
+		}
+
+		switch rule.Type {
+		case SplitTypeFixed:
+		case SplitTypePercent:
+			percentTotal = percentTotal.Add(rule.Value)
+		default:
+			return errors.New(common.SplitRulesInvalid)
+		}
+	}
+
+	if percentTotal.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New(common.SplitPercentExceeded)
+	}
+	return nil
+}
+
+// SplitAllocation 单个收款方分得的订单金额（未扣手续费）
+type SplitAllocation struct {
+	UserID uint64
+	Amount decimal.Decimal
+}
+
+// Allocate 按分账规则拆分订单金额，百分比向下取整到分，余额归商户所有者
+func (r SplitRules) Allocate(amount decimal.Decimal, ownerID uint64) ([]SplitAllocation, error) {
+	allocations := make([]SplitAllocation, 0, len(r)+1)
+	remaining := amount
+
+	for _, rule := range r {
+		share := rule.Value
+		if rule.Type == SplitTypePercent {
+			share = amount.Mul(rule.Value).Div(decimal.NewFromInt(100)).RoundDown(2)
+		}
+		if share.IsZero() {
+			continue
+		}
+		if share.GreaterThan(remaining) {
+			return nil, errors.New(common.SplitAmountExceeded)
+		}
+		remaining = remaining.Sub(share)
+		allocations = append(allocations, SplitAllocation{UserID: rule.UserID, Amount: share})
+	}
+
+	if remaining.IsPositive() || len(allocations) == 0 {
+		allocations = append([]SplitAllocation{{UserID: ownerID, Amount: remaining}}, allocations...)
+	}
+	return allocations, nil
+}
+
+// OrderSplit 订单分账明细，支付时按规则快照，退款时按比例冲回
+type OrderSplit struct {
+	ID          uint64          `json:"id,string" gorm:"primaryKey"`
+	OrderID     uint64          `json:"order_id,string" gorm:"not null;uniqueIndex:idx_order_splits_order_payee,priority:1"`
+	PayeeUserID uint64          `json:"payee_user_id,string" gorm:"not null;uniqueIndex:idx_order_splits_order_payee,priority:2;index"`
+	Amount      decimal.Decimal `json:"amount" gorm:"type:numeric(20,2);not null"`
+	Fee         decimal.Decimal `json:"fee" gorm:"type:numeric(20,2);not null"`
+	NetAmount   decimal.Decimal `json:"net_amount" gorm:"type:numeric(20,2);not null"`
+	CreatedAt   time.Time       `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (s *OrderSplit) BeforeCreate(*gorm.DB) error {
+	if s.ID == 0 {
+		s.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
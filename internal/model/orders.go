@@ -54,6 +54,8 @@ const (
 	OrderStatusDisputing OrderStatus = "disputing"
 	OrderStatusRefund    OrderStatus = "refund"
 	OrderStatusRefused   OrderStatus = "refused"
+	// OrderStatusPartialRefund 争议以部分退款结束
+	OrderStatusPartialRefund OrderStatus = "partial_refund"
 )
 
 type Order struct {
@@ -70,12 +72,18 @@ type Order struct {
 	Status          OrderStatus     `json:"status" gorm:"type:varchar(20);not null;index:idx_orders_payee_status_type_created,priority:2;index:idx_orders_payer_status_type_created,priority:2;index:idx_orders_client_status_created,priority:2;index:idx_orders_payer_status_type_trade,priority:2;index:idx_orders_payment_link_status,priority:2;index:idx_orders_status_expires,priority:1"`
 	Type            OrderType       `json:"type" gorm:"type:varchar(20);not null;index:idx_orders_payee_status_type_created,priority:3;index:idx_orders_payer_status_type_created,priority:3;index:idx_orders_payer_status_type_trade,priority:3"`
 	Remark          string          `json:"remark" gorm:"size:255"`
+	PayerMessage    string          `json:"payer_message" gorm:"size:100"`
 	PaymentType     string          `json:"payment_type" gorm:"size:20"`
 	RedirectURI     string          `json:"redirect_uri" gorm:"size:100"`
 	NotifyURL       string          `json:"notify_url" gorm:"size:100"`
 	PaymentLinkID   *uint64         `json:"payment_link_id,string" gorm:"index:idx_orders_payment_link_status,priority:1"`
 	TradeTime       time.Time       `json:"trade_time" gorm:"index:idx_orders_payer_status_type_trade,priority:4"`
+	RefundedAt      *time.Time      `json:"refunded_at" gorm:"index"`
 	ExpiresAt       time.Time       `json:"expires_at" gorm:"not null;index:idx_orders_status_expires,priority:2"`
+	IsSandbox       bool            `json:"is_sandbox" gorm:"not null;default:false;index"`
+	Attach          string          `json:"attach" gorm:"type:text"`
+	SplitRules      SplitRules      `json:"split_rules" gorm:"type:jsonb"`
+	SignType        string          `json:"sign_type" gorm:"size:16"`
 	CreatedAt       time.Time       `json:"created_at" gorm:"autoCreateTime;index:idx_orders_payee_status_type_created,priority:4;index:idx_orders_payer_status_type_created,priority:4;index:idx_orders_client_status_created,priority:3"`
 	UpdatedAt       time.Time       `json:"updated_at" gorm:"autoUpdateTime;index"`
 }
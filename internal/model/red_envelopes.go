@@ -17,9 +17,17 @@ limitations under the License.
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/linux-do/credit/internal/common"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 type RedEnvelopeType string
@@ -35,26 +43,126 @@ const (
 	RedEnvelopeStatusActive   RedEnvelopeStatus = "active"
 	RedEnvelopeStatusFinished RedEnvelopeStatus = "finished"
 	RedEnvelopeStatusExpired  RedEnvelopeStatus = "expired"
+	// RedEnvelopeStatusScheduled 定时红包未到开启时间，到达 OpensAt 后首次领取落库时转为 active，读取时按 OpensAt 换算
+	RedEnvelopeStatusScheduled RedEnvelopeStatus = "scheduled"
 )
 
+// MaxRedEnvelopeUsernames 指定领取用户名单的最大人数
+const MaxRedEnvelopeUsernames = 100
+
+// RedEnvelopeEligibility 红包领取资格，各项条件需同时满足，零值表示不限制
+type RedEnvelopeEligibility struct {
+	Usernames            []string   `json:"usernames,omitempty"`
+	MinTrustLevel        TrustLevel `json:"min_trust_level,omitempty"`
+	MinAccountAgeDays    int        `json:"min_account_age_days,omitempty"`
+	PaidMerchantUserID   uint64     `json:"paid_merchant_user_id,string,omitempty"`
+	PaidMerchantUsername string     `json:"paid_merchant_username,omitempty"`
+}
+
+func (e *RedEnvelopeEligibility) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = RedEnvelopeEligibility{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("invalid value: %v", value)
+	}
+}
+
+func (e RedEnvelopeEligibility) Value() (driver.Value, error) {
+	if e.IsEmpty() {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+// IsEmpty 是否未设置任何领取限制
+func (e RedEnvelopeEligibility) IsEmpty() bool {
+	return len(e.Usernames) == 0 && e.MinTrustLevel == 0 && e.MinAccountAgeDays <= 0 && e.PaidMerchantUserID == 0
+}
+
+// Check 校验用户是否满足领取资格，不满足时返回第一个未满足条件的原因
+func (e RedEnvelopeEligibility) Check(tx *gorm.DB, user *User) error {
+	if len(e.Usernames) > 0 && !slices.ContainsFunc(e.Usernames, func(username string) bool {
+		return strings.EqualFold(username, user.Username)
+	}) {
+		return errors.New(common.RedEnvelopeNotInUserList)
+	}
+	if user.TrustLevel < e.MinTrustLevel {
+		return errors.New(common.RedEnvelopeTrustLevelTooLow)
+	}
+	if e.MinAccountAgeDays > 0 && user.CreatedAt.After(time.Now().AddDate(0, 0, -e.MinAccountAgeDays)) {
+		return errors.New(common.RedEnvelopeAccountTooNew)
+	}
+	// 付款后发生争议或部分退款的订单仍视为已付款，全额退款的订单不计入
+	if e.PaidMerchantUserID != 0 {
+		var paid int64
+		if err := tx.Model(&Order{}).
+			Where("payer_user_id = ? AND payee_user_id = ? AND type IN ? AND status IN ?",
+				user.ID, e.PaidMerchantUserID,
+				[]OrderType{OrderTypePayment, OrderTypeOnline},
+				[]OrderStatus{OrderStatusSuccess, OrderStatusRefused, OrderStatusDisputing, OrderStatusPartialRefund}).
+			Count(&paid).Error; err != nil {
+			return err
+		}
+		if paid == 0 {
+			return errors.New(common.RedEnvelopeMerchantNotPaid)
+		}
+	}
+	return nil
+}
+
 // RedEnvelope 红包
 type RedEnvelope struct {
-	ID                  uint64            `json:"id,string" gorm:"primaryKey"`
-	CreatorID           uint64            `json:"creator_id,string" gorm:"index;not null"`
-	CreatorUsername     string            `json:"creator_username" gorm:"-:migration;->"`
-	CreatorAvatarURL    string            `json:"creator_avatar_url" gorm:"-:migration;->"`
-	Type                RedEnvelopeType   `json:"type" gorm:"type:varchar(20);not null"`
-	TotalAmount         decimal.Decimal   `json:"total_amount" gorm:"type:numeric(20,2);not null"`
-	RemainingAmount     decimal.Decimal   `json:"remaining_amount" gorm:"type:numeric(20,2);not null"`
-	TotalCount          int               `json:"total_count" gorm:"not null"`
-	RemainingCount      int               `json:"remaining_count" gorm:"not null"`
-	Greeting            string            `json:"greeting" gorm:"size:100"`
-	Status              RedEnvelopeStatus `json:"status" gorm:"type:varchar(20);not null"`
-	CoverUploadID       *uint64           `json:"cover_upload_id,string,omitempty" gorm:"index"`
-	HeterotypicUploadID *uint64           `json:"heterotypic_upload_id,string,omitempty" gorm:"index"`
-	ExpiresAt           time.Time         `json:"expires_at" gorm:"not null;index"`
-	CreatedAt           time.Time         `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt           time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+	ID                  uint64                 `json:"id,string" gorm:"primaryKey"`
+	CreatorID           uint64                 `json:"creator_id,string" gorm:"index;not null"`
+	CreatorUsername     string                 `json:"creator_username" gorm:"-:migration;->"`
+	CreatorAvatarURL    string                 `json:"creator_avatar_url" gorm:"-:migration;->"`
+	Type                RedEnvelopeType        `json:"type" gorm:"type:varchar(20);not null"`
+	TotalAmount         decimal.Decimal        `json:"total_amount" gorm:"type:numeric(20,2);not null"`
+	RemainingAmount     decimal.Decimal        `json:"remaining_amount" gorm:"type:numeric(20,2);not null"`
+	TotalCount          int                    `json:"total_count" gorm:"not null"`
+	RemainingCount      int                    `json:"remaining_count" gorm:"not null"`
+	Greeting            string                 `json:"greeting" gorm:"size:100"`
+	HasPassphrase       bool                   `json:"has_passphrase" gorm:"not null;default:false"`
+	PassphraseHash      string                 `json:"-" gorm:"size:60"`
+	Eligibility         RedEnvelopeEligibility `json:"eligibility" gorm:"type:jsonb"`
+	ServerSeed          string                 `json:"-" gorm:"size:64"`
+	ServerSeedHash      string                 `json:"server_seed_hash" gorm:"size:64"`
+	ClientID            string                 `json:"client_id,omitempty" gorm:"size:64;index"`
+	MerchantOrderNo     *string                `json:"out_trade_no,omitempty" gorm:"size:64"`
+	NotifyURL           string                 `json:"-" gorm:"size:100"`
+	NotifyPending       bool                   `json:"-" gorm:"not null;default:false;index"`
+	Status              RedEnvelopeStatus      `json:"status" gorm:"type:varchar(20);not null"`
+	CoverUploadID       *uint64                `json:"cover_upload_id,string,omitempty" gorm:"index"`
+	HeterotypicUploadID *uint64                `json:"heterotypic_upload_id,string,omitempty" gorm:"index"`
+	OpensAt             *time.Time             `json:"opens_at"`
+	ExpiresAt           time.Time              `json:"expires_at" gorm:"not null;index"`
+	CreatedAt           time.Time              `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time              `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RedactEligibility 非创建者查看时隐藏指定领取用户名单，领取资格以 eligible 字段告知
+func (r *RedEnvelope) RedactEligibility(viewer *User) {
+	if viewer == nil || viewer.ID != r.CreatorID {
+		r.Eligibility.Usernames = nil
+	}
+}
+
+// ResolveStatus 定时红包到达开启时间后按 active 返回，数据库中的状态在首次领取落库前仍为 scheduled
+func (r *RedEnvelope) ResolveStatus(now time.Time) {
+	if r.Status == RedEnvelopeStatusScheduled && r.OpensAt != nil && !now.Before(*r.OpensAt) {
+		r.Status = RedEnvelopeStatusActive
+	}
+}
+
+// SeedRevealed 红包领完或过期后公开服务端种子，供领取者校验金额
+func (r *RedEnvelope) SeedRevealed() bool {
+	return r.ServerSeed != "" && (r.Status == RedEnvelopeStatusFinished || r.Status == RedEnvelopeStatusExpired)
 }
 
 // RedEnvelopeClaim 红包领取记录
@@ -65,5 +173,6 @@ type RedEnvelopeClaim struct {
 	Username      string          `json:"username" gorm:"-:migration;->"`
 	AvatarURL     string          `json:"avatar_url" gorm:"-:migration;->"`
 	Amount        decimal.Decimal `json:"amount" gorm:"type:numeric(20,2);not null"`
+	Seq           int             `json:"seq" gorm:"not null;default:0"`
 	ClaimedAt     time.Time       `json:"claimed_at" gorm:"autoCreateTime"`
 }
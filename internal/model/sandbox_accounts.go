@@ -0,0 +1,42 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"time"
+
+	"github.com/linux-do/credit/internal/db/idgen"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SandboxAccount 沙箱账户，每个测试模式 API Key 一份模拟余额，不会影响 users 表
+type SandboxAccount struct {
+	ID               uint64          `json:"id,string" gorm:"primaryKey"`
+	MerchantAPIKeyID uint64          `json:"merchant_api_key_id,string" gorm:"uniqueIndex;not null"`
+	BuyerBalance     decimal.Decimal `json:"buyer_balance" gorm:"type:numeric(20,2);not null;default:0"`
+	MerchantBalance  decimal.Decimal `json:"merchant_balance" gorm:"type:numeric(20,2);not null;default:0"`
+	CreatedAt        time.Time       `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time       `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (s *SandboxAccount) BeforeCreate(*gorm.DB) error {
+	if s.ID == 0 {
+		s.ID = idgen.NextUint64ID()
+	}
+	return nil
+}
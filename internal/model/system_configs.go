@@ -21,7 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -32,21 +34,41 @@ import (
 
 // 配置键常量 - 所有系统配置的 key 定义
 const (
-	ConfigKeyMerchantOrderExpireMinutes = "merchant_order_expire_minutes" // 商家订单过期时间（分钟）
-	ConfigKeyWebsiteOrderExpireMinutes  = "website_order_expire_minutes"  // 网站订单过期时间（分钟）
-	ConfigKeyDisputeTimeWindowHours     = "dispute_time_window_hours"     // 商家争议时间窗口（小时）
-	ConfigKeyNewUserInitialCredit       = "new_user_initial_credit"       // 新用户注册初始积分
-	ConfigKeyNewUserProtectionDays      = "new_user_protection_days"      // 新用户保护期天数（期内不扣分）
-	ConfigKeyLeaderboardCacheTTLSeconds = "leaderboard_cache_ttl_seconds" // 排行榜缓存过期时间（秒）
-	ConfigKeyRedEnvelopeEnabled         = "red_envelope_enabled"          // 红包功能是否启用（1启用，0禁用）
-	ConfigKeyRedEnvelopeMaxAmount       = "red_envelope_max_amount"       // 单个红包的最大积分上限
-	ConfigKeyRedEnvelopeDailyLimit      = "red_envelope_daily_limit"      // 每日发红包的个数限制
-	ConfigKeyRedEnvelopeFeeRate         = "red_envelope_fee_rate"         // 红包手续费率（0-1之间的小数，0表示不收费）
-	ConfigKeyRedEnvelopeMaxRecipients   = "red_envelope_max_recipients"   // 每个红包的最大可领取人数上限
-	ConfigKeyUserBalanceStatsCacheTTL   = "user_balance_stats_cache_ttl"  // 用户余额统计缓存过期时间（秒)
-	ConfigKeyUploadAllowedExtensions    = "upload_allowed_extensions"     // 允许上传的文件扩展名，逗号分隔
-	ConfigKeySettlementDelayDaysMin     = "settlement_delay_days_min"     // 商户收款延迟到账最小天数（0表示即时到账）
-	ConfigKeySettlementDelayDaysMax     = "settlement_delay_days_max"     // 商户收款延迟到账最大天数（实际天数在min~max随机）
+	ConfigKeyMerchantOrderExpireMinutes      = "merchant_order_expire_minutes"       // 商家订单过期时间（分钟）
+	ConfigKeyWebsiteOrderExpireMinutes       = "website_order_expire_minutes"        // 网站订单过期时间（分钟）
+	ConfigKeyDisputeTimeWindowHours          = "dispute_time_window_hours"           // 商家争议时间窗口（小时）
+	ConfigKeyDisputeAppealWindowHours        = "dispute_appeal_window_hours"         // 商家拒绝后买家申诉仲裁的时间窗口（小时）
+	ConfigKeyDisputeReminderHours            = "dispute_reminder_hours"              // 争议处理截止前发送提醒的时间点（小时），逗号分隔
+	ConfigKeyNewUserInitialCredit            = "new_user_initial_credit"             // 新用户注册初始积分
+	ConfigKeyNewUserProtectionDays           = "new_user_protection_days"            // 新用户保护期天数（期内不扣分）
+	ConfigKeyLeaderboardCacheTTLSeconds      = "leaderboard_cache_ttl_seconds"       // 排行榜缓存过期时间（秒）
+	ConfigKeyRedEnvelopeEnabled              = "red_envelope_enabled"                // 红包功能是否启用（1启用，0禁用）
+	ConfigKeyRedEnvelopeMaxAmount            = "red_envelope_max_amount"             // 单个红包的最大积分上限
+	ConfigKeyRedEnvelopeDailyLimit           = "red_envelope_daily_limit"            // 每日发红包的个数限制
+	ConfigKeyRedEnvelopeFeeRate              = "red_envelope_fee_rate"               // 红包手续费率（0-1之间的小数，0表示不收费）
+	ConfigKeyRedEnvelopeMaxRecipients        = "red_envelope_max_recipients"         // 每个红包的最大可领取人数上限
+	ConfigKeyUserBalanceStatsCacheTTL        = "user_balance_stats_cache_ttl"        // 用户余额统计缓存过期时间（秒)
+	ConfigKeyUploadAllowedExtensions         = "upload_allowed_extensions"           // 允许上传的文件扩展名，逗号分隔
+	ConfigKeySettlementDelayDaysMin          = "settlement_delay_days_min"           // 商户收款延迟到账最小天数（0表示即时到账）
+	ConfigKeySettlementDelayDaysMax          = "settlement_delay_days_max"           // 商户收款延迟到账最大天数（实际天数在min~max随机）
+	ConfigKeyRateLimitPaySubmit              = "rate_limit_pay_submit"               // 商户创建订单限流（次数/秒数，次数为0表示不限流）
+	ConfigKeyRateLimitPaySubmitIP            = "rate_limit_pay_submit_ip"            // 商户创建订单认证前按 IP 限流（次数/秒数）
+	ConfigKeyRateLimitMerchantAPI            = "rate_limit_merchant_api"             // 商户查询订单与退款限流（次数/秒数）
+	ConfigKeyRateLimitMerchantAPIIP          = "rate_limit_merchant_api_ip"          // 商户查询订单与退款认证前按 IP 限流（次数/秒数）
+	ConfigKeyRateLimitDistribute             = "rate_limit_distribute"               // 商户分发限流（次数/秒数）
+	ConfigKeyRateLimitDistributeIP           = "rate_limit_distribute_ip"            // 商户分发认证前按 IP 限流（次数/秒数）
+	ConfigKeyRateLimitTransfer               = "rate_limit_transfer"                 // 用户转账限流（次数/秒数）
+	ConfigKeyRateLimitRedEnvelopeClaim       = "rate_limit_red_envelope_claim"       // 领取红包限流（次数/秒数）
+	ConfigKeySandboxInitialBalance           = "sandbox_initial_balance"             // 沙箱账户初始模拟余额
+	ConfigKeyMerchantOrderExpireMinMinutes   = "merchant_order_expire_min_minutes"   // 商户自定义订单过期时间下限（分钟）
+	ConfigKeyMerchantOrderExpireMaxMinutes   = "merchant_order_expire_max_minutes"   // 商户自定义订单过期时间上限（分钟）
+	ConfigKeyMerchantRiskWindowDays          = "merchant_risk_window_days"           // 商户风控统计的滚动窗口（天），逗号分隔
+	ConfigKeyMerchantRiskMinOrders           = "merchant_risk_min_orders"            // 窗口内订单数达到该值才参与风控判定
+	ConfigKeyMerchantRiskDisputeRate         = "merchant_risk_dispute_rate"          // 商户争议率阈值（0-1之间的小数，0表示不判定）
+	ConfigKeyMerchantRiskRefundRate          = "merchant_risk_refund_rate"           // 商户退款率阈值（0-1之间的小数，0表示不判定）
+	ConfigKeyMerchantRiskSuspendRate         = "merchant_risk_suspend_rate"          // API Key 争议率或退款率达到该值时暂停使用（0表示不暂停）
+	ConfigKeyMerchantRiskSettlementDelayDays = "merchant_risk_settlement_delay_days" // 超过阈值的商户收款延迟到账天数（0表示不延长）
+	ConfigKeyMerchantRiskFeeRate             = "merchant_risk_fee_rate"              // 超过阈值的商户手续费率（0表示不调整）
 )
 
 const (
@@ -132,8 +154,16 @@ func GetBoolByKey(ctx context.Context, key string) (bool, error) {
 const defaultHoldDays = 7
 
 func GetRandomHoldDays(ctx context.Context) int {
+	holdDaysMin, holdDaysMax := getHoldDaysRange(ctx)
+	if holdDaysMin == holdDaysMax {
+		return holdDaysMin
+	}
+	return holdDaysMin + rand.Intn(holdDaysMax-holdDaysMin+1)
+}
+
+// getHoldDaysRange 获取延迟到账天数的取值范围，配置缺失或非法时使用默认天数
+func getHoldDaysRange(ctx context.Context) (int, int) {
 	// get config
-	holdDays := defaultHoldDays
 	holdDaysMin, errMin := GetIntByKey(ctx, ConfigKeySettlementDelayDaysMin)
 	if errMin != nil || holdDaysMin <= 0 {
 		holdDaysMin = defaultHoldDays
@@ -143,15 +173,83 @@ func GetRandomHoldDays(ctx context.Context) int {
 		holdDaysMax = defaultHoldDays
 	}
 	// check config
-	if holdDaysMin == holdDaysMax {
-		return holdDaysMin
-	}
 	if holdDaysMin > holdDaysMax {
-		return holdDays
+		return defaultHoldDays, defaultHoldDays
 	}
-	return holdDaysMin + rand.Intn(holdDaysMax-holdDaysMin+1)
+	return holdDaysMin, holdDaysMax
+}
+
+const (
+	defaultMerchantOrderExpireMinMinutes = 1
+	defaultMerchantOrderExpireMaxMinutes = 7 * 24 * 60
+)
+
+// ClampMerchantOrderExpireMinutes 将商户指定的订单过期时间限制在配置的上下限内
+func ClampMerchantOrderExpireMinutes(ctx context.Context, minutes int) int {
+	minMinutes, errMin := GetIntByKey(ctx, ConfigKeyMerchantOrderExpireMinMinutes)
+	if errMin != nil || minMinutes <= 0 {
+		minMinutes = defaultMerchantOrderExpireMinMinutes
+	}
+	maxMinutes, errMax := GetIntByKey(ctx, ConfigKeyMerchantOrderExpireMaxMinutes)
+	if errMax != nil || maxMinutes < minMinutes {
+		maxMinutes = max(defaultMerchantOrderExpireMaxMinutes, minMinutes)
+	}
+	return min(max(minutes, minMinutes), maxMinutes)
+}
+
+// defaultDisputeAppealWindowHours 未配置时，商家拒绝后买家可在 72 小时内申诉
+const defaultDisputeAppealWindowHours = 72
+
+// GetDisputeAppealWindowHours 获取商家拒绝后买家申诉仲裁的时间窗口（小时），未配置或非法时使用默认值
+func GetDisputeAppealWindowHours(ctx context.Context) int {
+	hours, err := GetIntByKey(ctx, ConfigKeyDisputeAppealWindowHours)
+	if err != nil || hours <= 0 {
+		return defaultDisputeAppealWindowHours
+	}
+	return hours
+}
+
+// defaultDisputeReminderHours 未配置时，截止前 24 小时与 1 小时各提醒一次
+var defaultDisputeReminderHours = []int{24, 1}
+
+// GetDisputeReminderHours 获取争议截止前的提醒时间点（小时），按距离截止时间从远到近排列
+func GetDisputeReminderHours(ctx context.Context) []int {
+	hours, err := GetIntListByKey(ctx, ConfigKeyDisputeReminderHours)
+	if err != nil {
+		return defaultDisputeReminderHours
+	}
+	slices.Reverse(hours)
+	return hours
+}
+
+// GetIntListByKey 通过 key 查询逗号分隔的正整数配置，忽略非法值并去重后升序返回
+func GetIntListByKey(ctx context.Context, key string) ([]int, error) {
+	var sc SystemConfig
+	if err := sc.GetByKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	var values []int
+	for _, item := range strings.Split(sc.Value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || v <= 0 || slices.Contains(values, v) {
+			continue
+		}
+		values = append(values, v)
+	}
+	slices.Sort(values)
+	return values, nil
 }
 
 func GetRandomSettleAt(ctx context.Context) time.Time {
 	return time.Now().AddDate(0, 0, GetRandomHoldDays(ctx))
 }
+
+// GetPayeeSettleAt 计算收款方的到账时间，被风控延长到账的商户至少比常规最长延迟多一天，避免处置后反而提前到账
+func GetPayeeSettleAt(ctx context.Context, riskDelayDays int) time.Time {
+	if riskDelayDays > 0 {
+		_, holdDaysMax := getHoldDaysRange(ctx)
+		return time.Now().AddDate(0, 0, max(riskDelayDays, holdDaysMax+1))
+	}
+	return GetRandomSettleAt(ctx)
+}
@@ -33,6 +33,7 @@ const (
 const (
 	UploadTypeCover       = "cover"       // 红包背景封面
 	UploadTypeHeterotypic = "heterotypic" // 红包异形装饰
+	UploadTypeEvidence    = "evidence"    // 争议凭证，仅争议双方与管理员可访问
 )
 
 // Upload 上传文件记录
@@ -41,7 +42,7 @@ type Upload struct {
 	UserID    uint64       `json:"user_id,string" gorm:"index;not null"`
 	FilePath  string       `json:"file_path" gorm:"size:500;not null;uniqueIndex"` // 文件路径
 	FileSize  int64        `json:"file_size" gorm:"not null"`                      // 文件大小（字节）
-	Type      string       `json:"type" gorm:"column:type;size:50;not null;index"` // 类型 (cover, heterotypic)
+	Type      string       `json:"type" gorm:"column:type;size:50;not null;index"` // 类型 (cover, heterotypic, evidence)
 	Status    UploadStatus `json:"status" gorm:"type:varchar(20);not null"`        // 状态
 	CreatedAt time.Time    `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
@@ -52,6 +52,17 @@ func (upc *UserPayConfig) GetByPayScore(tx *gorm.DB, payScore int64) error {
 		First(upc).Error
 }
 
+// GetByMerchant 查询商户的支付配置，商户被风控提高手续费率时以较高费率为准
+func (upc *UserPayConfig) GetByMerchant(tx *gorm.DB, merchant *User) error {
+	if err := upc.GetByPayScore(tx, merchant.PayScore); err != nil {
+		return err
+	}
+	if merchant.RiskFeeRate.GreaterThan(upc.FeeRate) {
+		upc.FeeRate = merchant.RiskFeeRate
+	}
+	return nil
+}
+
 // GetByID 通过 ID 查询支付配置
 func (upc *UserPayConfig) GetByID(tx *gorm.DB, id uint64) error {
 	return tx.Where("id = ?", id).First(upc).Error
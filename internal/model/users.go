@@ -106,11 +106,14 @@ type User struct {
 	CommunityBalance decimal.Decimal `json:"community_balance" gorm:"type:numeric(20,2);default:0"`
 	AvailableBalance decimal.Decimal `json:"available_balance" gorm:"type:numeric(20,2);default:0;index:idx_users_active_bal_id,priority:2"`
 	PendingBalance   decimal.Decimal `json:"pending_balance" gorm:"type:numeric(20,2);default:0"`
-	IsActive         bool            `json:"is_active" gorm:"default:true;index:idx_users_active_bal_id,priority:1"`
-	IsAdmin          bool            `json:"is_admin" gorm:"default:false"`
-	LastLoginAt      time.Time       `json:"last_login_at" gorm:"index"`
-	CreatedAt        time.Time       `json:"created_at" gorm:"autoCreateTime;index"`
-	UpdatedAt        time.Time       `json:"updated_at" gorm:"autoUpdateTime;index"`
+	// 风控处置：争议或退款率过高时延长到账天数、提高手续费率，0 表示未处置
+	RiskSettlementDelayDays int             `json:"risk_settlement_delay_days" gorm:"default:0"`
+	RiskFeeRate             decimal.Decimal `json:"risk_fee_rate" gorm:"type:numeric(3,2);default:0"`
+	IsActive                bool            `json:"is_active" gorm:"default:true;index:idx_users_active_bal_id,priority:1"`
+	IsAdmin                 bool            `json:"is_admin" gorm:"default:false"`
+	LastLoginAt             time.Time       `json:"last_login_at" gorm:"index"`
+	CreatedAt               time.Time       `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt               time.Time       `json:"updated_at" gorm:"autoUpdateTime;index"`
 }
 
 func (u *User) GetByID(tx *gorm.DB, id uint64) error {
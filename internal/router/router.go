@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/linux-do/credit/internal/apps/admin"
+	admin_dispute "github.com/linux-do/credit/internal/apps/admin/dispute"
 	admin_task "github.com/linux-do/credit/internal/apps/admin/task"
 	admin_user "github.com/linux-do/credit/internal/apps/admin/user"
 	publicconfig "github.com/linux-do/credit/internal/apps/config"
@@ -35,17 +36,23 @@ import (
 	"github.com/linux-do/credit/internal/apps/health"
 	"github.com/linux-do/credit/internal/apps/merchant/api_key"
 	"github.com/linux-do/credit/internal/apps/merchant/link"
+	"github.com/linux-do/credit/internal/apps/merchant/member"
+	"github.com/linux-do/credit/internal/apps/merchant/sandbox"
+	"github.com/linux-do/credit/internal/apps/merchant/statement"
+	"github.com/linux-do/credit/internal/apps/ratelimit"
 	"github.com/linux-do/credit/internal/apps/redenvelope"
 	"github.com/linux-do/credit/internal/apps/upload"
 	"github.com/linux-do/credit/internal/listener"
 	"github.com/linux-do/credit/internal/util"
 
 	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/apps/qrcode"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/redis"
 	"github.com/gin-gonic/gin"
 	_ "github.com/linux-do/credit/docs"
+	"github.com/linux-do/credit/internal/apps/admin/merchant_risk"
 	"github.com/linux-do/credit/internal/apps/admin/system_config"
 	"github.com/linux-do/credit/internal/apps/admin/user_pay_config"
 	"github.com/linux-do/credit/internal/apps/dashboard"
@@ -54,6 +61,7 @@ import (
 	"github.com/linux-do/credit/internal/apps/order"
 	"github.com/linux-do/credit/internal/apps/user"
 	"github.com/linux-do/credit/internal/config"
+	"github.com/linux-do/credit/internal/model"
 	"github.com/linux-do/credit/internal/otel_trace"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -70,6 +78,11 @@ func Serve() {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	// 受信任代理，保证 ClientIP 在反向代理后解析正确
+	if err := r.SetTrustedProxies(config.Config.App.TrustedProxies); err != nil {
+		log.Fatalf("[API] set trusted proxies failed: %v\n", err)
+	}
+
 	cfg := config.Config.Redis
 	addrs := cfg.Addrs
 	sessionAddr := "localhost:6379"
@@ -105,13 +118,21 @@ func Serve() {
 	r.Use(otelgin.Middleware(config.Config.App.AppName), loggerMiddleware())
 
 	// 支付接口
-	r.Match([]string{"GET", "POST"}, "/pay/submit.php", payment.RequireSignatureAuth(), payment.CreateMerchantOrder)
+	r.Match([]string{"GET", "POST"}, "/pay/submit.php", ratelimit.RateLimit(ratelimit.PaySubmitIPPolicy), payment.RequireSignatureAuth(), ratelimit.RateLimit(ratelimit.PaySubmitPolicy), payment.CreateMerchantOrder)
+	// API 模式支付接口
+	r.Match([]string{"GET", "POST"}, "/pay/mapi.php", ratelimit.RateLimit(ratelimit.PaySubmitIPPolicy), payment.RequireSignatureAuth(), ratelimit.RateLimit(ratelimit.PaySubmitPolicy), payment.CreateMerchantOrderAPI)
+	// 订单收银台二维码
+	r.GET("/pay/qrcode.php", ratelimit.RateLimit(ratelimit.MerchantAPIIPPolicy), qrcode.GetOrderQRCode)
 	// 查询订单
-	r.GET("/api.php", payment.QueryMerchantOrder)
+	r.GET("/api.php", ratelimit.RateLimit(ratelimit.MerchantAPIIPPolicy), payment.LoadEPayAPIKey(), ratelimit.RateLimit(ratelimit.MerchantAPIPolicy), payment.QueryMerchantOrder)
 	// 退款接口
-	r.POST("/api.php", payment.RefundMerchantOrder)
+	r.POST("/api.php", ratelimit.RateLimit(ratelimit.MerchantAPIIPPolicy), payment.LoadEPayAPIKey(), ratelimit.RateLimit(ratelimit.MerchantAPIPolicy), payment.RefundMerchantOrder)
 	// 商户分发接口
-	r.POST("/pay/distribute", payment.RequireMerchantAuth(), payment.MerchantDistribute)
+	r.POST("/pay/distribute", ratelimit.RateLimit(ratelimit.DistributeIPPolicy), payment.RequireMerchantAuth(), ratelimit.RateLimit(ratelimit.DistributePolicy), payment.MerchantDistribute)
+	// 商户红包接口
+	r.POST("/pay/redenvelope", ratelimit.RateLimit(ratelimit.DistributeIPPolicy), payment.RequireMerchantAuth(), redenvelope.CheckRedEnvelopeEnabled(), ratelimit.RateLimit(ratelimit.DistributePolicy), redenvelope.MerchantCreate)
+	r.GET("/pay/redenvelope/:id", ratelimit.RateLimit(ratelimit.MerchantAPIIPPolicy), payment.RequireMerchantAuth(), ratelimit.RateLimit(ratelimit.MerchantAPIPolicy), redenvelope.MerchantGetDetail)
+	r.GET("/pay/redenvelope/:id/claims", ratelimit.RateLimit(ratelimit.MerchantAPIIPPolicy), payment.RequireMerchantAuth(), ratelimit.RateLimit(ratelimit.MerchantAPIPolicy), redenvelope.MerchantListClaims)
 
 	// Serve files by ID
 	r.GET("/f/:id", upload.ServeFileByID)
@@ -146,7 +167,7 @@ func Serve() {
 
 			// Dashboard
 			dashboardRouter := apiV1Router.Group("/dashboard")
-			dashboardRouter.Use(oauth.LoginRequired())
+			dashboardRouter.Use(oauth.LoginRequired(), member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionReport))
 			{
 				dashboardRouter.GET("/stats/daily", dashboard.GetDailyStats)
 				dashboardRouter.GET("/stats/top-customers", dashboard.GetTopCustomers)
@@ -169,17 +190,29 @@ func Serve() {
 			{
 				orderRouter.POST("/transactions", order.ListTransactions)
 				orderRouter.POST("/dispute", dispute.CreateDispute)
-				orderRouter.POST("/disputes/merchant", dispute.ListMerchantDisputes)
+				orderRouter.POST("/disputes/merchant", member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionDispute), dispute.ListMerchantDisputes)
 				orderRouter.POST("/disputes", dispute.ListDisputes)
-				orderRouter.POST("/refund-review", dispute.RefundReview)
+				orderRouter.POST("/refund-review", member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionDispute), member.AuditAction(member.AuditActionDisputeRefundReview), dispute.RefundReview)
 				orderRouter.POST("/dispute/close", dispute.CloseDispute)
+				orderRouter.POST("/dispute/appeal", dispute.AppealDispute)
+				orderRouter.POST("/dispute/offer", member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionDispute), member.AuditAction(member.AuditActionDisputeRefundOffer), dispute.RefundOffer)
+				orderRouter.POST("/dispute/offer/accept", dispute.AcceptRefundOffer)
+
+				// 争议沟通，商户成员通过 X-Merchant-ID 代表商户参与
+				disputeThreadRouter := orderRouter.Group("/dispute/:id")
+				disputeThreadRouter.Use(member.RequireMerchantContext())
+				{
+					disputeThreadRouter.GET("/messages", dispute.ListDisputeMessages)
+					disputeThreadRouter.POST("/messages", dispute.CreateDisputeMessage)
+					disputeThreadRouter.GET("/attachments/:uploadId", dispute.GetDisputeAttachment)
+				}
 			}
 
 			// Payment
 			paymentRouter := apiV1Router.Group("/payment")
 			paymentRouter.Use(oauth.LoginRequired())
 			{
-				paymentRouter.POST("/transfer", payment.Transfer)
+				paymentRouter.POST("/transfer", ratelimit.RateLimit(ratelimit.TransferPolicy), payment.Transfer)
 			}
 
 			// Red Envelope
@@ -187,8 +220,9 @@ func Serve() {
 			{
 				redEnvelopeRouter.GET("/covers", oauth.LoginRequired(), upload.ListRedEnvelopeCovers)
 				redEnvelopeRouter.GET("/:id", oauth.LoginRequired(), redenvelope.CheckRedEnvelopeEnabled(), redenvelope.GetDetail)
+				redEnvelopeRouter.GET("/:id/verify", oauth.LoginRequired(), redenvelope.CheckRedEnvelopeEnabled(), redenvelope.Verify)
 				redEnvelopeRouter.POST("/create", oauth.LoginRequired(), redenvelope.CheckRedEnvelopeEnabled(), redenvelope.Create)
-				redEnvelopeRouter.POST("/claim", oauth.LoginRequired(), redenvelope.CheckRedEnvelopeEnabled(), redenvelope.Claim)
+				redEnvelopeRouter.POST("/claim", oauth.LoginRequired(), redenvelope.CheckRedEnvelopeEnabled(), ratelimit.RateLimit(ratelimit.RedEnvelopeClaimPolicy), redenvelope.Claim)
 				redEnvelopeRouter.POST("/list", oauth.LoginRequired(), redenvelope.CheckRedEnvelopeEnabled(), redenvelope.List)
 			}
 
@@ -197,6 +231,7 @@ func Serve() {
 			uploadRouter.Use(oauth.LoginRequired())
 			{
 				uploadRouter.POST("/redenvelope/cover", upload.UploadRedEnvelopeCover)
+				uploadRouter.POST("/dispute/evidence", upload.UploadDisputeEvidence)
 			}
 
 			// Config (public)
@@ -209,33 +244,80 @@ func Serve() {
 			// MerchantAPIKey
 			merchantRouter := apiV1Router.Group("/merchant")
 			{
-				merchantRouter.POST("/api-keys", oauth.LoginRequired(), api_key.CreateAPIKey)
-				merchantRouter.GET("/api-keys", oauth.LoginRequired(), api_key.ListAPIKeys)
+				merchantRouter.POST("/api-keys", oauth.LoginRequired(), member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionAPIKey), member.AuditAction(member.AuditActionAPIKeyCreate), api_key.CreateAPIKey)
+				merchantRouter.GET("/api-keys", oauth.LoginRequired(), member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionAPIKey), api_key.ListAPIKeys)
 
 				apiKeyRouter := merchantRouter.Group("/api-keys/:id")
-				apiKeyRouter.Use(oauth.LoginRequired(), api_key.RequireAPIKey())
+				apiKeyRouter.Use(oauth.LoginRequired(), member.RequireMerchantContext(), api_key.RequireAPIKey())
 				{
-					apiKeyRouter.GET("", api_key.GetAPIKey)
-					apiKeyRouter.PUT("", api_key.UpdateAPIKey)
-					apiKeyRouter.DELETE("", api_key.DeleteAPIKey)
+					apiKeyManageRouter := apiKeyRouter.Group("")
+					apiKeyManageRouter.Use(member.RequirePermission(model.MerchantPermissionAPIKey))
+					{
+						apiKeyManageRouter.GET("", api_key.GetAPIKey)
+						apiKeyManageRouter.PUT("", member.AuditAction(member.AuditActionAPIKeyUpdate), api_key.UpdateAPIKey)
+						apiKeyManageRouter.DELETE("", member.AuditAction(member.AuditActionAPIKeyDelete), api_key.DeleteAPIKey)
+						apiKeyManageRouter.GET("/ip-denials", api_key.ListIPDenials)
+
+						// Sandbox
+						sandboxRouter := apiKeyManageRouter.Group("/sandbox")
+						sandboxRouter.Use(sandbox.RequireSandboxAPIKey())
+						{
+							sandboxRouter.GET("", sandbox.GetSandboxAccount)
+							sandboxRouter.POST("/reset", member.AuditAction(member.AuditActionSandboxReset), sandbox.ResetSandboxAccount)
+							sandboxRouter.POST("/simulate", member.AuditAction(member.AuditActionSandboxSimulate), sandbox.SimulateOrderEvent)
+						}
+					}
 
 					// Payment Links
 					linkRouter := apiKeyRouter.Group("/payment-links")
+					linkRouter.Use(member.RequirePermission(model.MerchantPermissionPaymentLink))
 					{
 						linkRouter.GET("", link.ListPaymentLinks)
-						linkRouter.POST("", link.CreatePaymentLink)
-						linkRouter.PUT("/:linkId", link.UpdatePaymentLink)
-						linkRouter.DELETE("/:linkId", link.DeletePaymentLink)
+						linkRouter.POST("", member.AuditAction(member.AuditActionPaymentLinkCreate), link.CreatePaymentLink)
+						linkRouter.PUT("/:linkId", member.AuditAction(member.AuditActionPaymentLinkUpdate), link.UpdatePaymentLink)
+						linkRouter.DELETE("/:linkId", member.AuditAction(member.AuditActionPaymentLinkDelete), link.DeletePaymentLink)
+						linkRouter.PUT("/:linkId/pause", member.AuditAction(member.AuditActionPaymentLinkPause), link.PausePaymentLink)
+						linkRouter.GET("/:linkId/stats", link.GetPaymentLinkStats)
+					}
+
+					// Statements
+					statementRouter := apiKeyRouter.Group("/statements")
+					statementRouter.Use(member.RequirePermission(model.MerchantPermissionReport))
+					{
+						statementRouter.GET("", statement.ListStatements)
+						statementRouter.GET("/:statementId/download", statement.DownloadStatement)
 					}
 				}
 
+				// Members
+				memberRouter := merchantRouter.Group("/members")
+				memberRouter.Use(oauth.LoginRequired(), member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionMember))
+				{
+					memberRouter.GET("", member.ListMembers)
+					memberRouter.POST("", member.AuditAction(member.AuditActionMemberInvite), member.InviteMember)
+					memberRouter.PUT("/:memberId", member.AuditAction(member.AuditActionMemberUpdate), member.UpdateMember)
+					memberRouter.DELETE("/:memberId", member.AuditAction(member.AuditActionMemberRemove), member.RemoveMember)
+				}
+				merchantRouter.GET("/audit-logs", oauth.LoginRequired(), member.RequireMerchantContext(), member.RequirePermission(model.MerchantPermissionMember), member.ListAuditLogs)
+
+				// Memberships
+				membershipRouter := merchantRouter.Group("/memberships")
+				membershipRouter.Use(oauth.LoginRequired())
+				{
+					membershipRouter.GET("", member.ListMemberships)
+					membershipRouter.POST("/:memberId/accept", member.AcceptInvitation)
+					membershipRouter.DELETE("/:memberId", member.LeaveMerchant)
+				}
+
 				merchantRouter.GET("/payment-links/:token", oauth.LoginRequired(), link.GetPaymentLinkByToken)
+				merchantRouter.GET("/payment-links/:token/qrcode", oauth.LoginRequired(), link.RequirePaymentLink(), qrcode.GetPaymentLinkQRCode)
 				merchantRouter.POST("/payment-links/pay", oauth.LoginRequired(), link.PayByLink)
 
 				// MerchantAPIKey Payment
 				MerchantPaymentRouter := merchantRouter.Group("/payment")
 				{
 					MerchantPaymentRouter.GET("/order", oauth.LoginRequired(), payment.GetPaymentPageDetails)
+					MerchantPaymentRouter.GET("/order/qrcode", oauth.LoginRequired(), qrcode.GetOrderQRCode)
 					MerchantPaymentRouter.POST("", oauth.LoginRequired(), payment.PayMerchantOrder)
 				}
 			}
@@ -252,6 +334,14 @@ func Serve() {
 				adminRouter.GET("/users", admin_user.ListUsers)
 				adminRouter.PUT("/users/:id/status", admin_user.UpdateUserStatus)
 
+				// Dispute Arbitration
+				adminRouter.GET("/disputes", admin_dispute.ListDisputes)
+				adminRouter.POST("/disputes/:id/ruling", admin_dispute.RuleDispute)
+
+				// Merchant Risk
+				adminRouter.GET("/merchant-risk/logs", merchant_risk.ListRiskLogs)
+				adminRouter.POST("/merchant-risk/lift", merchant_risk.LiftRiskPenalty)
+
 				// System Config
 				adminRouter.POST("/system-configs", system_config.CreateSystemConfig)
 				adminRouter.GET("/system-configs", system_config.ListSystemConfigs)
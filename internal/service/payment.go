@@ -140,12 +140,13 @@ func GetTodayUsedAmount(db *gorm.DB, userID uint64) (decimal.Decimal, error) {
 
 	var total decimal.Decimal
 	err := db.Model(&model.Order{}).
-		Where("payer_user_id = ? AND status IN ? AND type IN ? AND trade_time >= ? AND trade_time < ?",
+		Where("payer_user_id = ? AND status IN ? AND type IN ? AND trade_time >= ? AND trade_time < ? AND is_sandbox = ?",
 			userID,
-			[]model.OrderStatus{model.OrderStatusSuccess, model.OrderStatusDisputing, model.OrderStatusRefused},
+			[]model.OrderStatus{model.OrderStatusSuccess, model.OrderStatusDisputing, model.OrderStatusRefused, model.OrderStatusPartialRefund},
 			[]model.OrderType{model.OrderTypePayment, model.OrderTypeOnline, model.OrderTypeDistribute, model.OrderTypeTransfer},
 			todayStart,
-			todayEnd).
+			todayEnd,
+			false).
 		Select("COALESCE(SUM(amount), 0)").
 		Scan(&total).Error
 
@@ -174,11 +175,17 @@ func ValidateTestModePayment(currentUserID, merchantUserID uint64, isTestMode bo
 	return nil
 }
 
-// EnqueueMerchantNotify 下发商户回调任务
+// EnqueueMerchantNotify 下发商户支付成功回调任务
 func EnqueueMerchantNotify(orderID uint64, clientID string) error {
+	return EnqueueMerchantEventNotify(orderID, clientID, common.TradeStatusSuccess)
+}
+
+// EnqueueMerchantEventNotify 下发指定交易状态的商户回调任务
+func EnqueueMerchantEventNotify(orderID uint64, clientID string, tradeStatus string) error {
 	notifyPayload, _ := json.Marshal(map[string]interface{}{
-		"order_id":  orderID,
-		"client_id": clientID,
+		"order_id":     orderID,
+		"client_id":    clientID,
+		"trade_status": tradeStatus,
 	})
 	if _, err := scheduler.AsynqClient.Enqueue(
 		asynq.NewTask(task.MerchantPaymentNotifyTask, notifyPayload),
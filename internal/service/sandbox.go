@@ -0,0 +1,151 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+
+	"github.com/linux-do/credit/internal/common"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultSandboxInitialBalance = 10000
+
+// GetSandboxInitialBalance 获取沙箱账户初始模拟余额
+func GetSandboxInitialBalance(tx *gorm.DB) decimal.Decimal {
+	balance, err := model.GetDecimalByKey(tx.Statement.Context, model.ConfigKeySandboxInitialBalance, 2)
+	if err != nil || balance.IsNegative() {
+		return decimal.NewFromInt(defaultSandboxInitialBalance)
+	}
+	return balance
+}
+
+// GetOrCreateSandboxAccount 获取 API Key 对应的沙箱账户，不存在时按初始余额创建
+func GetOrCreateSandboxAccount(tx *gorm.DB, apiKeyID uint64) (*model.SandboxAccount, error) {
+	account := model.SandboxAccount{
+		MerchantAPIKeyID: apiKeyID,
+		BuyerBalance:     GetSandboxInitialBalance(tx),
+		MerchantBalance:  decimal.Zero,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("merchant_api_key_id = ?", apiKeyID).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ApplySandboxPayment 沙箱支付：扣减模拟买家余额，按费率增加模拟商户净额，并记录本次收取的手续费供退款时扣回
+func ApplySandboxPayment(tx *gorm.DB, apiKeyID uint64, order *model.Order, feeRate decimal.Decimal) error {
+	if _, err := GetOrCreateSandboxAccount(tx, apiKeyID); err != nil {
+		return err
+	}
+
+	fee, merchantAmount, _ := CalculateFee(order.Amount, feeRate)
+	result := tx.Model(&model.SandboxAccount{}).
+		Where("merchant_api_key_id = ? AND buyer_balance >= ?", apiKeyID, order.Amount).
+		UpdateColumns(map[string]interface{}{
+			"buyer_balance":    gorm.Expr("buyer_balance - ?", order.Amount),
+			"merchant_balance": gorm.Expr("merchant_balance + ?", merchantAmount),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(common.InsufficientBalance)
+	}
+
+	return tx.Create(&model.OrderSplit{
+		OrderID:     order.ID,
+		PayeeUserID: order.PayeeUserID,
+		Amount:      order.Amount,
+		Fee:         fee,
+		NetAmount:   merchantAmount,
+	}).Error
+}
+
+// ApplySandboxRefund 沙箱退款：扣回支付时入账的模拟商户净额，向模拟买家退回订单金额
+// 支付时未记录手续费的历史订单按当前费率计算商户净额
+func ApplySandboxRefund(tx *gorm.DB, apiKeyID uint64, order *model.Order, feeRate decimal.Decimal) error {
+	if _, err := GetOrCreateSandboxAccount(tx, apiKeyID); err != nil {
+		return err
+	}
+
+	var split model.OrderSplit
+	var merchantAmount decimal.Decimal
+	if err := tx.Where("order_id = ?", order.ID).First(&split).Error; err == nil {
+		merchantAmount = split.NetAmount
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		_, merchantAmount, _ = CalculateFee(order.Amount, feeRate)
+	} else {
+		return err
+	}
+
+	result := tx.Model(&model.SandboxAccount{}).
+		Where("merchant_api_key_id = ? AND merchant_balance >= ?", apiKeyID, merchantAmount).
+		UpdateColumns(map[string]interface{}{
+			"buyer_balance":    gorm.Expr("buyer_balance + ?", order.Amount),
+			"merchant_balance": gorm.Expr("merchant_balance - ?", merchantAmount),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(common.InsufficientBalance)
+	}
+	return nil
+}
+
+// ApplySandboxDistribute 沙箱分发：扣减模拟商户余额
+func ApplySandboxDistribute(tx *gorm.DB, apiKeyID uint64, amount decimal.Decimal) error {
+	if _, err := GetOrCreateSandboxAccount(tx, apiKeyID); err != nil {
+		return err
+	}
+
+	result := tx.Model(&model.SandboxAccount{}).
+		Where("merchant_api_key_id = ? AND merchant_balance >= ?", apiKeyID, amount).
+		UpdateColumn("merchant_balance", gorm.Expr("merchant_balance - ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(common.InsufficientBalance)
+	}
+	return nil
+}
+
+// ResetSandboxAccount 重置沙箱账户余额
+func ResetSandboxAccount(tx *gorm.DB, apiKeyID uint64) (*model.SandboxAccount, error) {
+	account, err := GetOrCreateSandboxAccount(tx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	account.BuyerBalance = GetSandboxInitialBalance(tx)
+	account.MerchantBalance = decimal.Zero
+	if err := tx.Model(account).UpdateColumns(map[string]interface{}{
+		"buyer_balance":    account.BuyerBalance,
+		"merchant_balance": account.MerchantBalance,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/linux-do/credit/internal/common"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ValidateSplitRules 校验分账规则及收款人，amount 非空时同时校验分账金额不超过订单金额
+func ValidateSplitRules(tx *gorm.DB, ownerID uint64, rules model.SplitRules, amount *decimal.Decimal) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	if err := rules.Validate(ownerID); err != nil {
+		return err
+	}
+
+	if amount != nil {
+		if _, err := rules.Allocate(*amount, ownerID); err != nil {
+			return err
+		}
+	}
+
+	payeeIDs := make([]uint64, 0, len(rules))
+	for _, rule := range rules {
+		payeeIDs = append(payeeIDs, rule.UserID)
+	}
+
+	var count int64
+	if err := tx.Model(&model.User{}).
+		Where("id IN ? AND is_active = ?", payeeIDs, true).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(payeeIDs)) {
+		return errors.New(common.SplitPayeeInvalid)
+	}
+	return nil
+}
+
+// getPayeePayConfig 获取收款方的支付配置，商户所有者直接复用已查询的配置
+func getPayeePayConfig(tx *gorm.DB, payeeUserID uint64, ownerID uint64, ownerPayConfig *model.UserPayConfig) (*model.UserPayConfig, error) {
+	if payeeUserID == ownerID {
+		return ownerPayConfig, nil
+	}
+
+	var payee model.User
+	if err := payee.GetByID(tx, payeeUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New(common.SplitPayeeInvalid)
+		}
+		return nil, err
+	}
+
+	var payConfig model.UserPayConfig
+	if err := payConfig.GetByMerchant(tx, &payee); err != nil {
+		return nil, err
+	}
+	return &payConfig, nil
+}
+
+// getPayeeSettleAt 计算收款方的到账时间，被风控延长到账的收款方按处置天数结算
+func getPayeeSettleAt(tx *gorm.DB, payeeUserID uint64) (time.Time, error) {
+	var riskDelayDays int
+	if err := tx.Model(&model.User{}).
+		Where("id = ?", payeeUserID).
+		Select("risk_settlement_delay_days").
+		Scan(&riskDelayDays).Error; err != nil {
+		return time.Time{}, err
+	}
+	return model.GetPayeeSettleAt(tx.Statement.Context, riskDelayDays), nil
+}
+
+// CreditMerchantPayees 将订单金额按分账规则计入各收款方待结算余额，并为每个收款方创建延迟到账记录
+// 各收款方按自身支付配置计算手续费与积分，未配置分账规则时全部计入商户所有者
+func CreditMerchantPayees(tx *gorm.DB, order *model.Order, ownerPayConfig *model.UserPayConfig, rules model.SplitRules) error {
+	ownerID := order.PayeeUserID
+
+	allocations, err := rules.Allocate(order.Amount, ownerID)
+	if err != nil {
+		return err
+	}
+
+	for _, allocation := range allocations {
+		payConfig, err := getPayeePayConfig(tx, allocation.UserID, ownerID, ownerPayConfig)
+		if err != nil {
+			return err
+		}
+
+		fee, netAmount, _ := CalculateFee(allocation.Amount, payConfig.FeeRate)
+		scoreIncrease := allocation.Amount.Mul(payConfig.ScoreRate).Round(0).IntPart()
+		if err := UpdateBalance(tx, BalanceUpdateOptions{
+			UserID:        allocation.UserID,
+			Amount:        netAmount,
+			Operation:     BalanceAdd,
+			ScoreChange:   scoreIncrease,
+			TotalField:    "total_receive",
+			CheckBalance:  false,
+			AsyncTransfer: true,
+		}); err != nil {
+			return err
+		}
+
+		// 异步到账任务
+		transferAt, err := getPayeeSettleAt(tx, allocation.UserID)
+		if err != nil {
+			return err
+		}
+		orderTransfer := model.OrderTransfer{
+			OrderID:     order.ID,
+			PayeeUserID: allocation.UserID,
+			Amount:      netAmount,
+			Status:      model.OrderTransferStatusPending,
+			TransferAt:  transferAt,
+		}
+		if err := tx.Create(&orderTransfer).Error; err != nil {
+			return err
+		}
+
+		if len(rules) > 0 {
+			orderSplit := model.OrderSplit{
+				OrderID:     order.ID,
+				PayeeUserID: allocation.UserID,
+				Amount:      allocation.Amount,
+				Fee:         fee,
+				NetAmount:   netAmount,
+			}
+			if err := tx.Create(&orderSplit).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReverseMerchantPayeesAmount 按退款金额冲回收款方余额，各收款方按分得比例承担
+// 向下取整到分后的余数由商户所有者承担，所有者未参与分账时单独从其余额扣回
+func ReverseMerchantPayeesAmount(tx *gorm.DB, order *model.Order, ownerPayConfig *model.UserPayConfig, amount decimal.Decimal) error {
+	var splits []model.OrderSplit
+	if err := tx.Where("order_id = ?", order.ID).Find(&splits).Error; err != nil {
+		return err
+	}
+
+	// 未分账订单全部由商户所有者承担
+	if len(splits) == 0 {
+		splits = []model.OrderSplit{{PayeeUserID: order.PayeeUserID, Amount: order.Amount}}
+	}
+
+	shares := make([]decimal.Decimal, len(splits))
+	remainder := amount
+	ownerIndex := -1
+	for i, split := range splits {
+		if split.PayeeUserID == order.PayeeUserID {
+			ownerIndex = i
+		}
+		if amount.Equal(order.Amount) {
+			shares[i] = split.Amount
+		} else {
+			shares[i] = split.Amount.Mul(amount).Div(order.Amount).RoundFloor(2)
+		}
+		remainder = remainder.Sub(shares[i])
+	}
+	if ownerIndex < 0 {
+		splits = append(splits, model.OrderSplit{PayeeUserID: order.PayeeUserID})
+		shares = append(shares, decimal.Zero)
+		ownerIndex = len(splits) - 1
+	}
+	shares[ownerIndex] = shares[ownerIndex].Add(remainder)
+
+	for i, split := range splits {
+		share := shares[i]
+		if share.IsZero() {
+			continue
+		}
+
+		payConfig, err := getPayeePayConfig(tx, split.PayeeUserID, order.PayeeUserID, ownerPayConfig)
+		if err != nil {
+			return err
+		}
+
+		scoreDecrease := share.Mul(payConfig.ScoreRate).Round(0).IntPart()
+		if err := tx.Model(&model.User{}).
+			Where("id = ?", split.PayeeUserID).
+			UpdateColumns(map[string]interface{}{
+				"available_balance": gorm.Expr("available_balance - ?", share),
+				"total_receive":     gorm.Expr("total_receive - ?", share),
+				"pay_score":         gorm.Expr("pay_score - ?", scoreDecrease),
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RefundOrderAmount 按指定金额退款：冲回收款方余额并退回付款方，付款方积分按退款金额扣减
+// 全额退款时归还支付链接库存，部分退款仍视为已售出
+func RefundOrderAmount(tx *gorm.DB, order *model.Order, ownerPayConfig *model.UserPayConfig, amount decimal.Decimal) error {
+	if err := ReverseMerchantPayeesAmount(tx, order, ownerPayConfig, amount); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&model.User{}).
+		Where("id = ?", order.PayerUserID).
+		UpdateColumns(map[string]interface{}{
+			"available_balance": gorm.Expr("available_balance + ?", amount),
+			"total_payment":     gorm.Expr("total_payment - ?", amount),
+			"pay_score":         gorm.Expr("pay_score - ?", amount.Round(0).IntPart()),
+		}).Error; err != nil {
+		return err
+	}
+
+	if amount.GreaterThanOrEqual(order.Amount) {
+		return model.RestorePaymentLinkStock(tx, order)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/linux-do/credit/internal/model"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestRefundOrderAmountRestoresStock 全额退款归还支付链接库存，部分退款不归还，库存不超过总量
+func TestRefundOrderAmountRestoresStock(t *testing.T) {
+	tx, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := tx.AutoMigrate(&model.User{}, &model.OrderSplit{}, &model.MerchantPaymentLink{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	merchant := &model.User{ID: 1, Username: "merchant", SignKey: "k1", AvailableBalance: decimal.NewFromInt(100)}
+	payer := &model.User{ID: 2, Username: "payer", SignKey: "k2"}
+	for _, u := range []*model.User{merchant, payer} {
+		if err := tx.Create(u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	totalLimit, remaining := uint(2), uint(0)
+	link := &model.MerchantPaymentLink{ID: 10, Token: "t", Amount: decimal.NewFromInt(10), ProductName: "p", TotalLimit: &totalLimit, RemainingStock: &remaining}
+	if err := tx.Create(link).Error; err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+
+	payConfig := &model.UserPayConfig{ScoreRate: decimal.Zero}
+	newOrder := func(id uint64) *model.Order {
+		return &model.Order{ID: id, PayerUserID: payer.ID, PayeeUserID: merchant.ID, Amount: decimal.NewFromInt(10), PaymentLinkID: &link.ID}
+	}
+	stock := func() uint {
+		var l model.MerchantPaymentLink
+		if err := tx.First(&l, link.ID).Error; err != nil {
+			t.Fatalf("load link: %v", err)
+		}
+		return *l.RemainingStock
+	}
+
+	steps := []struct {
+		name   string
+		order  *model.Order
+		amount decimal.Decimal
+		want   uint
+	}{
+		{name: "partial refund", order: newOrder(100), amount: decimal.NewFromInt(4), want: 0},
+		{name: "full refund", order: newOrder(101), amount: decimal.NewFromInt(10), want: 1},
+		{name: "second full refund", order: newOrder(102), amount: decimal.NewFromInt(10), want: 2},
+		{name: "capped at total limit", order: newOrder(103), amount: decimal.NewFromInt(10), want: 2},
+	}
+	for _, step := range steps {
+		if err := RefundOrderAmount(tx, step.order, payConfig, step.amount); err != nil {
+			t.Fatalf("%s: refund: %v", step.name, err)
+		}
+		if got := stock(); got != step.want {
+			t.Fatalf("%s: remaining_stock = %d, want %d", step.name, got, step.want)
+		}
+	}
+}
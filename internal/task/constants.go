@@ -21,11 +21,19 @@ const (
 	UpdateSingleUserGamificationScoreTask = "user:gamification:update_single_score_task"
 	AutoRefundExpiredDisputesTask         = "dispute:auto_refund_expired"
 	AutoRefundSingleDisputeTask           = "dispute:auto_refund_single"
+	DisputeDeadlineReminderTask           = "dispute:deadline_reminder"
+	DisputeReminderNotifyTask             = "dispute:reminder_notify"
 	MerchantPaymentNotifyTask             = "payment:merchant_notify"
 	SyncOrdersToClickHouseTask            = "order:sync_to_clickhouse"
 	RefundExpiredRedEnvelopesTask         = "redenvelope:refund_expired"
+	PersistRedEnvelopeClaimTask           = "redenvelope:persist_claim"
+	ReconcileRedEnvelopesTask             = "redenvelope:reconcile"
+	RedEnvelopeMerchantNotifyTask         = "redenvelope:merchant_notify"
 	CleanupUnusedUploadsTask              = "upload:cleanup_unused"
 	SettlePendingPaymentsTask             = "order:settle_pending_payments"
+	GenerateMerchantStatementsTask        = "merchant:generate_daily_statements"
+	MerchantStatementNotifyTask           = "merchant:statement_notify"
+	EvaluateMerchantRiskTask              = "merchant:evaluate_risk"
 )
 
 const (
@@ -40,8 +48,11 @@ const (
 	TaskTypeUserGamification  = "user_gamification"
 	TaskTypeDisputeRefund     = "dispute_auto_refund"
 	TaskTypeRedEnvelopeRefund = "redenvelope_auto_refund"
+	TaskTypeRedEnvelopeClaims = "redenvelope_reconcile"
 	TaskTypeCleanupUploads    = "cleanup_unused_uploads"
 	TaskTypeSettlePending     = "settle_pending_payments"
+	TaskTypeMerchantStatement = "merchant_statements"
+	TaskTypeMerchantRisk      = "merchant_risk"
 )
 
 // TaskMeta 任务元数据
@@ -78,8 +89,8 @@ var DispatchableTasks = []TaskMeta{
 	{
 		Type:         TaskTypeDisputeRefund,
 		AsynqTask:    AutoRefundExpiredDisputesTask,
-		Name:         "争议自动退款",
-		Description:  "处理过期争议的自动退款",
+		Name:         "争议任务补偿",
+		Description:  "为缺少截止时间或逾期未处理的争议补建提醒与自动退款任务",
 		SupportsTime: false,
 		MaxRetry:     5,
 		Queue:        QueueDefault,
@@ -93,6 +104,15 @@ var DispatchableTasks = []TaskMeta{
 		MaxRetry:     5,
 		Queue:        QueueDefault,
 	},
+	{
+		Type:         TaskTypeRedEnvelopeClaims,
+		AsynqTask:    ReconcileRedEnvelopesTask,
+		Name:         "红包领取核对",
+		Description:  "核对红包 Redis 领取池与数据库，补写未落库的领取记录并修复不一致的领取池",
+		SupportsTime: false,
+		MaxRetry:     3,
+		Queue:        QueueDefault,
+	},
 	{
 		Type:         TaskTypeCleanupUploads,
 		AsynqTask:    CleanupUnusedUploadsTask,
@@ -111,6 +131,24 @@ var DispatchableTasks = []TaskMeta{
 		MaxRetry:     5,
 		Queue:        QueueDefault,
 	},
+	{
+		Type:         TaskTypeMerchantStatement,
+		AsynqTask:    GenerateMerchantStatementsTask,
+		Name:         "商户日结对账单",
+		Description:  "生成商户日结对账单，指定开始时间时生成该日对账单，默认生成前一日",
+		SupportsTime: true,
+		MaxRetry:     3,
+		Queue:        QueueDefault,
+	},
+	{
+		Type:         TaskTypeMerchantRisk,
+		AsynqTask:    EvaluateMerchantRiskTask,
+		Name:         "商户风控评估",
+		Description:  "统计商户争议率与退款率，超过阈值时延长到账、提高费率或暂停 API Key",
+		SupportsTime: false,
+		MaxRetry:     3,
+		Queue:        QueueDefault,
+	},
 }
 
 // GetTaskMeta 根据任务类型获取元数据
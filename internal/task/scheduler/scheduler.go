@@ -64,7 +64,7 @@ func StartScheduler() error {
 			return
 		}
 
-		// 争议自动退款任务
+		// 争议任务补偿：补齐缺少截止时间或下发失败的争议提醒与自动退款任务
 		if _, err = scheduler.Register(
 			config.Config.Scheduler.AutoRefundExpiredDisputesTaskCron,
 			asynq.NewTask(task.AutoRefundExpiredDisputesTask, nil),
@@ -93,6 +93,16 @@ func StartScheduler() error {
 			return
 		}
 
+		// 红包领取核对任务
+		if _, err = scheduler.Register(
+			config.Config.Scheduler.ReconcileRedEnvelopesTaskCron,
+			asynq.NewTask(task.ReconcileRedEnvelopesTask, nil),
+			asynq.Unique(9*time.Minute),
+			asynq.MaxRetry(3),
+		); err != nil {
+			return
+		}
+
 		// 清理未使用的上传文件任务
 		if _, err = scheduler.Register(
 			config.Config.Scheduler.CleanupUnusedUploadsTaskCron,
@@ -113,6 +123,26 @@ func StartScheduler() error {
 			return
 		}
 
+		// 商户日结对账单任务
+		if _, err = scheduler.Register(
+			config.Config.Scheduler.GenerateMerchantStatementsTaskCron,
+			asynq.NewTask(task.GenerateMerchantStatementsTask, nil),
+			asynq.Unique(23*time.Hour),
+			asynq.MaxRetry(3),
+		); err != nil {
+			return
+		}
+
+		// 商户风控评估任务
+		if _, err = scheduler.Register(
+			config.Config.Scheduler.EvaluateMerchantRiskTaskCron,
+			asynq.NewTask(task.EvaluateMerchantRiskTask, nil),
+			asynq.Unique(55*time.Minute),
+			asynq.MaxRetry(3),
+		); err != nil {
+			return
+		}
+
 		// 启动调度器
 		err = scheduler.Run()
 	})
@@ -24,6 +24,8 @@ import (
 
 	"github.com/hibiken/asynq"
 	"github.com/linux-do/credit/internal/apps/dispute"
+	"github.com/linux-do/credit/internal/apps/merchant/risk"
+	"github.com/linux-do/credit/internal/apps/merchant/statement"
 	"github.com/linux-do/credit/internal/apps/order"
 	"github.com/linux-do/credit/internal/apps/payment"
 	"github.com/linux-do/credit/internal/apps/redenvelope"
@@ -77,11 +79,19 @@ func StartWorker() error {
 	mux.HandleFunc(task.UpdateSingleUserGamificationScoreTask, user.HandleUpdateSingleUserGamificationScore)
 	mux.HandleFunc(task.AutoRefundExpiredDisputesTask, dispute.HandleAutoRefundExpiredDisputes)
 	mux.HandleFunc(task.AutoRefundSingleDisputeTask, dispute.HandleAutoRefundSingleDispute)
+	mux.HandleFunc(task.DisputeDeadlineReminderTask, dispute.HandleDisputeDeadlineReminder)
+	mux.HandleFunc(task.DisputeReminderNotifyTask, dispute.HandleDisputeReminderNotify)
 	mux.HandleFunc(task.MerchantPaymentNotifyTask, payment.HandleMerchantPaymentNotify)
 	mux.HandleFunc(task.SyncOrdersToClickHouseTask, order.HandleSyncOrdersToClickHouse)
 	mux.HandleFunc(task.RefundExpiredRedEnvelopesTask, redenvelope.HandleRefundExpiredRedEnvelopes)
+	mux.HandleFunc(task.PersistRedEnvelopeClaimTask, redenvelope.HandlePersistRedEnvelopeClaim)
+	mux.HandleFunc(task.ReconcileRedEnvelopesTask, redenvelope.HandleReconcileRedEnvelopes)
+	mux.HandleFunc(task.RedEnvelopeMerchantNotifyTask, redenvelope.HandleRedEnvelopeMerchantNotify)
 	mux.HandleFunc(task.CleanupUnusedUploadsTask, upload.HandleCleanupUnusedUploads)
 	mux.HandleFunc(task.SettlePendingPaymentsTask, order.HandleSettlePendingPayments)
+	mux.HandleFunc(task.GenerateMerchantStatementsTask, statement.HandleGenerateMerchantStatements)
+	mux.HandleFunc(task.MerchantStatementNotifyTask, statement.HandleMerchantStatementNotify)
+	mux.HandleFunc(task.EvaluateMerchantRiskTask, risk.HandleEvaluateMerchantRisk)
 
 	// 启动服务器
 	return asynqServer.Run(mux)
@@ -17,15 +17,21 @@ limitations under the License.
 package util
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Encrypt 使用 SignKey 加密字符串数据
@@ -156,3 +162,26 @@ func Ed25519Verify(publicKey, message, signature []byte) bool {
 
 	return ed25519.Verify(publicKey, message, signature)
 }
+
+// ParseRSAPublicKey 解析 PEM 格式的 RSA 公钥，支持 PKIX 与 PKCS#1 两种编码
+func ParseRSAPublicKey(pemKey string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemKey)))
+	if block == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("not an RSA public key")
+		}
+		return rsaPub, nil
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// RSAVerifySHA256 校验 RSA PKCS#1 v1.5 + SHA-256 签名
+func RSAVerifySHA256(publicKey *rsa.PublicKey, message, signature []byte) bool {
+	hash := sha256.Sum256(message)
+	return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], signature) == nil
+}
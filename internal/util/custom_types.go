@@ -20,19 +20,48 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+
+	"github.com/shopspring/decimal"
 )
 
 // StringArray custom type for handling JSON arrays
 type StringArray []string
 
 func (sa *StringArray) Scan(value interface{}) error {
-	bytesValue, ok := value.([]byte)
-	if !ok {
+	switch v := value.(type) {
+	case nil:
+		*sa = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, sa)
+	case string:
+		return json.Unmarshal([]byte(v), sa)
+	default:
 		return fmt.Errorf("invalid value: %v", value)
 	}
-	return json.Unmarshal(bytesValue, sa)
 }
 
 func (sa StringArray) Value() (driver.Value, error) {
 	return json.Marshal(sa)
 }
+
+// DecimalArray custom type for handling JSON arrays of decimals
+type DecimalArray []decimal.Decimal
+
+func (da *DecimalArray) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*da = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, da)
+	case string:
+		return json.Unmarshal([]byte(v), da)
+	default:
+		return fmt.Errorf("invalid value: %v", value)
+	}
+}
+
+func (da DecimalArray) Value() (driver.Value, error) {
+	return json.Marshal(da)
+}
@@ -23,3 +23,12 @@ func DerefString(s *string) string {
 	}
 	return *s
 }
+
+// TruncateString 按字符数截断字符串
+func TruncateString(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
@@ -0,0 +1,473 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PayTypeEPay 易支付 MD5 签名
+	PayTypeEPay = "epay"
+	// PayTypeLDCPay LDC Pay Ed25519 签名
+	PayTypeLDCPay = "ldcpay"
+
+	defaultTimeout = 15 * time.Second
+)
+
+// Client 商户接入客户端
+type Client struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	privateKey   ed25519.PrivateKey
+	rsaKey       *rsa.PrivateKey
+	httpClient   *http.Client
+}
+
+// Option 客户端配置项
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义 HTTP 客户端
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithPrivateKey 配置 Ed25519 私钥，用于 LDC Pay 下单
+func WithPrivateKey(privateKey ed25519.PrivateKey) Option {
+	return func(c *Client) {
+		c.privateKey = privateKey
+	}
+}
+
+// WithRSAPrivateKey 配置 RSA 私钥，用于 sign_type=RSA 的易支付下单
+func WithRSAPrivateKey(privateKey *rsa.PrivateKey) Option {
+	return func(c *Client) {
+		c.rsaKey = privateKey
+	}
+}
+
+// NewClient 创建客户端，baseURL 为平台地址，如 https://credit.linux.do
+func NewClient(baseURL, clientID, clientSecret string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CreateOrderRequest 下单请求
+type CreateOrderRequest struct {
+	// PayType 签名方式，默认 epay
+	PayType string
+	// SignType 易支付签名类型：MD5（默认）、SHA256 或 RSA
+	SignType   string
+	Name       string
+	OutTradeNo string
+	// Money 订单金额，最多两位小数，如 "10.00"
+	Money     string
+	NotifyURL string
+	ReturnURL string
+	Device    string
+	// Attach 透传数据，须为 JSON 对象，回调与查询时原样返回
+	Attach string
+	// Splits 订单级分账规则 JSON，覆盖 API Key 默认规则
+	Splits string
+	// TimeoutExpress 订单超时时间，支持 m、h、d 单位，如 30m、2h、3d
+	TimeoutExpress string
+	// ExpireMinutes 订单超时分钟数，优先于 TimeoutExpress
+	ExpireMinutes int
+}
+
+// BuildOrderParams 构建已签名的下单参数
+func (c *Client) BuildOrderParams(req *CreateOrderRequest) (url.Values, error) {
+	payType := req.PayType
+	if payType == "" {
+		payType = PayTypeEPay
+	}
+
+	var params map[string]string
+	switch payType {
+	case PayTypeEPay:
+		params = map[string]string{
+			"pid":          c.clientID,
+			"type":         payType,
+			"out_trade_no": req.OutTradeNo,
+			"notify_url":   req.NotifyURL,
+			"return_url":   req.ReturnURL,
+			"name":         req.Name,
+			"money":        req.Money,
+			"device":       req.Device,
+			"attach":       req.Attach,
+			"splits":       req.Splits,
+		}
+		setTimeoutParams(params, req)
+		signType := strings.ToUpper(req.SignType)
+		switch signType {
+		case "", SignTypeMD5:
+			signType = SignTypeMD5
+			params["sign"] = SignMD5(params, c.clientSecret)
+		case SignTypeSHA256:
+			params["sign"] = SignSHA256(params, c.clientSecret)
+		case SignTypeRSA:
+			if c.rsaKey == nil {
+				return nil, ErrPrivateKeyRequired
+			}
+			sign, err := SignRSA(params, c.rsaKey)
+			if err != nil {
+				return nil, err
+			}
+			params["sign"] = sign
+		default:
+			return nil, fmt.Errorf("不支持的签名类型: %s", req.SignType)
+		}
+		params["sign_type"] = signType
+	case PayTypeLDCPay:
+		if len(c.privateKey) != ed25519.PrivateKeySize {
+			return nil, ErrPrivateKeyRequired
+		}
+		params = map[string]string{
+			"client_id":    c.clientID,
+			"type":         payType,
+			"out_trade_no": req.OutTradeNo,
+			"order_name":   req.Name,
+			"notify_url":   req.NotifyURL,
+			"return_url":   req.ReturnURL,
+			"money":        req.Money,
+			"attach":       req.Attach,
+			"splits":       req.Splits,
+		}
+		setTimeoutParams(params, req)
+		params["sign"] = SignEd25519(params, c.clientSecret, c.privateKey)
+	default:
+		return nil, fmt.Errorf("不支持的支付类型: %s", payType)
+	}
+
+	values := url.Values{}
+	for k, v := range params {
+		if v != "" {
+			values.Set(k, v)
+		}
+	}
+	return values, nil
+}
+
+// setTimeoutParams 写入订单超时参数
+func setTimeoutParams(params map[string]string, req *CreateOrderRequest) {
+	if req.ExpireMinutes > 0 {
+		params["expire_minutes"] = strconv.Itoa(req.ExpireMinutes)
+	}
+	if req.TimeoutExpress != "" {
+		params["timeout_express"] = req.TimeoutExpress
+	}
+}
+
+// PaymentURL 生成可直接跳转的 GET 下单链接
+func (c *Client) PaymentURL(req *CreateOrderRequest) (string, error) {
+	values, err := c.BuildOrderParams(req)
+	if err != nil {
+		return "", err
+	}
+	return c.baseURL + "/pay/submit.php?" + values.Encode(), nil
+}
+
+// CreateOrder 提交订单并返回收银台支付链接
+func (c *Client) CreateOrder(ctx context.Context, req *CreateOrderRequest) (string, error) {
+	values, err := c.BuildOrderParams(req)
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/pay/submit.php", strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// 下单成功时平台返回 302，不跟随跳转以便取出支付链接
+	noRedirect := *c.httpClient
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := noRedirect.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusFound || resp.StatusCode == http.StatusSeeOther {
+		payURL := resp.Header.Get("Location")
+		if payURL == "" {
+			return "", ErrMissingPayURL
+		}
+		return payURL, nil
+	}
+
+	return "", decodeError(resp)
+}
+
+// OrderResult API 模式下单结果
+type OrderResult struct {
+	TradeNo    string `json:"trade_no"`
+	OutTradeNo string `json:"out_trade_no"`
+	// PayURL 收银台支付链接
+	PayURL string `json:"payurl"`
+	// QRCode 二维码内容，与支付链接一致
+	QRCode string `json:"qrcode"`
+	// Img 二维码图片地址
+	Img string `json:"img"`
+}
+
+// CreateOrderAPI 通过 API 模式（mapi.php）下单，以 JSON 返回平台订单号与支付链接
+func (c *Client) CreateOrderAPI(ctx context.Context, req *CreateOrderRequest) (*OrderResult, error) {
+	values, err := c.BuildOrderParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/pay/mapi.php", strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result struct {
+		epayResponse
+		OrderResult
+	}
+	if err := c.doEPay(httpReq, &result, &result.epayResponse); err != nil {
+		return nil, err
+	}
+	return &result.OrderResult, nil
+}
+
+// Order 订单查询结果
+type Order struct {
+	TradeNo    string `json:"trade_no"`
+	OutTradeNo string `json:"out_trade_no"`
+	Type       string `json:"type"`
+	Pid        string `json:"pid"`
+	AddTime    string `json:"addtime"`
+	EndTime    string `json:"endtime"`
+	Name       string `json:"name"`
+	Money      string `json:"money"`
+	// Status 1 为已支付，0 为未支付
+	Status int    `json:"status"`
+	Attach string `json:"attach"`
+}
+
+// Paid 订单是否已支付
+func (o *Order) Paid() bool {
+	return o.Status == 1
+}
+
+// QueryOrder 按商户订单号查询订单
+func (c *Client) QueryOrder(ctx context.Context, outTradeNo string) (*Order, error) {
+	values := url.Values{
+		"act":          {"order"},
+		"pid":          {c.clientID},
+		"key":          {c.clientSecret},
+		"out_trade_no": {outTradeNo},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api.php?"+values.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		epayResponse
+		Order
+	}
+	if err := c.doEPay(httpReq, &result, &result.epayResponse); err != nil {
+		return nil, err
+	}
+	return &result.Order, nil
+}
+
+// RefundRequest 退款请求
+type RefundRequest struct {
+	TradeNo    string
+	OutTradeNo string
+	Money      string
+}
+
+// Refund 对已支付订单发起退款
+func (c *Client) Refund(ctx context.Context, req *RefundRequest) error {
+	values := url.Values{
+		"pid":      {c.clientID},
+		"key":      {c.clientSecret},
+		"trade_no": {req.TradeNo},
+		"money":    {req.Money},
+	}
+	if req.OutTradeNo != "" {
+		values.Set("out_trade_no", req.OutTradeNo)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api.php", strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result epayResponse
+	return c.doEPay(httpReq, &result, &result)
+}
+
+// DistributeRequest 商户分发请求
+type DistributeRequest struct {
+	UserID     uint64
+	Username   string
+	Amount     string
+	OutTradeNo string
+	Remark     string
+}
+
+// DistributeResult 商户分发结果
+type DistributeResult struct {
+	TradeNo    string  `json:"trade_no"`
+	OutTradeNo *string `json:"out_trade_no"`
+}
+
+// Distribute 从商户余额向指定用户分发积分
+func (c *Client) Distribute(ctx context.Context, req *DistributeRequest) (*DistributeResult, error) {
+	body := map[string]any{
+		"user_id":  req.UserID,
+		"username": req.Username,
+		"amount":   json.Number(req.Amount),
+		"remark":   req.Remark,
+	}
+	if req.OutTradeNo != "" {
+		body["out_trade_no"] = req.OutTradeNo
+	}
+	var result DistributeResult
+	if err := c.doBasicAuth(ctx, http.MethodPost, "/pay/distribute", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// doBasicAuth 以 Basic Auth 调用 JSON 接口，body 为 nil 时不发送请求体，data 字段解码到 out
+func (c *Client) doBasicAuth(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.clientID+":"+c.clientSecret)))
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	result := struct {
+		ErrorMsg string `json:"error_msg"`
+		Data     any    `json:"data"`
+	}{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析响应失败[HTTP %d]: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || result.ErrorMsg != "" {
+		return &APIError{StatusCode: resp.StatusCode, Message: result.ErrorMsg}
+	}
+	return nil
+}
+
+// epayResponse 易支付兼容接口的公共响应
+type epayResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// doEPay 执行易支付兼容接口请求，code 非 1 时返回 APIError
+func (c *Client) doEPay(req *http.Request, out any, status *epayResponse) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("解析响应失败[HTTP %d]: %w", resp.StatusCode, err)
+	}
+	if status.Code != 1 {
+		// 签名校验等前置失败时平台返回 {error_msg} 格式
+		if status.Msg == "" {
+			var errResp struct {
+				ErrorMsg string `json:"error_msg"`
+			}
+			_ = json.Unmarshal(body, &errResp)
+			status.Msg = errResp.ErrorMsg
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: status.Msg}
+	}
+	return nil
+}
+
+// decodeError 从非预期响应中提取错误信息，兼容 {error_msg} 与 {code, msg} 两种格式
+func decodeError(resp *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		ErrorMsg string `json:"error_msg"`
+		Msg      string `json:"msg"`
+	}
+	if errJSON := json.Unmarshal(body, &result); errJSON == nil {
+		if msg := result.ErrorMsg + result.Msg; msg != "" {
+			return &APIError{StatusCode: resp.StatusCode, Message: msg}
+		}
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: "HTTP " + strconv.Itoa(resp.StatusCode)}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/linux-do/credit/sdk"
+)
+
+// formParams 将请求表单转换为签名参数
+func formParams(r *http.Request) map[string]string {
+	params := make(map[string]string, len(r.PostForm))
+	for k := range r.PostForm {
+		params[k] = r.PostForm.Get(k)
+	}
+	return params
+}
+
+func testOrderRequest() *sdk.CreateOrderRequest {
+	return &sdk.CreateOrderRequest{
+		Name:       "测试商品",
+		OutTradeNo: "M20250101001",
+		Money:      "10.00",
+		NotifyURL:  "https://example.com/notify",
+	}
+}
+
+func TestBuildOrderParams(t *testing.T) {
+	client := sdk.NewClient("https://credit.example.com/", "1001", testSecret,
+		sdk.WithPrivateKey(testEd25519Key()), sdk.WithRSAPrivateKey(testRSAKey(t)))
+
+	cases := []struct {
+		name     string
+		payType  string
+		signType string
+		wantType string
+		wantSign string
+	}{
+		{name: "md5", wantType: sdk.SignTypeMD5, wantSign: "dc61b9019e9811660bd27b82c6aa677b"},
+		{name: "sha256", signType: "sha256", wantType: sdk.SignTypeSHA256, wantSign: "ca2b4184a69a07450dad547cd4b3bd04cc451b65f97b0bf083ec21dec075fe8d"},
+		{name: "rsa", signType: sdk.SignTypeRSA, wantType: sdk.SignTypeRSA, wantSign: "0O4SYfefWk9L0aN5dC6HgPVQoLlqwLEgwuo6M34tn0PgAhVgLm4IH4s9URdgZmJNmnQQMOonZkvdpdutGKMV/1nIYsSb0/O5FJ7NMiX1t/ZpksLwySUyK13pQ8IgighH/0N1MyebJjfX27livFYqzBy52frwqLI5UEsn1T3IRhss2I2XiKzEatqSxYvoKYV6xnCsbzciieiAfqE30hUWNTdj3wbfhpR+/wbLoWjocMsVctV0xQ2vYJ5QWOkUjVpM7mS7cSFxx4mjmkn/8NUAXy0UtM0enObnR17E3uTw4zpQYNueDI/hAyPdA9DENMuDhrS49mAYO6S7QQAVjDjYDw=="},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := testOrderRequest()
+			req.SignType = tc.signType
+			values, err := client.BuildOrderParams(req)
+			if err != nil {
+				t.Fatalf("BuildOrderParams: %v", err)
+			}
+			if got := values.Get("sign_type"); got != tc.wantType {
+				t.Fatalf("sign_type = %s, want %s", got, tc.wantType)
+			}
+			if got := values.Get("sign"); got != tc.wantSign {
+				t.Fatalf("sign = %s, want %s", got, tc.wantSign)
+			}
+			if values.Has("device") {
+				t.Fatal("empty device should be omitted")
+			}
+		})
+	}
+
+	t.Run("ldcpay", func(t *testing.T) {
+		req := testOrderRequest()
+		req.PayType = sdk.PayTypeLDCPay
+		values, err := client.BuildOrderParams(req)
+		if err != nil {
+			t.Fatalf("BuildOrderParams: %v", err)
+		}
+		params := map[string]string{}
+		for k := range values {
+			params[k] = values.Get(k)
+		}
+		signature, err := base64.StdEncoding.DecodeString(values.Get("sign"))
+		if err != nil {
+			t.Fatalf("decode sign: %v", err)
+		}
+		publicKey := testEd25519Key().Public().(ed25519.PublicKey)
+		if !ed25519.Verify(publicKey, []byte(sdk.SignBaseString(params, testSecret)), signature) {
+			t.Fatal("ldcpay signature does not verify")
+		}
+		if values.Get("client_id") != "1001" || values.Get("order_name") != "测试商品" {
+			t.Fatalf("unexpected ldcpay params: %v", values)
+		}
+	})
+
+	t.Run("missing private key", func(t *testing.T) {
+		req := testOrderRequest()
+		req.SignType = sdk.SignTypeRSA
+		if _, err := sdk.NewClient("https://credit.example.com", "1001", testSecret).BuildOrderParams(req); !errors.Is(err, sdk.ErrPrivateKeyRequired) {
+			t.Fatalf("err = %v, want ErrPrivateKeyRequired", err)
+		}
+	})
+}
+
+func TestPaymentURL(t *testing.T) {
+	client := sdk.NewClient("https://credit.example.com/", "1001", testSecret)
+	payURL, err := client.PaymentURL(testOrderRequest())
+	if err != nil {
+		t.Fatalf("PaymentURL: %v", err)
+	}
+	u, err := url.Parse(payURL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	if u.Host != "credit.example.com" || u.Path != "/pay/submit.php" {
+		t.Fatalf("unexpected payment url: %s", payURL)
+	}
+	query := u.Query()
+	if !sdk.VerifyDigest(map[string]string{
+		"pid": query.Get("pid"), "type": query.Get("type"), "out_trade_no": query.Get("out_trade_no"),
+		"name": query.Get("name"), "money": query.Get("money"), "notify_url": query.Get("notify_url"),
+	}, testSecret, query.Get("sign_type"), query.Get("sign")) {
+		t.Fatalf("payment url signature does not verify: %s", payURL)
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	const payURL = "https://credit.example.com/paying?order_no=000000000000000001"
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantURL string
+		wantErr string
+	}{
+		{
+			name: "redirect",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, payURL, http.StatusFound)
+			},
+			wantURL: payURL,
+		},
+		{
+			name: "see other",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, payURL, http.StatusSeeOther)
+			},
+			wantURL: payURL,
+		},
+		{
+			name: "missing location",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusFound)
+			},
+			wantErr: sdk.ErrMissingPayURL.Error(),
+		},
+		{
+			name: "signature rejected",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error_msg":"签名验证失败"}`))
+			},
+			wantErr: "签名验证失败",
+		},
+		{
+			name: "non json error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantErr: "HTTP 502",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var verified bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/pay/submit.php" {
+					t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+				}
+				if err := r.ParseForm(); err != nil {
+					t.Errorf("parse form: %v", err)
+				}
+				verified = sdk.VerifyDigest(formParams(r), testSecret, r.PostForm.Get("sign_type"), r.PostForm.Get("sign"))
+				tc.handler(w, r)
+			}))
+			defer server.Close()
+
+			client := sdk.NewClient(server.URL, "1001", testSecret)
+			got, err := client.CreateOrder(context.Background(), testOrderRequest())
+			if !verified {
+				t.Fatal("request signature does not verify")
+			}
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("err = %v, want %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateOrder: %v", err)
+			}
+			if got != tc.wantURL {
+				t.Fatalf("pay url = %s, want %s", got, tc.wantURL)
+			}
+		})
+	}
+}
+
+func TestCreateOrderAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pay/mapi.php" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if !sdk.VerifyDigest(formParams(r), testSecret, r.PostForm.Get("sign_type"), r.PostForm.Get("sign")) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"code":-1,"msg":"签名验证失败"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":1,"msg":"success","trade_no":"000000000000000001","out_trade_no":"M20250101001","payurl":"https://credit.example.com/paying?order_no=000000000000000001","img":"https://credit.example.com/epay/pay/qrcode.php?order_no=000000000000000001"}`))
+	}))
+	defer server.Close()
+
+	result, err := sdk.NewClient(server.URL, "1001", testSecret).CreateOrderAPI(context.Background(), testOrderRequest())
+	if err != nil {
+		t.Fatalf("CreateOrderAPI: %v", err)
+	}
+	if result.TradeNo != "000000000000000001" || !strings.HasSuffix(result.Img, "/epay/pay/qrcode.php?order_no=000000000000000001") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	_, err = sdk.NewClient(server.URL, "1001", "wrong_secret").CreateOrderAPI(context.Background(), testOrderRequest())
+	var apiErr *sdk.APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "签名验证失败" {
+		t.Fatalf("err = %v, want APIError 签名验证失败", err)
+	}
+}
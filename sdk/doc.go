@@ -0,0 +1,28 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sdk 是 LINUX DO Credit 商户接入的 Go 客户端
+//
+// 覆盖易支付（MD5）与 LDC Pay（Ed25519）两种签名方式的下单、订单查询、退款、
+// 商户分发、商户红包，以及异步回调的验签与解析。
+//
+//	client := sdk.NewClient("https://credit.linux.do", clientID, clientSecret)
+//	payURL, err := client.CreateOrder(ctx, &sdk.CreateOrderRequest{
+//		Name:       "会员月卡",
+//		OutTradeNo: "M202401010001",
+//		Money:      "10.00",
+//	})
+package sdk
@@ -0,0 +1,35 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import "errors"
+
+var (
+	ErrPrivateKeyRequired = errors.New("Ed25519 与 RSA 签名需要配置对应的商户私钥")
+	ErrMissingPayURL      = errors.New("下单响应中缺少支付链接")
+	ErrInvalidSign        = errors.New("回调签名校验失败")
+)
+
+// APIError 平台返回的业务错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,342 @@
+//go:build integration
+
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// 集成测试：以 SDK 调用真实的支付处理函数，防止 SDK 签名与平台验签实现出现偏差
+// 需要可用的 PostgreSQL 与 Redis，运行方式：
+//
+//	CONFIG_PATH=/path/to/config.yaml go test -tags integration ./sdk/...
+package sdk_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/linux-do/credit/internal/apps/payment"
+	"github.com/linux-do/credit/internal/common"
+	"github.com/linux-do/credit/internal/db"
+	"github.com/linux-do/credit/internal/db/migrator"
+	"github.com/linux-do/credit/internal/model"
+	"github.com/linux-do/credit/internal/service"
+	"github.com/linux-do/credit/sdk"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// fixture 测试商户、收款用户与沙箱 API Key
+type fixture struct {
+	server     *httptest.Server
+	apiKey     model.MerchantAPIKey
+	privateKey ed25519.PrivateKey
+	recipient  model.User
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	migrator.Migrate()
+	os.Exit(m.Run())
+}
+
+// newFixture 创建测试数据并挂载与线上一致的支付路由
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成 Ed25519 密钥失败: %v", err)
+	}
+
+	merchant := model.User{ID: uint64(time.Now().UnixNano()), Username: "sdk_m_" + suffix, SignKey: "sdk_m_" + suffix, IsActive: true}
+	recipient := model.User{ID: merchant.ID + 1, Username: "sdk_r_" + suffix, SignKey: "sdk_r_" + suffix, IsActive: true}
+	if err := db.DB(ctx).Create(&merchant).Error; err != nil {
+		t.Fatalf("创建商户用户失败: %v", err)
+	}
+	if err := db.DB(ctx).Create(&recipient).Error; err != nil {
+		t.Fatalf("创建收款用户失败: %v", err)
+	}
+
+	f := &fixture{
+		apiKey: model.MerchantAPIKey{
+			UserID:         merchant.ID,
+			ClientID:       "sdk_" + suffix,
+			ClientSecret:   "secret_" + suffix,
+			AppName:        "sdk-test",
+			AppHomepageURL: "https://example.com",
+			NotifyURL:      "https://example.com/notify",
+			PublicKey:      publicKey,
+			TestMode:       true,
+		},
+		privateKey: privateKey,
+		recipient:  recipient,
+	}
+	if err := db.DB(ctx).Create(&f.apiKey).Error; err != nil {
+		t.Fatalf("创建 API Key 失败: %v", err)
+	}
+
+	r := gin.New()
+	r.Match([]string{"GET", "POST"}, "/pay/submit.php", payment.RequireSignatureAuth(), payment.CreateMerchantOrder)
+	r.Match([]string{"GET", "POST"}, "/pay/mapi.php", payment.RequireSignatureAuth(), payment.CreateMerchantOrderAPI)
+	r.GET("/api.php", payment.QueryMerchantOrder)
+	r.POST("/api.php", payment.RefundMerchantOrder)
+	r.POST("/pay/distribute", payment.RequireMerchantAuth(), payment.MerchantDistribute)
+	f.server = httptest.NewServer(r)
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+// client 创建指向测试服务的 SDK 客户端
+func (f *fixture) client() *sdk.Client {
+	return sdk.NewClient(f.server.URL, f.apiKey.ClientID, f.apiKey.ClientSecret, sdk.WithPrivateKey(f.privateKey))
+}
+
+// markPaid 模拟收银台完成沙箱支付
+func (f *fixture) markPaid(t *testing.T, tradeNo string) model.Order {
+	t.Helper()
+	var order model.Order
+	if err := db.DB(context.Background()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", tradeNo).First(&order).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&order).UpdateColumns(map[string]interface{}{
+			"status":     model.OrderStatusSuccess,
+			"trade_time": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return service.ApplySandboxPayment(tx, f.apiKey.ID, &order, decimal.Zero)
+	}); err != nil {
+		t.Fatalf("标记订单已支付失败: %v", err)
+	}
+	return order
+}
+
+func TestCreateOrderAndQuery(t *testing.T) {
+	f := newFixture(t)
+	client := f.client()
+
+	cases := []struct {
+		name     string
+		payType  string
+		signType string
+	}{
+		{name: "epay md5", payType: sdk.PayTypeEPay, signType: sdk.SignTypeMD5},
+		{name: "epay sha256", payType: sdk.PayTypeEPay, signType: sdk.SignTypeSHA256},
+		{name: "ldcpay ed25519", payType: sdk.PayTypeLDCPay},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			outTradeNo := fmt.Sprintf("%s-%d", f.apiKey.ClientID, i)
+			result, err := client.CreateOrderAPI(context.Background(), &sdk.CreateOrderRequest{
+				PayType:    tc.payType,
+				SignType:   tc.signType,
+				Name:       "SDK 测试订单",
+				OutTradeNo: outTradeNo,
+				Money:      "10.00",
+				Attach:     `{"case":"` + tc.name + `"}`,
+			})
+			if err != nil {
+				t.Fatalf("下单失败: %v", err)
+			}
+			if result.TradeNo == "" || result.PayURL == "" {
+				t.Fatalf("下单结果缺少订单号或支付链接: %+v", result)
+			}
+
+			order, err := client.QueryOrder(context.Background(), outTradeNo)
+			if err != nil {
+				t.Fatalf("查询订单失败: %v", err)
+			}
+			if order.TradeNo != result.TradeNo || order.Money != "10.00" || order.Paid() {
+				t.Fatalf("查询结果不符: %+v", order)
+			}
+			if order.Attach != `{"case":"`+tc.name+`"}` {
+				t.Fatalf("透传数据不符: %s", order.Attach)
+			}
+		})
+	}
+}
+
+func TestCreateOrderRedirect(t *testing.T) {
+	f := newFixture(t)
+
+	payURL, err := f.client().CreateOrder(context.Background(), &sdk.CreateOrderRequest{
+		Name:       "SDK 跳转下单",
+		OutTradeNo: f.apiKey.ClientID + "-redirect",
+		Money:      "1.00",
+	})
+	if err != nil {
+		t.Fatalf("下单失败: %v", err)
+	}
+	if payURL == "" {
+		t.Fatal("未返回支付链接")
+	}
+}
+
+func TestCreateOrderRejectsBadSign(t *testing.T) {
+	f := newFixture(t)
+
+	wrong := sdk.NewClient(f.server.URL, f.apiKey.ClientID, "wrong-secret")
+	if _, err := wrong.CreateOrderAPI(context.Background(), &sdk.CreateOrderRequest{
+		Name:       "SDK 错误签名",
+		OutTradeNo: f.apiKey.ClientID + "-bad",
+		Money:      "1.00",
+	}); err == nil {
+		t.Fatal("错误密钥签名的订单不应创建成功")
+	}
+}
+
+func TestRefund(t *testing.T) {
+	f := newFixture(t)
+	client := f.client()
+	outTradeNo := f.apiKey.ClientID + "-refund"
+
+	result, err := client.CreateOrderAPI(context.Background(), &sdk.CreateOrderRequest{
+		Name:       "SDK 退款订单",
+		OutTradeNo: outTradeNo,
+		Money:      "5.00",
+	})
+	if err != nil {
+		t.Fatalf("下单失败: %v", err)
+	}
+	f.markPaid(t, result.TradeNo)
+
+	order, err := client.QueryOrder(context.Background(), outTradeNo)
+	if err != nil {
+		t.Fatalf("查询订单失败: %v", err)
+	}
+	if !order.Paid() {
+		t.Fatalf("订单应为已支付: %+v", order)
+	}
+
+	if err := client.Refund(context.Background(), &sdk.RefundRequest{TradeNo: result.TradeNo, Money: "5.00"}); err != nil {
+		t.Fatalf("退款失败: %v", err)
+	}
+	if err := client.Refund(context.Background(), &sdk.RefundRequest{TradeNo: result.TradeNo, Money: "5.00"}); err == nil {
+		t.Fatal("重复退款应失败")
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	f := newFixture(t)
+	client := f.client()
+
+	if err := db.DB(context.Background()).Transaction(func(tx *gorm.DB) error {
+		if _, err := service.GetOrCreateSandboxAccount(tx, f.apiKey.ID); err != nil {
+			return err
+		}
+		return tx.Model(&model.SandboxAccount{}).
+			Where("merchant_api_key_id = ?", f.apiKey.ID).
+			UpdateColumn("merchant_balance", decimal.NewFromInt(20)).Error
+	}); err != nil {
+		t.Fatalf("初始化沙箱商户余额失败: %v", err)
+	}
+
+	result, err := client.Distribute(context.Background(), &sdk.DistributeRequest{
+		UserID:     f.recipient.ID,
+		Username:   f.recipient.Username,
+		Amount:     "12.50",
+		OutTradeNo: f.apiKey.ClientID + "-distribute",
+		Remark:     "SDK 分发",
+	})
+	if err != nil {
+		t.Fatalf("分发失败: %v", err)
+	}
+	if result.TradeNo == "" || result.OutTradeNo == nil || *result.OutTradeNo != f.apiKey.ClientID+"-distribute" {
+		t.Fatalf("分发结果不符: %+v", result)
+	}
+
+	if _, err := client.Distribute(context.Background(), &sdk.DistributeRequest{
+		UserID:   f.recipient.ID,
+		Username: f.recipient.Username,
+		Amount:   "12.50",
+	}); err == nil {
+		t.Fatal("沙箱余额不足时分发应失败")
+	}
+}
+
+func TestNotifyVerify(t *testing.T) {
+	f := newFixture(t)
+	client := f.client()
+
+	for _, signType := range []string{sdk.SignTypeMD5, sdk.SignTypeSHA256} {
+		t.Run(signType, func(t *testing.T) {
+			received := make(chan *sdk.Notify, 1)
+			notifyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				notify, err := client.ParseNotifyRequest(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				received <- notify
+				_, _ = w.Write([]byte(sdk.NotifySuccess))
+			}))
+			defer notifyServer.Close()
+
+			outTradeNo := f.apiKey.ClientID + "-notify-" + signType
+			result, err := client.CreateOrderAPI(context.Background(), &sdk.CreateOrderRequest{
+				SignType:   signType,
+				Name:       "SDK 回调订单",
+				OutTradeNo: outTradeNo,
+				Money:      "3.00",
+				NotifyURL:  notifyServer.URL,
+				Attach:     `{"user":1}`,
+			})
+			if err != nil {
+				t.Fatalf("下单失败: %v", err)
+			}
+			order := f.markPaid(t, result.TradeNo)
+
+			payload, _ := json.Marshal(map[string]interface{}{
+				"order_id":     order.ID,
+				"client_id":    f.apiKey.ClientID,
+				"trade_status": common.TradeStatusSuccess,
+			})
+			if err := payment.HandleMerchantPaymentNotify(context.Background(), asynq.NewTask("", payload)); err != nil {
+				t.Fatalf("发送回调失败: %v", err)
+			}
+
+			notify := <-received
+			if !notify.Paid() || notify.TradeNo != result.TradeNo || notify.OutTradeNo != outTradeNo || notify.Money != "3.00" || !notify.Sandbox || notify.Attach != `{"user":1}` {
+				t.Fatalf("回调内容不符: %+v", notify)
+			}
+
+			// 篡改任一参数后签名校验应失败
+			tampered := url.Values{}
+			for k, v := range notify.Params {
+				tampered[k] = v
+			}
+			tampered.Set("money", "300.00")
+			if client.VerifyNotify(tampered) {
+				t.Fatal("篡改后的回调不应通过验签")
+			}
+		})
+	}
+}
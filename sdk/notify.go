@@ -0,0 +1,117 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const (
+	// NotifySuccess 回调处理成功后须原样返回的响应体，否则平台将重试
+	NotifySuccess = "success"
+
+	// TradeStatusSuccess 支付成功
+	TradeStatusSuccess = "TRADE_SUCCESS"
+	// TradeStatusFailed 支付失败
+	TradeStatusFailed = "TRADE_FAILED"
+	// TradeStatusRefund 已退款
+	TradeStatusRefund = "TRADE_REFUND"
+	// TradeStatusDispute 发生争议
+	TradeStatusDispute = "TRADE_DISPUTE"
+
+	// EventStatementReady 日对账单生成事件
+	EventStatementReady = "statement.ready"
+	// EventRedEnvelopeFinished 商户红包领完事件
+	EventRedEnvelopeFinished = "redenvelope.finished"
+	// EventRedEnvelopeRefunded 商户红包过期退款事件
+	EventRedEnvelopeRefunded = "redenvelope.refunded"
+)
+
+// Notify 异步回调参数
+type Notify struct {
+	Pid         string
+	TradeNo     string
+	OutTradeNo  string
+	Type        string
+	Name        string
+	Money       string
+	TradeStatus string
+	// Sandbox 是否为测试模式订单
+	Sandbox bool
+	// Message 付款人留言
+	Message string
+	// Attach 下单时传入的透传数据
+	Attach string
+	// Event 非订单类通知的事件类型，如 statement.ready
+	Event string
+	// Params 全部原始参数，用于读取事件特有字段
+	Params url.Values
+}
+
+// VerifyNotify 校验回调参数签名
+func (c *Client) VerifyNotify(values url.Values) bool {
+	return VerifyNotify(values, c.clientSecret)
+}
+
+// ParseNotify 校验并解析回调参数
+func (c *Client) ParseNotify(values url.Values) (*Notify, error) {
+	return ParseNotify(values, c.clientSecret)
+}
+
+// ParseNotifyRequest 从 HTTP 请求中校验并解析回调参数
+func (c *Client) ParseNotifyRequest(r *http.Request) (*Notify, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	return ParseNotify(r.Form, c.clientSecret)
+}
+
+// VerifyNotify 使用商户密钥校验回调参数签名
+func VerifyNotify(values url.Values, secret string) bool {
+	sign := values.Get("sign")
+	if sign == "" {
+		return false
+	}
+	return VerifyDigest(valuesToMap(values), secret, values.Get("sign_type"), sign)
+}
+
+// ParseNotify 使用商户密钥校验并解析回调参数，签名不正确时返回 ErrInvalidSign
+func ParseNotify(values url.Values, secret string) (*Notify, error) {
+	if !VerifyNotify(values, secret) {
+		return nil, ErrInvalidSign
+	}
+	return &Notify{
+		Pid:         values.Get("pid"),
+		TradeNo:     values.Get("trade_no"),
+		OutTradeNo:  values.Get("out_trade_no"),
+		Type:        values.Get("type"),
+		Name:        values.Get("name"),
+		Money:       values.Get("money"),
+		TradeStatus: values.Get("trade_status"),
+		Sandbox:     values.Get("sandbox") == "1",
+		Message:     values.Get("message"),
+		Attach:      values.Get("attach"),
+		Event:       values.Get("event"),
+		Params:      values,
+	}, nil
+}
+
+// Paid 是否为支付成功通知
+func (n *Notify) Paid() bool {
+	return n.Event == "" && n.TradeStatus == TradeStatusSuccess
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/linux-do/credit/sdk"
+)
+
+const (
+	// testNotifyMD5 平台以 MD5 签名的回调向量，易支付 MD5 与 LDC Pay（Ed25519 下单）订单的回调使用该签名
+	testNotifyMD5 = "d2b369b4ca63133a6a9ba1706596e560"
+	// testNotifySHA256 平台以 SHA-256 签名的回调向量，SHA256 与 RSA 下单的订单回调使用该签名
+	testNotifySHA256 = "adf94159faf253a065e7177f6276dc817263c677efb2f6c31151f6f7eaa25f5a"
+)
+
+// testNotifyValues 回调参数，sign 与 sign_type 由调用方填写
+func testNotifyValues(signType, sign string) url.Values {
+	values := url.Values{
+		"pid":          {"1001"},
+		"trade_no":     {"202501010000000001"},
+		"out_trade_no": {"M20250101001"},
+		"type":         {"epay"},
+		"name":         {"测试商品"},
+		"money":        {"10.00"},
+		"trade_status": {sdk.TradeStatusSuccess},
+		"attach":       {`{"k":"v"}`},
+		"sandbox":      {"1"},
+		"sign":         {sign},
+	}
+	if signType != "" {
+		values.Set("sign_type", signType)
+	}
+	return values
+}
+
+func TestVerifyNotify(t *testing.T) {
+	cases := []struct {
+		name   string
+		values url.Values
+		want   bool
+	}{
+		{name: "md5 order", values: testNotifyValues(sdk.SignTypeMD5, testNotifyMD5), want: true},
+		{name: "ldcpay ed25519 order", values: testNotifyValues("", testNotifyMD5), want: true},
+		{name: "sha256 or rsa order", values: testNotifyValues(sdk.SignTypeSHA256, testNotifySHA256), want: true},
+		{name: "sha256 upper", values: testNotifyValues(sdk.SignTypeSHA256, strings.ToUpper(testNotifySHA256)), want: true},
+		{name: "sign type mismatch", values: testNotifyValues(sdk.SignTypeSHA256, testNotifyMD5), want: false},
+		{name: "unsupported sign type", values: testNotifyValues(sdk.SignTypeRSA, testNotifySHA256), want: false},
+		{name: "missing sign", values: testNotifyValues(sdk.SignTypeMD5, ""), want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sdk.VerifyNotify(tc.values, testSecret); got != tc.want {
+				t.Fatalf("VerifyNotify = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	tampered := testNotifyValues(sdk.SignTypeMD5, testNotifyMD5)
+	tampered.Set("money", "100.00")
+	if sdk.VerifyNotify(tampered, testSecret) {
+		t.Fatal("VerifyNotify accepted tampered money")
+	}
+	if sdk.VerifyNotify(testNotifyValues(sdk.SignTypeMD5, testNotifyMD5), "wrong_secret") {
+		t.Fatal("VerifyNotify accepted a wrong secret")
+	}
+}
+
+func TestParseNotifyRequest(t *testing.T) {
+	client := sdk.NewClient("https://credit.example.com", "1001", testSecret)
+
+	body := testNotifyValues(sdk.SignTypeSHA256, testNotifySHA256).Encode()
+	req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	notify, err := client.ParseNotifyRequest(req)
+	if err != nil {
+		t.Fatalf("ParseNotifyRequest: %v", err)
+	}
+	if notify.TradeNo != "202501010000000001" || notify.OutTradeNo != "M20250101001" || notify.Money != "10.00" {
+		t.Fatalf("unexpected notify: %+v", notify)
+	}
+	if !notify.Paid() || !notify.Sandbox || notify.Attach != `{"k":"v"}` {
+		t.Fatalf("unexpected notify flags: %+v", notify)
+	}
+
+	tampered := testNotifyValues(sdk.SignTypeMD5, testNotifyMD5)
+	tampered.Set("trade_status", sdk.TradeStatusRefund)
+	if _, err := client.ParseNotify(tampered); !errors.Is(err, sdk.ErrInvalidSign) {
+		t.Fatalf("ParseNotify err = %v, want ErrInvalidSign", err)
+	}
+}
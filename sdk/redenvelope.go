@@ -0,0 +1,163 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// RedEnvelopeTypeFixed 固定金额红包
+	RedEnvelopeTypeFixed = "fixed"
+	// RedEnvelopeTypeRandom 拼手气红包
+	RedEnvelopeTypeRandom = "random"
+)
+
+// RedEnvelopeEligibility 红包领取资格，各项条件需同时满足，零值表示不限制
+type RedEnvelopeEligibility struct {
+	Usernames            []string `json:"usernames,omitempty"`
+	MinTrustLevel        int      `json:"min_trust_level,omitempty"`
+	MinAccountAgeDays    int      `json:"min_account_age_days,omitempty"`
+	PaidMerchantUsername string   `json:"paid_merchant_username,omitempty"`
+}
+
+// CreateRedEnvelopeRequest 创建红包请求，金额与手续费从商户余额扣除
+type CreateRedEnvelopeRequest struct {
+	Type        string
+	TotalAmount string
+	TotalCount  int
+	Greeting    string
+	Passphrase  string
+	Eligibility *RedEnvelopeEligibility
+	// OpensAt 定时开启时间，为空时立即开启
+	OpensAt    *time.Time
+	OutTradeNo string
+	// NotifyURL 领完或过期退款的回调地址，为空时使用 API Key 配置的回调地址
+	NotifyURL string
+}
+
+// CreateRedEnvelopeResult 创建红包结果
+type CreateRedEnvelopeResult struct {
+	ID             string     `json:"id"`
+	OutTradeNo     *string    `json:"out_trade_no"`
+	Status         string     `json:"status"`
+	ServerSeedHash string     `json:"server_seed_hash"`
+	OpensAt        *time.Time `json:"opens_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+}
+
+// RedEnvelope 红包
+type RedEnvelope struct {
+	ID              string     `json:"id"`
+	Type            string     `json:"type"`
+	TotalAmount     string     `json:"total_amount"`
+	RemainingAmount string     `json:"remaining_amount"`
+	TotalCount      int        `json:"total_count"`
+	RemainingCount  int        `json:"remaining_count"`
+	Greeting        string     `json:"greeting"`
+	HasPassphrase   bool       `json:"has_passphrase"`
+	Status          string     `json:"status"`
+	OutTradeNo      *string    `json:"out_trade_no"`
+	ServerSeedHash  string     `json:"server_seed_hash"`
+	OpensAt         *time.Time `json:"opens_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// RedEnvelopeDetail 红包状态
+type RedEnvelopeDetail struct {
+	RedEnvelope   RedEnvelope `json:"red_envelope"`
+	ClaimedCount  int         `json:"claimed_count"`
+	ClaimedAmount string      `json:"claimed_amount"`
+	// ServerSeed 红包领完或过期后公开的服务端种子
+	ServerSeed string `json:"server_seed"`
+}
+
+// RedEnvelopeClaim 红包领取记录
+type RedEnvelopeClaim struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Amount    string    `json:"amount"`
+	Seq       int       `json:"seq"`
+	ClaimedAt time.Time `json:"claimed_at"`
+}
+
+// RedEnvelopeClaims 红包领取记录分页结果
+type RedEnvelopeClaims struct {
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+	Claims   []RedEnvelopeClaim `json:"claims"`
+}
+
+// CreateRedEnvelope 从商户余额创建红包
+func (c *Client) CreateRedEnvelope(ctx context.Context, req *CreateRedEnvelopeRequest) (*CreateRedEnvelopeResult, error) {
+	body := map[string]any{
+		"type":         req.Type,
+		"total_amount": json.Number(req.TotalAmount),
+		"total_count":  req.TotalCount,
+		"greeting":     req.Greeting,
+	}
+	if req.Passphrase != "" {
+		body["passphrase"] = req.Passphrase
+	}
+	if req.Eligibility != nil {
+		body["eligibility"] = req.Eligibility
+	}
+	if req.OpensAt != nil {
+		body["opens_at"] = req.OpensAt
+	}
+	if req.OutTradeNo != "" {
+		body["out_trade_no"] = req.OutTradeNo
+	}
+	if req.NotifyURL != "" {
+		body["notify_url"] = req.NotifyURL
+	}
+
+	var result CreateRedEnvelopeResult
+	if err := c.doBasicAuth(ctx, http.MethodPost, "/pay/redenvelope", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetRedEnvelope 查询商户创建的红包状态
+func (c *Client) GetRedEnvelope(ctx context.Context, id string) (*RedEnvelopeDetail, error) {
+	var result RedEnvelopeDetail
+	if err := c.doBasicAuth(ctx, http.MethodGet, "/pay/redenvelope/"+url.PathEscape(id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListRedEnvelopeClaims 分页查询红包领取记录，按领取时间升序
+func (c *Client) ListRedEnvelopeClaims(ctx context.Context, id string, page, pageSize int) (*RedEnvelopeClaims, error) {
+	query := url.Values{
+		"page":      {strconv.Itoa(page)},
+		"page_size": {strconv.Itoa(pageSize)},
+	}
+	var result RedEnvelopeClaims
+	if err := c.doBasicAuth(ctx, http.MethodGet, "/pay/redenvelope/"+url.PathEscape(id)+"/claims?"+query.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2025 linux.do
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// SignTypeMD5 易支付 MD5 签名
+	SignTypeMD5 = "MD5"
+	// SignTypeSHA256 易支付 SHA-256 签名
+	SignTypeSHA256 = "SHA256"
+	// SignTypeRSA 易支付 RSA-SHA256 签名
+	SignTypeRSA = "RSA"
+)
+
+// SignBaseString 构建签名原文：去除 sign、sign_type 与空值后按 key 升序拼接为 k=v&k=v，末尾追加密钥
+func SignBaseString(params map[string]string, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if k == "sign" || k == "sign_type" || v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	builder.Grow(256)
+	for i, k := range keys {
+		if i > 0 {
+			builder.WriteByte('&')
+		}
+		builder.WriteString(k)
+		builder.WriteByte('=')
+		builder.WriteString(params[k])
+	}
+	builder.WriteString(secret)
+	return builder.String()
+}
+
+// SignMD5 生成易支付 MD5 签名（小写十六进制）
+func SignMD5(params map[string]string, secret string) string {
+	hash := md5.Sum([]byte(SignBaseString(params, secret)))
+	return hex.EncodeToString(hash[:])
+}
+
+// SignEd25519 使用商户私钥生成 LDC Pay 签名（标准 Base64）
+func SignEd25519(params map[string]string, secret string, privateKey ed25519.PrivateKey) string {
+	signature := ed25519.Sign(privateKey, []byte(SignBaseString(params, secret)))
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+// SignSHA256 生成 SHA-256 摘要签名（小写十六进制）
+func SignSHA256(params map[string]string, secret string) string {
+	hash := sha256.Sum256([]byte(SignBaseString(params, secret)))
+	return hex.EncodeToString(hash[:])
+}
+
+// SignRSA 使用商户 RSA 私钥生成 RSA-SHA256 签名（标准 Base64），签名原文不拼接商户密钥
+func SignRSA(params map[string]string, privateKey *rsa.PrivateKey) (string, error) {
+	hash := sha256.Sum256([]byte(SignBaseString(params, "")))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// VerifyDigest 按 sign_type 校验 MD5 或 SHA-256 摘要签名，sign_type 为空时视为 MD5
+func VerifyDigest(params map[string]string, secret string, signType string, sign string) bool {
+	var expected string
+	switch strings.ToUpper(signType) {
+	case "", SignTypeMD5:
+		expected = SignMD5(params, secret)
+	case SignTypeSHA256:
+		expected = SignSHA256(params, secret)
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(sign))) == 1
+}
+
+// VerifyMD5 校验 MD5 签名，忽略大小写
+func VerifyMD5(params map[string]string, secret string, sign string) bool {
+	expected := SignMD5(params, secret)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(sign))) == 1
+}
+
+// valuesToMap 将表单参数转换为签名参数，同名参数取第一个值
+func valuesToMap(values url.Values) map[string]string {
+	params := make(map[string]string, len(values))
+	for k := range values {
+		params[k] = values.Get(k)
+	}
+	return params
+}